@@ -1,10 +1,14 @@
 package agent
 
 import (
+	"context"
 	"sync"
 
 	"github.com/observiq/stanza/database"
+	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/operator"
 	"github.com/observiq/stanza/pipeline"
+	"github.com/observiq/stanza/plugin"
 	"go.uber.org/zap"
 )
 
@@ -13,8 +17,24 @@ type LogAgent struct {
 	database database.Database
 	pipeline pipeline.Pipeline
 
+	config        *Config
+	configFiles   []string
+	pluginDir     string
+	buildContext  operator.BuildContext
+	defaultOutput operator.Operator
+
+	remoteConfig  *RemoteConfig
+	remoteVersion *remoteConfigVersion
+	remoteMux     sync.Mutex
+
 	startOnce sync.Once
 	stopOnce  sync.Once
+	mux       sync.Mutex
+	started   bool
+	stopped   bool
+
+	checks    checkState
+	checksMux sync.Mutex
 
 	*zap.SugaredLogger
 }
@@ -22,10 +42,15 @@ type LogAgent struct {
 // Start will start the log monitoring process
 func (a *LogAgent) Start() (err error) {
 	a.startOnce.Do(func() {
+		a.mux.Lock()
+		defer a.mux.Unlock()
+
 		err = a.pipeline.Start()
 		if err != nil {
 			return
 		}
+		a.started = true
+		a.updateChecks()
 	})
 	return
 }
@@ -33,7 +58,12 @@ func (a *LogAgent) Start() (err error) {
 // Stop will stop the log monitoring process
 func (a *LogAgent) Stop() (err error) {
 	a.stopOnce.Do(func() {
-		err = a.pipeline.Stop()
+		a.mux.Lock()
+		defer a.mux.Unlock()
+		a.stopped = true
+		a.updateChecks()
+
+		err = a.stopPipeline()
 		if err != nil {
 			return
 		}
@@ -45,3 +75,80 @@ func (a *LogAgent) Stop() (err error) {
 	})
 	return
 }
+
+// stopPipeline stops the pipeline. If the pipeline can be drained, its operators are
+// given until the shutdown timeout to flush the entries they hold.
+func (a *LogAgent) stopPipeline() error {
+	drainer, ok := a.pipeline.(pipeline.Drainer)
+	if !ok {
+		return a.pipeline.Stop()
+	}
+
+	timeout := a.config.shutdownTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	unflushed, err := drainer.Drain(ctx)
+	if unflushed > 0 {
+		a.Warnw("Shutdown timeout reached before all entries were flushed", "unflushed", unflushed, "shutdown_timeout", timeout)
+	}
+	return err
+}
+
+// Reload will reread the agent's config files, or fetch its remote config, and apply
+// any changes to the running pipeline.
+func (a *LogAgent) Reload() error {
+	if a.remoteConfig != nil {
+		return a.pollRemoteConfig(context.Background())
+	}
+
+	if len(a.configFiles) == 0 {
+		return errors.NewError(
+			"agent can not be reloaded because it was not built from config files",
+			"build the agent WithConfigFiles or WithRemoteConfig, or use ReloadConfig",
+		)
+	}
+
+	if a.pluginDir != "" {
+		if errs := plugin.RegisterPlugins(a.pluginDir, operator.DefaultRegistry); len(errs) != 0 {
+			a.Errorw("Got errors parsing plugins", "errors", errs)
+		}
+	}
+
+	cfg, err := NewConfigFromGlobs(a.configFiles)
+	if err != nil {
+		err = errors.Wrap(err, "read configs from globs")
+		a.Errorw("Failed to reload agent", zap.Any("error", err))
+		return err
+	}
+
+	return a.ReloadConfig(cfg)
+}
+
+// ReloadConfig will apply the supplied config to the running pipeline. Operators
+// that are unaffected by the new config are kept running. If the new pipeline
+// fails to build, the current pipeline continues running unchanged.
+func (a *LogAgent) ReloadConfig(cfg *Config) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if !a.started || a.stopped {
+		return errors.NewError("agent can only be reloaded while it is running", "")
+	}
+
+	reloader, ok := a.pipeline.(pipeline.Reloader)
+	if !ok {
+		return errors.NewError("agent pipeline does not support reloading", "this is an unexpected internal error")
+	}
+
+	a.Info("Reloading agent pipeline")
+	defer a.updateCheckedOperators()
+	if err := reloader.Reload(cfg.Pipeline, a.buildContext, a.defaultOutput); err != nil {
+		a.Errorw("Failed to reload agent pipeline", zap.Any("error", err))
+		return err
+	}
+
+	a.config = cfg
+	a.Info("Reloaded agent pipeline")
+	return nil
+}
@@ -1,9 +1,12 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/observiq/stanza/operator/helper"
 	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -75,6 +78,37 @@ func TestStopAgentPipelineFailure(t *testing.T) {
 	database.AssertNotCalled(t, "Close")
 }
 
+type drainPipeline struct {
+	*testutil.Pipeline
+	deadline time.Time
+}
+
+func (p *drainPipeline) Drain(ctx context.Context) (int, error) {
+	p.deadline, _ = ctx.Deadline()
+	return 3, nil
+}
+
+func TestStopAgentDrain(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	pipeline := &drainPipeline{Pipeline: &testutil.Pipeline{}}
+	database := &testutil.Database{}
+	database.On("Close").Return(nil)
+	timeout := helper.NewDuration(time.Minute)
+
+	agent := LogAgent{
+		SugaredLogger: logger,
+		pipeline:      pipeline,
+		database:      database,
+		config:        &Config{ShutdownTimeout: &timeout},
+	}
+	start := time.Now()
+	err := agent.Stop()
+	require.NoError(t, err)
+	require.WithinDuration(t, start.Add(time.Minute), pipeline.deadline, 10*time.Second)
+	pipeline.AssertNotCalled(t, "Stop")
+	database.AssertCalled(t, "Close")
+}
+
 func TestStopAgentDatabaseFailure(t *testing.T) {
 	logger := zap.NewNop().Sugar()
 	pipeline := &testutil.Pipeline{}
@@ -93,3 +127,29 @@ func TestStopAgentDatabaseFailure(t *testing.T) {
 	pipeline.AssertCalled(t, "Stop")
 	database.AssertCalled(t, "Close")
 }
+
+func TestReloadAgentWithoutConfigFiles(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	pipeline := &testutil.Pipeline{}
+
+	agent := LogAgent{
+		SugaredLogger: logger,
+		pipeline:      pipeline,
+	}
+	err := agent.Reload()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not built from config files")
+}
+
+func TestReloadAgentNotRunning(t *testing.T) {
+	logger := zap.NewNop().Sugar()
+	pipeline := &testutil.Pipeline{}
+
+	agent := LogAgent{
+		SugaredLogger: logger,
+		pipeline:      pipeline,
+	}
+	err := agent.ReloadConfig(&Config{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only be reloaded while it is running")
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"time"
 
 	"github.com/observiq/stanza/database"
@@ -19,6 +20,7 @@ type LogAgentBuilder struct {
 	pluginDir     string
 	databaseFile  string
 	defaultOutput operator.Operator
+	remoteConfig  *RemoteConfig
 }
 
 // NewBuilder creates a new LogAgentBuilder
@@ -46,6 +48,14 @@ func (b *LogAgentBuilder) WithConfig(cfg *Config) *LogAgentBuilder {
 	return b
 }
 
+// WithRemoteConfig builds the agent with a config fetched from a remote source. If
+// the source can not be reached, the last known good config cached in the database
+// is used.
+func (b *LogAgentBuilder) WithRemoteConfig(remote *RemoteConfig) *LogAgentBuilder {
+	b.remoteConfig = remote
+	return b
+}
+
 // WithDatabaseFile adds the specified database file when building a log agent
 func (b *LogAgentBuilder) WithDatabaseFile(databaseFile string) *LogAgentBuilder {
 	b.databaseFile = databaseFile
@@ -71,10 +81,21 @@ func (b *LogAgentBuilder) Build() (*LogAgent, error) {
 		}
 	}
 
-	if b.config != nil && len(b.configFiles) > 0 {
+	var remoteVersion *remoteConfigVersion
+	if b.remoteConfig != nil && (b.config != nil || len(b.configFiles) > 0) {
+		return nil, errors.NewError("agent can be built WithRemoteConfig or from a local config, but not both", "")
+	} else if b.config != nil && len(b.configFiles) > 0 {
 		return nil, errors.NewError("agent can be built WithConfig or WithConfigFiles, but not both", "")
+	} else if b.remoteConfig != nil {
+		if b.databaseFile == "" {
+			b.logger.Warn("Remote config is used without a database, so the last known good config is not cached, and the agent can not start while the remote source is unreachable")
+		}
+		b.config, remoteVersion, err = initialRemoteConfig(context.Background(), b.remoteConfig, db, b.logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "read remote config")
+		}
 	} else if b.config == nil && len(b.configFiles) == 0 {
-		return nil, errors.NewError("agent cannot be built without WithConfig or WithConfigFiles", "")
+		return nil, errors.NewError("agent cannot be built without WithConfig, WithConfigFiles, or WithRemoteConfig", "")
 	} else if len(b.configFiles) > 0 {
 		b.config, err = NewConfigFromGlobs(b.configFiles)
 		if err != nil {
@@ -94,9 +115,24 @@ func (b *LogAgentBuilder) Build() (*LogAgent, error) {
 		return nil, err
 	}
 
-	return &LogAgent{
+	if remoteVersion != nil {
+		if err := saveRemoteConfig(db, remoteVersion); err != nil {
+			b.logger.Warnw("Failed to cache remote config", zap.Any("error", err))
+		}
+	}
+
+	agent := &LogAgent{
 		pipeline:      pipeline,
 		database:      db,
+		config:        b.config,
+		configFiles:   b.configFiles,
+		pluginDir:     b.pluginDir,
+		buildContext:  buildContext,
+		defaultOutput: b.defaultOutput,
+		remoteConfig:  b.remoteConfig,
+		remoteVersion: remoteVersion,
 		SugaredLogger: b.logger,
-	}, nil
+	}
+	agent.updateCheckedOperators()
+	return agent, nil
 }
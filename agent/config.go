@@ -1,64 +1,97 @@
 package agent
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"time"
 
+	"github.com/observiq/stanza/interpolate"
+	"github.com/observiq/stanza/operator/helper"
 	"github.com/observiq/stanza/pipeline"
-	yaml "gopkg.in/yaml.v2"
 )
 
+// DefaultShutdownTimeout is how long the agent waits for the pipeline to flush
+// its entries on shutdown, if no shutdown_timeout is configured.
+const DefaultShutdownTimeout = 10 * time.Second
+
 // Config is the configuration of the stanza log agent.
 type Config struct {
-	Pipeline pipeline.Config `json:"pipeline"                yaml:"pipeline"`
+	Pipeline        pipeline.Config  `json:"pipeline"                   yaml:"pipeline"`
+	ShutdownTimeout *helper.Duration `json:"shutdown_timeout,omitempty" yaml:"shutdown_timeout,omitempty"`
+
+	// secrets are the values resolved from references in the config files
+	secrets interpolate.Secrets
+	// includes are the patterns of the files included by the config files
+	includes []string
 }
 
-// NewConfigFromFile will create a new agent config from a YAML file.
-func NewConfigFromFile(file string) (*Config, error) {
-	contents, err := ioutil.ReadFile(file) // #nosec - configs load based on user specified directory
-	if err != nil {
-		return nil, fmt.Errorf("could not find config file: %s", err)
+// MarshalJSON marshals the config to JSON, replacing any values resolved from
+// references with the references, so that secrets are not exposed when the
+// config is logged or rendered.
+func (c Config) MarshalJSON() ([]byte, error) {
+	type rawConfig Config
+	marshalled, err := json.Marshal(rawConfig(c))
+	if err != nil || len(c.secrets) == 0 {
+		return marshalled, err
+	}
+
+	var masked interface{}
+	if err := json.Unmarshal(marshalled, &masked); err != nil {
+		return nil, err
 	}
+	return json.Marshal(c.secrets.Mask(masked))
+}
 
-	config := Config{}
-	if err := yaml.UnmarshalStrict(contents, &config); err != nil {
-		return nil, fmt.Errorf("failed to read config file as yaml: %s", err)
+// shutdownTimeout returns the configured shutdown timeout, or the default if none is configured
+func (c *Config) shutdownTimeout() time.Duration {
+	if c == nil || c.ShutdownTimeout == nil {
+		return DefaultShutdownTimeout
 	}
+	return c.ShutdownTimeout.Raw()
+}
 
-	return &config, nil
+// NewConfigFromFile will create a new agent config from a YAML file, and the
+// files it includes.
+func NewConfigFromFile(file string) (*Config, error) {
+	l := newConfigLoader()
+	l.load(file)
+	l.resolve()
+	return checkLoadedConfig(l.config())
 }
 
 // NewConfigFromGlobs will create an agent config from multiple files matching a pattern.
 func NewConfigFromGlobs(globs []string) (*Config, error) {
-	paths := make([]string, 0, len(globs))
-	for _, glob := range globs {
-		matches, err := filepath.Glob(glob)
-		if err != nil {
-			return nil, err
-		}
-		paths = append(paths, matches...)
-	}
+	return checkLoadedConfig(loadConfigFromGlobs(globs))
+}
 
-	if len(paths) == 0 {
-		return nil, fmt.Errorf("No config files found")
+// checkLoadedConfig returns the first problem found loading a config, if any
+func checkLoadedConfig(config *Config, positions []configPosition, problems []ConfigError) (*Config, error) {
+	if len(problems) != 0 {
+		return nil, fmt.Errorf("failed to load config: %s", problems[0])
 	}
 
-	config := &Config{}
-	for _, path := range paths {
-		newConfig, err := NewConfigFromFile(path)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load config from %s: %s", path, err)
+	for i, operatorConfig := range config.Pipeline {
+		if invalid, ok := operatorConfig.Builder.(*invalidBuilder); ok {
+			problem := ConfigError{File: positions[i].file, Line: positions[i].line, OperatorID: invalid.ID(), Err: invalid.err}
+			return nil, fmt.Errorf("failed to load config: %s", problem)
 		}
-
-		config = mergeConfigs(config, newConfig)
 	}
-
 	return config, nil
 }
 
-// mergeConfigs will merge two agent configs.
+// mergeConfigs will merge two agent configs. A shutdown timeout in src
+// replaces the one in dst.
 func mergeConfigs(dst *Config, src *Config) *Config {
 	dst.Pipeline = append(dst.Pipeline, src.Pipeline...)
+	if len(src.secrets) != 0 {
+		if dst.secrets == nil {
+			dst.secrets = interpolate.Secrets{}
+		}
+		dst.secrets.Merge(src.secrets)
+	}
+	dst.includes = append(dst.includes, src.includes...)
+	if src.ShutdownTimeout != nil {
+		dst.ShutdownTimeout = src.ShutdownTimeout
+	}
 	return dst
 }
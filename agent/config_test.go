@@ -1,12 +1,16 @@
 package agent
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/observiq/stanza/operator"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/noop"
+	"github.com/observiq/stanza/operator/helper"
 	"github.com/observiq/stanza/pipeline"
 	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/require"
@@ -27,6 +31,48 @@ pipeline:
 	require.Equal(t, len(config.Pipeline), 1)
 }
 
+func TestNewConfigFromFileWithReferences(t *testing.T) {
+	os.Setenv("AGENT_TEST_OPERATOR_ID", "secret_id")
+	defer os.Unsetenv("AGENT_TEST_OPERATOR_ID")
+
+	tempDir := testutil.NewTempDir(t)
+	configFile := filepath.Join(tempDir, "config.yaml")
+	configContents := `
+shutdown_timeout: ${env:AGENT_TEST_SHUTDOWN_TIMEOUT:-5s}
+pipeline:
+  - id: ${env:AGENT_TEST_OPERATOR_ID}
+    type: noop
+`
+	err := ioutil.WriteFile(configFile, []byte(configContents), 0755)
+	require.NoError(t, err)
+
+	config, err := NewConfigFromFile(configFile)
+	require.NoError(t, err)
+	require.Equal(t, "secret_id", config.Pipeline[0].ID())
+	require.Equal(t, 5*time.Second, config.shutdownTimeout())
+
+	marshalled, err := json.Marshal(config)
+	require.NoError(t, err)
+	require.NotContains(t, string(marshalled), "secret_id")
+	require.Contains(t, string(marshalled), `"id":"${env:AGENT_TEST_OPERATOR_ID}"`)
+}
+
+func TestNewConfigFromFileWithMissingReference(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	configFile := filepath.Join(tempDir, "config.yaml")
+	configContents := `
+pipeline:
+  - id: ${env:AGENT_TEST_UNSET:?must be set}
+    type: noop
+`
+	err := ioutil.WriteFile(configFile, []byte(configContents), 0755)
+	require.NoError(t, err)
+
+	_, err = NewConfigFromFile(configFile)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 3: ${env:AGENT_TEST_UNSET}: must be set")
+}
+
 func TestNewConfigWithMissingFile(t *testing.T) {
 	tempDir := testutil.NewTempDir(t)
 	configFile := filepath.Join(tempDir, "config.yaml")
@@ -114,3 +160,30 @@ func TestMergeConfigs(t *testing.T) {
 	config3 := mergeConfigs(&config1, &config2)
 	require.Equal(t, len(config3.Pipeline), 2)
 }
+
+func TestMergeConfigsShutdownTimeout(t *testing.T) {
+	timeout := helper.NewDuration(time.Minute)
+	config1 := Config{ShutdownTimeout: &timeout}
+	config2 := Config{}
+
+	config3 := mergeConfigs(&config1, &config2)
+	require.Equal(t, time.Minute, config3.shutdownTimeout())
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	configFile := filepath.Join(tempDir, "config.yaml")
+	configContents := `
+shutdown_timeout: 30s
+pipeline:
+  - type: noop
+`
+	err := ioutil.WriteFile(configFile, []byte(configContents), 0755)
+	require.NoError(t, err)
+
+	config, err := NewConfigFromFile(configFile)
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, config.shutdownTimeout())
+
+	require.Equal(t, DefaultShutdownTimeout, (&Config{}).shutdownTimeout())
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"github.com/observiq/stanza/operator"
+)
+
+// agentCheckKey is the key of problems with the agent itself, rather than one of its operators
+const agentCheckKey = "agent"
+
+// checkState is the state of the agent that the health and readiness checks read.
+// It is kept apart from the agent lock, which is held while the pipeline is stopped,
+// drained or reloaded, so that probes are answered while that happens.
+type checkState struct {
+	started   bool
+	stopped   bool
+	operators []operator.Operator
+}
+
+// updateChecks updates whether the agent has started and stopped in the state read by
+// the health and readiness checks. The agent lock must be held when calling this.
+func (a *LogAgent) updateChecks() {
+	a.checksMux.Lock()
+	defer a.checksMux.Unlock()
+	a.checks.started = a.started
+	a.checks.stopped = a.stopped
+}
+
+// updateCheckedOperators replaces the operators in the state read by the health and
+// readiness checks with those of the current pipeline. The agent lock must be held
+// when calling this.
+func (a *LogAgent) updateCheckedOperators() {
+	operators := a.pipeline.Operators()
+
+	a.checksMux.Lock()
+	defer a.checksMux.Unlock()
+	a.checks.operators = operators
+}
+
+// checkState returns the state read by the health and readiness checks
+func (a *LogAgent) checkState() checkState {
+	a.checksMux.Lock()
+	defer a.checksMux.Unlock()
+	return a.checks
+}
+
+// Health checks whether the agent is stuck in a way that restarting it may fix. It returns
+// the reason for each failed check, keyed by operator id, so the agent is healthy if the
+// result is empty.
+func (a *LogAgent) Health() map[string]string {
+	return healthProblems(a.checkState())
+}
+
+// Readiness checks whether the agent is running and every operator is able to do its
+// work. It returns the reason for each failed check, keyed by operator id, so the agent
+// is ready if the result is empty. An agent that is not healthy is not ready.
+func (a *LogAgent) Readiness() map[string]string {
+	state := a.checkState()
+	problems := healthProblems(state)
+	if !state.started {
+		problems[agentCheckKey] = "agent has not started"
+	}
+
+	for _, op := range state.operators {
+		if _, ok := problems[op.ID()]; ok {
+			continue
+		}
+		if checker, ok := op.(operator.ReadinessChecker); ok {
+			if err := checker.Ready(); err != nil {
+				problems[op.ID()] = err.Error()
+			}
+		}
+	}
+	return problems
+}
+
+// healthProblems returns the reason for each failed health check
+func healthProblems(state checkState) map[string]string {
+	problems := make(map[string]string)
+	if state.stopped {
+		problems[agentCheckKey] = "agent has stopped"
+	}
+
+	for _, op := range state.operators {
+		if checker, ok := op.(operator.HealthChecker); ok {
+			if err := checker.Healthy(); err != nil {
+				problems[op.ID()] = err.Error()
+			}
+		}
+	}
+	return problems
+}
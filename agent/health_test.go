@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type checkedOperator struct {
+	*testutil.Operator
+	healthErr error
+	readyErr  error
+}
+
+func (c *checkedOperator) Healthy() error { return c.healthErr }
+func (c *checkedOperator) Ready() error   { return c.readyErr }
+
+func newHealthTestAgent(operators ...operator.Operator) *LogAgent {
+	pipeline := &testutil.Pipeline{}
+	pipeline.On("Operators").Return(operators)
+	agent := &LogAgent{
+		SugaredLogger: zap.NewNop().Sugar(),
+		pipeline:      pipeline,
+		started:       true,
+	}
+	agent.updateChecks()
+	agent.updateCheckedOperators()
+	return agent
+}
+
+func TestHealth(t *testing.T) {
+	t.Run("Healthy", func(t *testing.T) {
+		op := &checkedOperator{Operator: testutil.NewMockOperator("op"), readyErr: fmt.Errorf("unreachable")}
+		agent := newHealthTestAgent(op, testutil.NewMockOperator("unchecked"))
+		require.Empty(t, agent.Health())
+	})
+
+	t.Run("Unhealthy", func(t *testing.T) {
+		op := &checkedOperator{Operator: testutil.NewMockOperator("op"), healthErr: fmt.Errorf("wedged")}
+		agent := newHealthTestAgent(op)
+		require.Equal(t, map[string]string{"op": "wedged"}, agent.Health())
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		agent := newHealthTestAgent()
+		agent.stopped = true
+		agent.updateChecks()
+		require.Equal(t, map[string]string{"agent": "agent has stopped"}, agent.Health())
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("Ready", func(t *testing.T) {
+		op := &checkedOperator{Operator: testutil.NewMockOperator("op")}
+		agent := newHealthTestAgent(op, testutil.NewMockOperator("unchecked"))
+		require.Empty(t, agent.Readiness())
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		op := &checkedOperator{Operator: testutil.NewMockOperator("op"), readyErr: fmt.Errorf("unreachable")}
+		agent := newHealthTestAgent(op)
+		require.Equal(t, map[string]string{"op": "unreachable"}, agent.Readiness())
+	})
+
+	t.Run("Unhealthy", func(t *testing.T) {
+		op := &checkedOperator{
+			Operator:  testutil.NewMockOperator("op"),
+			healthErr: fmt.Errorf("wedged"),
+			readyErr:  fmt.Errorf("unreachable"),
+		}
+		agent := newHealthTestAgent(op)
+		require.Equal(t, map[string]string{"op": "wedged"}, agent.Readiness())
+	})
+
+	t.Run("NotStarted", func(t *testing.T) {
+		agent := newHealthTestAgent()
+		agent.started = false
+		agent.updateChecks()
+		require.Equal(t, map[string]string{"agent": "agent has not started"}, agent.Readiness())
+	})
+}
+
+func TestChecksDoNotWaitForAgentLock(t *testing.T) {
+	op := &checkedOperator{Operator: testutil.NewMockOperator("op")}
+	agent := newHealthTestAgent(op)
+
+	// The agent lock is held while the pipeline is stopped, drained or reloaded
+	agent.mux.Lock()
+	defer agent.mux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.Empty(t, agent.Health())
+		require.Empty(t, agent.Readiness())
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for checks")
+	}
+}
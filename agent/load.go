@@ -0,0 +1,534 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/observiq/stanza/interpolate"
+	"github.com/observiq/stanza/operator"
+	yaml "gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+const (
+	includeKey   = "include"
+	fragmentsKey = "fragments"
+	overridesKey = "overrides"
+	pipelineKey  = "pipeline"
+
+	// fragmentKey is the key used within a map to merge in named fragments
+	fragmentKey = "$fragment"
+)
+
+// configLoader reads config files and the files they include, and combines them
+// into a single config.
+//
+// Files are loaded in order, with the files included by a file loaded before the
+// file itself, and each file is only loaded once. Fragments are resolved once all
+// files are loaded, so a fragment defined in a later file replaces one of the same
+// name defined in an earlier file, wherever it is used. Overrides are applied to
+// the pipeline in the order they are loaded, after fragments are resolved.
+type configLoader struct {
+	files     []*loadedFile
+	fragments map[string]*yaml3.Node
+	overrides []configOverride
+	includes  []string
+	problems  []ConfigError
+	secrets   interpolate.Secrets
+
+	// remote is set when loading a config fetched from a remote source, which can
+	// not include or reference local files
+	remote bool
+
+	loaded  map[string]bool
+	loading map[string]bool
+}
+
+// loadedFile holds the contents of a single config file
+type loadedFile struct {
+	path     string
+	fields   []*yaml3.Node
+	pipeline []*pipelineItem
+}
+
+// pipelineItem is the config of an operator in the pipeline of a config file
+type pipelineItem struct {
+	node *yaml3.Node
+	// err is the problem found while loading the item, if any
+	err error
+}
+
+// configOverride is a set of fields that override the config of an operator
+type configOverride struct {
+	file       string
+	operatorID *yaml3.Node
+	fields     *yaml3.Node
+}
+
+// newConfigLoader creates a config loader with no files loaded
+func newConfigLoader() *configLoader {
+	return &configLoader{
+		fragments: make(map[string]*yaml3.Node),
+		secrets:   interpolate.Secrets{},
+		loaded:    make(map[string]bool),
+		loading:   make(map[string]bool),
+	}
+}
+
+// loadConfigFromGlobs loads the config files matching the globs, and the files they
+// include, returning the config they describe, the position of each item in its
+// pipeline, and any problems found
+func loadConfigFromGlobs(globs []string) (*Config, []configPosition, []ConfigError) {
+	l := newConfigLoader()
+	matched := false
+	for _, glob := range globs {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			l.problem(glob, 0, err)
+			continue
+		}
+		for _, match := range matches {
+			matched = true
+			l.load(match)
+		}
+	}
+
+	if !matched {
+		l.problems = append(l.problems, ConfigError{Err: fmt.Errorf("No config files found")})
+		return &Config{}, nil, l.problems
+	}
+
+	l.resolve()
+	return l.config()
+}
+
+// load reads a config file, after the files it includes
+func (l *configLoader) load(path string) {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	if l.loading[key] {
+		l.problem(path, 0, fmt.Errorf("file includes itself"))
+		return
+	}
+	if l.loaded[key] {
+		return
+	}
+	l.loaded[key] = true
+	l.loading[key] = true
+	defer delete(l.loading, key)
+
+	contents, err := ioutil.ReadFile(path) // #nosec - configs load based on user specified directory
+	if err != nil {
+		l.problem(path, 0, fmt.Errorf("could not find config file: %s", err))
+		return
+	}
+	l.loadContents(path, contents)
+}
+
+// loadContents reads the contents of a config file, after the files it includes
+func (l *configLoader) loadContents(path string, contents []byte) {
+	var document yaml3.Node
+	if err := yaml3.Unmarshal(contents, &document); err != nil {
+		l.problem(path, 0, fmt.Errorf("failed to read config file as yaml: %s", err))
+		return
+	}
+
+	file := &loadedFile{path: path}
+	if len(document.Content) == 0 {
+		l.files = append(l.files, file)
+		return
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml3.MappingNode {
+		l.problem(path, root.Line, fmt.Errorf("failed to read config file as yaml: config must be a map"))
+		return
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key, value := root.Content[i], root.Content[i+1]; key.Value == includeKey {
+			l.loadIncludes(path, value)
+		}
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case includeKey:
+		case fragmentsKey:
+			l.loadFragments(path, value)
+		case overridesKey:
+			l.loadOverrides(path, value)
+		case pipelineKey:
+			if value.Kind != yaml3.SequenceNode {
+				l.problem(path, value.Line, fmt.Errorf("failed to read config file as yaml: pipeline must be a list of operators"))
+				continue
+			}
+			for _, node := range value.Content {
+				item := &pipelineItem{node: node}
+				item.err = l.interpolate(node)
+				file.pipeline = append(file.pipeline, item)
+			}
+		default:
+			field := &yaml3.Node{Kind: yaml3.MappingNode, Content: []*yaml3.Node{key, value}}
+			if err := l.interpolate(field); err != nil {
+				l.problem(path, key.Line, err)
+				continue
+			}
+			file.fields = append(file.fields, field)
+		}
+	}
+
+	l.files = append(l.files, file)
+}
+
+// interpolate substitutes the references in the values of a node. Remote configs
+// can not reference files, which would send their contents to the destinations
+// of the config.
+func (l *configLoader) interpolate(node *yaml3.Node) error {
+	return interpolate.Interpolator{DisableFiles: l.remote}.Node(node, l.secrets)
+}
+
+// loadIncludes loads the files matching the include patterns of a file. Relative
+// patterns are relative to the directory of the file.
+func (l *configLoader) loadIncludes(path string, value *yaml3.Node) {
+	patterns := []*yaml3.Node{value}
+	if value.Kind == yaml3.SequenceNode {
+		patterns = value.Content
+	}
+
+	for _, pattern := range patterns {
+		if l.remote {
+			l.problem(path, pattern.Line, fmt.Errorf("include is not supported in remote configs"))
+			continue
+		}
+		if pattern.Kind != yaml3.ScalarNode {
+			l.problem(path, pattern.Line, fmt.Errorf("failed to read config file as yaml: include must be a list of paths"))
+			continue
+		}
+		if err := l.interpolate(pattern); err != nil {
+			l.problem(path, pattern.Line, err)
+			continue
+		}
+
+		glob := pattern.Value
+		if !filepath.IsAbs(glob) {
+			glob = filepath.Join(filepath.Dir(path), glob)
+		}
+		l.includes = append(l.includes, glob)
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			l.problem(path, pattern.Line, err)
+			continue
+		}
+		if len(matches) == 0 {
+			l.problem(path, pattern.Line, fmt.Errorf("include '%s' does not match any files", pattern.Value))
+			continue
+		}
+		for _, match := range matches {
+			l.load(match)
+		}
+	}
+}
+
+// loadFragments records the named fragments defined in a file
+func (l *configLoader) loadFragments(path string, value *yaml3.Node) {
+	if value.Kind != yaml3.MappingNode {
+		l.problem(path, value.Line, fmt.Errorf("failed to read config file as yaml: fragments must be a map of names to fragments"))
+		return
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		name, fragment := value.Content[i], value.Content[i+1]
+		if fragment.Kind != yaml3.MappingNode {
+			l.problem(path, fragment.Line, fmt.Errorf("fragment '%s' must be a map", name.Value))
+			continue
+		}
+		if err := l.interpolate(fragment); err != nil {
+			l.problem(path, fragment.Line, err)
+			continue
+		}
+		l.fragments[name.Value] = fragment
+	}
+}
+
+// loadOverrides records the operator overrides defined in a file
+func (l *configLoader) loadOverrides(path string, value *yaml3.Node) {
+	if value.Kind != yaml3.MappingNode {
+		l.problem(path, value.Line, fmt.Errorf("failed to read config file as yaml: overrides must be a map of operator ids to fields"))
+		return
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		operatorID, fields := value.Content[i], value.Content[i+1]
+		if fields.Kind != yaml3.MappingNode {
+			l.problem(path, fields.Line, fmt.Errorf("override for operator '%s' must be a map", operatorID.Value))
+			continue
+		}
+		if err := l.interpolate(fields); err != nil {
+			l.problem(path, fields.Line, err)
+			continue
+		}
+		l.overrides = append(l.overrides, configOverride{file: path, operatorID: operatorID, fields: fields})
+	}
+}
+
+// problem records a problem found while loading a file
+func (l *configLoader) problem(path string, line int, err error) {
+	l.problems = append(l.problems, ConfigError{File: path, Line: line, Err: err})
+}
+
+// resolve resolves the fragments used in the pipeline and overrides, then applies
+// the overrides to the pipeline
+func (l *configLoader) resolve() {
+	for _, file := range l.files {
+		for _, item := range file.pipeline {
+			if item.err == nil {
+				item.err = l.resolveFragments(item.node, map[string]bool{})
+			}
+		}
+	}
+
+	for _, override := range l.overrides {
+		if err := l.resolveFragments(override.fields, map[string]bool{}); err != nil {
+			l.problem(override.file, override.fields.Line, err)
+			continue
+		}
+
+		matched := false
+		for _, file := range l.files {
+			for _, item := range file.pipeline {
+				if itemOperatorID(item.node) != override.operatorID.Value {
+					continue
+				}
+				matched = true
+				if item.err == nil {
+					mergeNodes(item.node, override.fields)
+				}
+			}
+		}
+		if !matched {
+			l.problem(override.file, override.operatorID.Line, fmt.Errorf("override for operator '%s' does not match any operator in the pipeline", override.operatorID.Value))
+		}
+	}
+}
+
+// resolveFragments replaces each map in a node that contains a $fragment key with
+// the named fragments, merged with the other fields of the map
+func (l *configLoader) resolveFragments(node *yaml3.Node, resolving map[string]bool) error {
+	switch node.Kind {
+	case yaml3.SequenceNode:
+		for _, child := range node.Content {
+			if err := l.resolveFragments(child, resolving); err != nil {
+				return err
+			}
+		}
+	case yaml3.MappingNode:
+		if err := l.mergeFragments(node, resolving); err != nil {
+			return err
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := l.resolveFragments(node.Content[i], resolving); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeFragments merges the fragments named by the $fragment key of a map into it.
+// Fields of the map take precedence over fields of the fragments, and later fragments
+// take precedence over earlier ones.
+func (l *configLoader) mergeFragments(node *yaml3.Node, resolving map[string]bool) error {
+	index := mappingIndex(node, fragmentKey)
+	if index < 0 {
+		return nil
+	}
+
+	ref := node.Content[index+1]
+	names := []*yaml3.Node{ref}
+	if ref.Kind == yaml3.SequenceNode {
+		names = ref.Content
+	}
+
+	merged := &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		fragment, ok := l.fragments[name.Value]
+		switch {
+		case name.Kind != yaml3.ScalarNode:
+			return fmt.Errorf("line %d: %s must be the name of a fragment or a list of names", name.Line, fragmentKey)
+		case !ok:
+			return fmt.Errorf("line %d: fragment '%s' is not defined", name.Line, name.Value)
+		case resolving[name.Value]:
+			return fmt.Errorf("line %d: fragment '%s' uses itself", name.Line, name.Value)
+		}
+
+		fragment = copyNode(fragment)
+		resolving[name.Value] = true
+		err := l.resolveFragments(fragment, resolving)
+		delete(resolving, name.Value)
+		if err != nil {
+			return err
+		}
+		mergeNodes(merged, fragment)
+	}
+
+	local := &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map"}
+	local.Content = append(append(local.Content, node.Content[:index]...), node.Content[index+2:]...)
+	mergeNodes(merged, local)
+	node.Content = merged.Content
+	return nil
+}
+
+// mergeNodes merges the fields of the map src into the map dst. Maps are merged
+// recursively, a null value removes the field from dst, and any other value
+// replaces the value in dst.
+func mergeNodes(dst, src *yaml3.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		index := mappingIndex(dst, key.Value)
+		switch {
+		case value.Tag == "!!null" && index >= 0:
+			dst.Content = append(dst.Content[:index], dst.Content[index+2:]...)
+		case value.Tag == "!!null":
+		case index < 0:
+			dst.Content = append(dst.Content, copyNode(key), copyNode(value))
+		case value.Kind == yaml3.MappingNode && dst.Content[index+1].Kind == yaml3.MappingNode:
+			mergeNodes(dst.Content[index+1], value)
+		default:
+			dst.Content[index+1] = copyNode(value)
+		}
+	}
+}
+
+// mappingIndex returns the index of the key in a map node, or -1 if it is not present
+func mappingIndex(node *yaml3.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyNode returns a deep copy of a node
+func copyNode(node *yaml3.Node) *yaml3.Node {
+	copied := *node
+	copied.Content = make([]*yaml3.Node, 0, len(node.Content))
+	for _, child := range node.Content {
+		copied.Content = append(copied.Content, copyNode(child))
+	}
+	return &copied
+}
+
+// itemOperatorID returns the id of the operator configured by a pipeline item,
+// which defaults to its type
+func itemOperatorID(node *yaml3.Node) string {
+	var fields struct {
+		ID   string `yaml:"id"`
+		Type string `yaml:"type"`
+	}
+	_ = node.Decode(&fields)
+	if fields.ID == "" {
+		return fields.Type
+	}
+	return fields.ID
+}
+
+// config decodes the loaded files into a config, returning the position of each
+// item in the pipeline, and any problems found. Items in the pipeline that can not
+// be decoded are replaced by configs that fail to build with the error, so that the
+// pipeline keeps its shape.
+func (l *configLoader) config() (*Config, []configPosition, []ConfigError) {
+	config := &Config{}
+	positions := make([]configPosition, 0)
+	for _, file := range l.files {
+		fileConfig := &Config{}
+		for _, field := range file.fields {
+			if err := decodeNode(field, fileConfig); err != nil {
+				l.problem(file.path, field.Content[0].Line, err)
+			}
+		}
+
+		for _, item := range file.pipeline {
+			var operatorConfig operator.Config
+			err := item.err
+			if err == nil {
+				err = decodeNode(item.node, &operatorConfig)
+			}
+			if err != nil {
+				operatorConfig = operator.Config{Builder: newInvalidBuilder(item.node, err)}
+			}
+			fileConfig.Pipeline = append(fileConfig.Pipeline, operatorConfig)
+			positions = append(positions, configPosition{file: file.path, line: item.node.Line})
+		}
+
+		config = mergeConfigs(config, fileConfig)
+	}
+
+	config.secrets = l.secrets
+	config.includes = l.includes
+	return config, positions, l.problems
+}
+
+// snippetLineRegex matches the line numbers in errors from decoding a single node,
+// which are relative to the node rather than the file
+var snippetLineRegex = regexp.MustCompile(`\n\s*line \d+: `)
+
+// decodeNode strictly decodes a yaml node into v, in the same way that config files
+// are decoded when the agent starts
+func decodeNode(node *yaml3.Node, v interface{}) error {
+	var raw interface{}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	bytes, err := yaml3.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.UnmarshalStrict(bytes, v); err != nil {
+		return fmt.Errorf("%s", snippetLineRegex.ReplaceAllString(err.Error(), " "))
+	}
+	return nil
+}
+
+// invalidBuilder stands in for a pipeline item that could not be decoded
+type invalidBuilder struct {
+	id           string
+	operatorType string
+	err          error
+}
+
+// newInvalidBuilder creates an invalidBuilder with the id and type of a pipeline item, if it has them
+func newInvalidBuilder(item *yaml3.Node, err error) *invalidBuilder {
+	var fields struct {
+		ID   string `yaml:"id"`
+		Type string `yaml:"type"`
+	}
+	_ = item.Decode(&fields)
+	return &invalidBuilder{id: fields.ID, operatorType: fields.Type, err: err}
+}
+
+// ID returns the id of the item, or its type if it has no id
+func (b *invalidBuilder) ID() string {
+	if b.id == "" {
+		return b.operatorType
+	}
+	return b.id
+}
+
+// Type returns the type of the item
+func (b *invalidBuilder) Type() string {
+	return b.operatorType
+}
+
+// Build returns the error the item could not be decoded with
+func (b *invalidBuilder) Build(_ operator.BuildContext) ([]operator.Operator, error) {
+	return nil, b.err
+}
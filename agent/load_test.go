@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFiles writes config files to a temp directory, returning the directory
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	tempDir := testutil.NewTempDir(t)
+	for name, contents := range files {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0755))
+	}
+	return tempDir
+}
+
+// pipelineFields returns the fields of each operator config in a pipeline, as they
+// are marshalled to JSON
+func pipelineFields(t *testing.T, config *Config) []map[string]interface{} {
+	marshalled, err := json.Marshal(config.Pipeline)
+	require.NoError(t, err)
+
+	fields := make([]map[string]interface{}, 0)
+	require.NoError(t, json.Unmarshal(marshalled, &fields))
+	return fields
+}
+
+func TestLoadConfigInclude(t *testing.T) {
+	tempDir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `
+include:
+  - base/*.yaml
+  - extra.yaml
+pipeline:
+  - id: app
+    type: noop
+`,
+		"base/a.yaml": `
+shutdown_timeout: 5s
+pipeline:
+  - id: base_a
+    type: noop
+`,
+		"base/b.yaml": `
+include: ../extra.yaml
+pipeline:
+  - id: base_b
+    type: noop
+`,
+		"extra.yaml": `
+pipeline:
+  - id: extra
+    type: noop
+`,
+	})
+
+	config, err := NewConfigFromFile(filepath.Join(tempDir, "config.yaml"))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(config.Pipeline))
+	for _, operatorConfig := range config.Pipeline {
+		ids = append(ids, operatorConfig.ID())
+	}
+	require.Equal(t, []string{"base_a", "extra", "base_b", "app"}, ids)
+	require.Equal(t, 5*time.Second, config.shutdownTimeout())
+	require.Equal(t, []string{
+		filepath.Join(tempDir, "base/*.yaml"),
+		filepath.Join(tempDir, "extra.yaml"),
+		filepath.Join(tempDir, "extra.yaml"),
+	}, config.includes)
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			"Cycle",
+			map[string]string{
+				"config.yaml": "include: other.yaml\n",
+				"other.yaml":  "include: config.yaml\n",
+			},
+			"config.yaml: file includes itself",
+		},
+		{
+			"NoMatches",
+			map[string]string{
+				"config.yaml": "include:\n  - missing/*.yaml\n",
+			},
+			"config.yaml:2: include 'missing/*.yaml' does not match any files",
+		},
+		{
+			"NotAList",
+			map[string]string{
+				"config.yaml": "include:\n  nested: map\n",
+			},
+			"config.yaml:2: failed to read config file as yaml: include must be a list of paths",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := writeConfigFiles(t, tc.files)
+			_, err := NewConfigFromFile(filepath.Join(tempDir, "config.yaml"))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestLoadConfigFragments(t *testing.T) {
+	tempDir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `
+include: fragments.yaml
+fragments:
+  generated:
+    $fragment: [record, labelled]
+    count: 1
+pipeline:
+  - id: first
+    type: generate_input
+    $fragment: generated
+    entry:
+      labels:
+        env: test
+  - id: second
+    type: generate_input
+    $fragment: [generated]
+    count: 2
+`,
+		"fragments.yaml": `
+fragments:
+  record:
+    entry:
+      record: base
+  labelled:
+    entry:
+      labels:
+        team: platform
+`,
+	})
+
+	config, err := NewConfigFromFile(filepath.Join(tempDir, "config.yaml"))
+	require.NoError(t, err)
+
+	fields := pipelineFields(t, config)
+	require.Len(t, fields, 2)
+	require.Equal(t, float64(1), fields[0]["count"])
+	require.Equal(t, map[string]interface{}{
+		"record": "base",
+		"labels": map[string]interface{}{"team": "platform", "env": "test"},
+	}, filterKeys(fields[0]["entry"], "record", "labels"))
+	require.Equal(t, float64(2), fields[1]["count"])
+	require.Equal(t, map[string]interface{}{
+		"record": "base",
+		"labels": map[string]interface{}{"team": "platform"},
+	}, filterKeys(fields[1]["entry"], "record", "labels"))
+}
+
+func TestLoadConfigFragmentErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		contents string
+		expected string
+	}{
+		{
+			"Undefined",
+			`
+pipeline:
+  - type: noop
+    $fragment: missing
+`,
+			"config.yaml:3: noop: line 4: fragment 'missing' is not defined",
+		},
+		{
+			"Cycle",
+			`
+fragments:
+  a:
+    $fragment: b
+  b:
+    $fragment: a
+pipeline:
+  - type: noop
+    $fragment: a
+`,
+			"config.yaml:8: noop: line 6: fragment 'a' uses itself",
+		},
+		{
+			"NotAMap",
+			`
+fragments:
+  output: stdout
+`,
+			"config.yaml:3: fragment 'output' must be a map",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := writeConfigFiles(t, map[string]string{"config.yaml": tc.contents})
+			_, err := NewConfigFromFile(filepath.Join(tempDir, "config.yaml"))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	tempDir := writeConfigFiles(t, map[string]string{
+		"base.yaml": `
+fragments:
+  generated:
+    count: 5
+pipeline:
+  - id: generator
+    type: generate_input
+    $fragment: generated
+    entry:
+      record: base
+      labels:
+        team: platform
+        env: prod
+  - type: noop
+`,
+		"app.yaml": `
+include: base.yaml
+overrides:
+  generator:
+    entry:
+      labels:
+        env: test
+        team: null
+    count: 1
+  noop:
+    output: generator
+`,
+		"later.yaml": `
+overrides:
+  generator:
+    count: 2
+`,
+	})
+
+	config, err := NewConfigFromGlobs([]string{
+		filepath.Join(tempDir, "app.yaml"),
+		filepath.Join(tempDir, "later.yaml"),
+	})
+	require.NoError(t, err)
+
+	fields := pipelineFields(t, config)
+	require.Len(t, fields, 2)
+	require.Equal(t, float64(2), fields[0]["count"])
+	require.Equal(t, map[string]interface{}{
+		"record": "base",
+		"labels": map[string]interface{}{"env": "test"},
+	}, filterKeys(fields[0]["entry"], "record", "labels"))
+	require.Equal(t, []interface{}{"generator"}, fields[1]["output"])
+}
+
+func TestLoadConfigOverrideUnknownOperator(t *testing.T) {
+	tempDir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `
+pipeline:
+  - type: noop
+overrides:
+  missing:
+    output: noop
+`,
+	})
+
+	_, err := NewConfigFromFile(filepath.Join(tempDir, "config.yaml"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "config.yaml:5: override for operator 'missing' does not match any operator in the pipeline")
+}
+
+// filterKeys returns a map containing only the given keys of a decoded JSON map
+func filterKeys(value interface{}, keys ...string) map[string]interface{} {
+	fields, _ := value.(map[string]interface{})
+	filtered := make(map[string]interface{})
+	for _, key := range keys {
+		if v, ok := fields[key]; ok {
+			filtered[key] = v
+		}
+	}
+	return filtered
+}
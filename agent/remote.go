@@ -0,0 +1,367 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/observiq/stanza/database"
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+const (
+	// SignatureHeader is the response header that holds the base64 encoded ed25519
+	// signature of a remote config
+	SignatureHeader = "X-Stanza-Signature"
+
+	// VersionHeader is the response header that holds the version of a remote config.
+	// Versions are unsigned integers that increase with each new config, such as the
+	// unix time at which it was published.
+	VersionHeader = "X-Stanza-Config-Version"
+
+	// DefaultRemoteConfigTimeout is how long a request for a remote config can take,
+	// if no client is configured
+	DefaultRemoteConfigTimeout = 10 * time.Second
+
+	// maxRemoteConfigSize is the largest remote config that will be read
+	maxRemoteConfigSize = 16 * 1024 * 1024
+)
+
+// RemoteConfigBucket is the database bucket that holds the last known good remote config
+var RemoteConfigBucket = []byte(`remote_config`)
+
+var (
+	remoteConfigKey    = []byte(`config`)
+	remoteSignatureKey = []byte(`signature`)
+	remoteETagKey      = []byte(`etag`)
+	remoteVersionKey   = []byte(`version`)
+)
+
+// RemoteConfig is a source of the agent's config that is served over HTTP. Each
+// config is sent with its version in the X-Stanza-Config-Version header, and must be
+// signed together with its version with the private key matching PublicKey, with the
+// signature sent in the X-Stanza-Signature header of the response.
+type RemoteConfig struct {
+	// URL is the address the config is fetched from
+	URL string
+	// PublicKey verifies the signature of each config
+	PublicKey ed25519.PublicKey
+	// PollInterval is how often the config is fetched while the agent runs. Disabled if 0.
+	PollInterval time.Duration
+	// Client is the client used to fetch the config. If nil, a client with the
+	// default timeout is used.
+	Client *http.Client
+}
+
+// ParsePublicKey parses an ed25519 public key, either PEM encoded or as the base64
+// encoding of the raw key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %s", err)
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key must be an ed25519 key, got %T", key)
+		}
+		return publicKey, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %s", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// client returns the client used to fetch the config
+func (r *RemoteConfig) client() *http.Client {
+	if r.Client == nil {
+		return &http.Client{Timeout: DefaultRemoteConfigTimeout}
+	}
+	return r.Client
+}
+
+// fetch requests the config from the remote source. If etag is set and the config
+// has not changed, it returns nil.
+func (r *RemoteConfig) fetch(ctx context.Context, etag string) (*remoteConfigVersion, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %s", err)
+	}
+	if len(body) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("config is larger than %d bytes", maxRemoteConfigSize)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(resp.Header.Get(SignatureHeader))
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %s", err)
+	}
+
+	number, err := parseConfigVersion(resp.Header.Get(VersionHeader))
+	if err != nil {
+		return nil, err
+	}
+
+	version := &remoteConfigVersion{
+		config:    body,
+		signature: signature,
+		etag:      resp.Header.Get("ETag"),
+		version:   number,
+	}
+	if err := version.verify(r.PublicKey); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// parseConfigVersion parses the version of a remote config
+func parseConfigVersion(value string) (uint64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("config version is missing from the %s header", VersionHeader)
+	}
+	version, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config version must be an unsigned integer, got '%s'", value)
+	}
+	return version, nil
+}
+
+// remoteConfigVersion is a single version of a remote config
+type remoteConfigVersion struct {
+	config    []byte
+	signature []byte
+	etag      string
+	version   uint64
+}
+
+// signedPayload returns the payload that is signed, which is the version of the
+// config on its own line, followed by the config. Signing the version means that
+// an older config can not be replayed with a newer version.
+func (v *remoteConfigVersion) signedPayload() []byte {
+	payload := []byte(strconv.FormatUint(v.version, 10) + "\n")
+	return append(payload, v.config...)
+}
+
+// verify checks that the config and its version were signed by the private key matching publicKey
+func (v *remoteConfigVersion) verify(publicKey ed25519.PublicKey) error {
+	switch {
+	case len(v.signature) == 0:
+		return fmt.Errorf("config is not signed")
+	case len(publicKey) != ed25519.PublicKeySize:
+		return fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	case !ed25519.Verify(publicKey, v.signedPayload(), v.signature):
+		return fmt.Errorf("config signature is invalid")
+	}
+	return nil
+}
+
+// checkNewer returns an error if the version is older than current. A config with
+// the same version as current must be the same config.
+func (v *remoteConfigVersion) checkNewer(current *remoteConfigVersion) error {
+	switch {
+	case current == nil || v.version > current.version:
+		return nil
+	case v.version < current.version:
+		return fmt.Errorf("config version %d is older than the current version %d", v.version, current.version)
+	case !bytes.Equal(v.config, current.config):
+		return fmt.Errorf("config version %d differs from the current config with the same version", v.version)
+	}
+	return nil
+}
+
+// parse reads the agent config in the version
+func (v *remoteConfigVersion) parse(source string) (*Config, error) {
+	l := newConfigLoader()
+	l.remote = true
+	l.loadContents(source, v.config)
+	l.resolve()
+	return checkLoadedConfig(l.config())
+}
+
+// saveRemoteConfig caches a remote config in the database, as the last known good config
+func saveRemoteConfig(db database.Database, version *remoteConfigVersion) error {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(RemoteConfigBucket)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(remoteConfigKey, version.config); err != nil {
+			return err
+		}
+		if err := bucket.Put(remoteSignatureKey, version.signature); err != nil {
+			return err
+		}
+		if err := bucket.Put(remoteETagKey, []byte(version.etag)); err != nil {
+			return err
+		}
+		return bucket.Put(remoteVersionKey, []byte(strconv.FormatUint(version.version, 10)))
+	})
+	if err != nil {
+		return err
+	}
+	return db.Sync()
+}
+
+// loadRemoteConfig returns the remote config cached in the database, or nil if there is none
+func loadRemoteConfig(db database.Database) (*remoteConfigVersion, error) {
+	var version *remoteConfigVersion
+	err := db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(RemoteConfigBucket)
+		if bucket == nil || bucket.Get(remoteConfigKey) == nil {
+			return nil
+		}
+		number, err := parseConfigVersion(string(bucket.Get(remoteVersionKey)))
+		if err != nil {
+			return fmt.Errorf("cached %s", err)
+		}
+		// Values are only valid for the life of the transaction, so they are copied
+		version = &remoteConfigVersion{
+			config:    append([]byte{}, bucket.Get(remoteConfigKey)...),
+			signature: append([]byte{}, bucket.Get(remoteSignatureKey)...),
+			etag:      string(bucket.Get(remoteETagKey)),
+			version:   number,
+		}
+		return nil
+	})
+	return version, err
+}
+
+// initialRemoteConfig fetches the config the agent starts with. If the remote source
+// can not be reached, or serves a config that can not be read or is older than the
+// last known good config cached in the database, the cached config is used instead.
+func initialRemoteConfig(ctx context.Context, remote *RemoteConfig, db database.Database, logger *zap.SugaredLogger) (*Config, *remoteConfigVersion, error) {
+	cached, cacheErr := loadRemoteConfig(db)
+	if cacheErr == nil && cached != nil {
+		// The cache is verified in case the database was modified
+		cacheErr = cached.verify(remote.PublicKey)
+	}
+
+	version, err := remote.fetch(ctx, "")
+	if err == nil && cacheErr == nil {
+		err = version.checkNewer(cached)
+	}
+	if err == nil {
+		var config *Config
+		if config, err = version.parse(remote.URL); err == nil {
+			return config, version, nil
+		}
+	}
+	logger.Warnw("Failed to fetch remote config, falling back to the cached config", zap.Any("error", err))
+
+	switch {
+	case cacheErr != nil:
+		return nil, nil, fmt.Errorf("fetch remote config: %s, and read cached config: %s", err, cacheErr)
+	case cached == nil:
+		return nil, nil, fmt.Errorf("fetch remote config: %s, and no cached config exists", err)
+	}
+
+	config, err := cached.parse(remote.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read cached config: %s", err)
+	}
+	return config, cached, nil
+}
+
+// WatchRemoteConfig polls the agent's remote config source at its poll interval,
+// and applies each new config it serves. If the source can not be reached, or a
+// config fails to apply, the current pipeline keeps running. It blocks until the
+// context is cancelled.
+func (a *LogAgent) WatchRemoteConfig(ctx context.Context) {
+	if a.remoteConfig == nil || a.remoteConfig.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.remoteConfig.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Errors are logged by pollRemoteConfig, and the current pipeline keeps running
+		_ = a.pollRemoteConfig(ctx)
+	}
+}
+
+// pollRemoteConfig fetches the remote config, and applies it if it has changed.
+// Once applied, it is cached in the database as the last known good config.
+func (a *LogAgent) pollRemoteConfig(ctx context.Context) error {
+	a.remoteMux.Lock()
+	defer a.remoteMux.Unlock()
+
+	etag := ""
+	if a.remoteVersion != nil {
+		etag = a.remoteVersion.etag
+	}
+
+	version, err := a.remoteConfig.fetch(ctx, etag)
+	if err != nil {
+		a.Errorw("Failed to fetch remote config", zap.Any("error", err))
+		return err
+	}
+	if version == nil || (a.remoteVersion != nil && bytes.Equal(version.config, a.remoteVersion.config)) {
+		return nil
+	}
+	if err := version.checkNewer(a.remoteVersion); err != nil {
+		a.Errorw("Rejected remote config", zap.Any("error", err))
+		return err
+	}
+
+	cfg, err := version.parse(a.remoteConfig.URL)
+	if err != nil {
+		a.Errorw("Failed to read remote config", zap.Any("error", err))
+		return err
+	}
+
+	a.Info("Detected change in remote config")
+	if err := a.ReloadConfig(cfg); err != nil {
+		return err
+	}
+
+	a.remoteVersion = version
+	if err := saveRemoteConfig(a.database, version); err != nil {
+		a.Warnw("Failed to cache remote config", zap.Any("error", err))
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/observiq/stanza/database"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// remoteConfigServer serves a signed config, with an ETag of its version
+type remoteConfigServer struct {
+	*httptest.Server
+	privateKey ed25519.PrivateKey
+
+	mux       sync.Mutex
+	config    string
+	version   string
+	signature string
+	requests  int
+	status    int
+}
+
+func newRemoteConfigServer(t *testing.T) (*remoteConfigServer, ed25519.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	s := &remoteConfigServer{privateKey: privateKey, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.requests++
+
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		if r.Header.Get("If-None-Match") == s.version {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.version)
+		w.Header().Set(VersionHeader, strings.Trim(s.version, `"`))
+		w.Header().Set(SignatureHeader, s.signature)
+		_, _ = w.Write([]byte(s.config))
+	}))
+	t.Cleanup(s.Close)
+	return s, publicKey
+}
+
+// serve sets the config served, signed with the server's key
+func (s *remoteConfigServer) serve(version, config string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.version = `"` + version + `"`
+	s.config = config
+	s.signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, []byte(version+"\n"+config)))
+}
+
+func (s *remoteConfigServer) setStatus(status int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.status = status
+}
+
+func TestRemoteConfigFetch(t *testing.T) {
+	server, publicKey := newRemoteConfigServer(t)
+	server.serve("1", "pipeline:\n  - type: noop\n")
+	remote := &RemoteConfig{URL: server.URL, PublicKey: publicKey}
+
+	version, err := remote.fetch(context.Background(), "")
+	require.NoError(t, err)
+	require.Equal(t, `"1"`, version.etag)
+
+	config, err := version.parse(remote.URL)
+	require.NoError(t, err)
+	require.Len(t, config.Pipeline, 1)
+
+	version, err = remote.fetch(context.Background(), `"1"`)
+	require.NoError(t, err)
+	require.Nil(t, version)
+}
+
+func TestRemoteConfigFetchErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(*remoteConfigServer)
+		expected string
+	}{
+		{
+			"Unsigned",
+			func(s *remoteConfigServer) { s.signature = "" },
+			"config is not signed",
+		},
+		{
+			"InvalidSignature",
+			func(s *remoteConfigServer) { s.config = "pipeline:\n  - type: stdout\n" },
+			"config signature is invalid",
+		},
+		{
+			"MissingVersion",
+			func(s *remoteConfigServer) { s.version = `""` },
+			"config version is missing",
+		},
+		{
+			"ReplayedVersion",
+			func(s *remoteConfigServer) { s.version = `"2"` },
+			"config signature is invalid",
+		},
+		{
+			"UnexpectedStatus",
+			func(s *remoteConfigServer) { s.status = http.StatusInternalServerError },
+			"unexpected response status: 500 Internal Server Error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, publicKey := newRemoteConfigServer(t)
+			server.serve("1", "pipeline:\n  - type: noop\n")
+			tc.modify(server)
+
+			remote := &RemoteConfig{URL: server.URL, PublicKey: publicKey}
+			_, err := remote.fetch(context.Background(), "")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestRemoteConfigRejectsIncludes(t *testing.T) {
+	version := &remoteConfigVersion{config: []byte("include: /etc/stanza/*.yaml\n")}
+	_, err := version.parse("http://localhost/config.yaml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "include is not supported in remote configs")
+}
+
+func TestRemoteConfigRejectsFileReferences(t *testing.T) {
+	version := &remoteConfigVersion{config: []byte("pipeline:\n  - type: noop\n    id: ${file:/etc/passwd}\n")}
+	_, err := version.parse("http://localhost/config.yaml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "file references are disabled")
+}
+
+func TestBuildAgentWithRemoteConfigFallback(t *testing.T) {
+	server, publicKey := newRemoteConfigServer(t)
+	server.serve("1", "pipeline:\n  - id: remote\n    type: noop\n")
+	remote := &RemoteConfig{URL: server.URL, PublicKey: publicKey}
+	databaseFile := filepath.Join(testutil.NewTempDir(t), "stanza.db")
+
+	build := func() (*LogAgent, error) {
+		return NewBuilder(zap.NewNop().Sugar()).
+			WithRemoteConfig(remote).
+			WithDatabaseFile(databaseFile).
+			WithDefaultOutput(testutil.NewFakeOutput(t)).
+			Build()
+	}
+
+	agent, err := build()
+	require.NoError(t, err)
+	require.Equal(t, "remote", agent.Config().Pipeline[0].ID())
+	require.NoError(t, agent.database.Close())
+
+	// The cached config is used when the server can not be reached
+	server.Close()
+	agent, err = build()
+	require.NoError(t, err)
+	require.Equal(t, "remote", agent.Config().Pipeline[0].ID())
+	require.Equal(t, `"1"`, agent.remoteVersion.etag)
+	require.NoError(t, agent.database.Close())
+}
+
+func TestBuildAgentWithRemoteConfigOlderThanCache(t *testing.T) {
+	server, publicKey := newRemoteConfigServer(t)
+	server.serve("2", "pipeline:\n  - id: newer\n    type: noop\n")
+	remote := &RemoteConfig{URL: server.URL, PublicKey: publicKey}
+	databaseFile := filepath.Join(testutil.NewTempDir(t), "stanza.db")
+
+	build := func() (*LogAgent, error) {
+		return NewBuilder(zap.NewNop().Sugar()).
+			WithRemoteConfig(remote).
+			WithDatabaseFile(databaseFile).
+			WithDefaultOutput(testutil.NewFakeOutput(t)).
+			Build()
+	}
+
+	agent, err := build()
+	require.NoError(t, err)
+	require.NoError(t, agent.database.Close())
+
+	// A replayed older config is rejected in favor of the cached config
+	server.serve("1", "pipeline:\n  - id: older\n    type: noop\n")
+	agent, err = build()
+	require.NoError(t, err)
+	require.Equal(t, "newer", agent.Config().Pipeline[0].ID())
+	require.Equal(t, uint64(2), agent.remoteVersion.version)
+	require.NoError(t, agent.database.Close())
+}
+
+func TestBuildAgentWithRemoteConfigNoCache(t *testing.T) {
+	server, publicKey := newRemoteConfigServer(t)
+	server.setStatus(http.StatusServiceUnavailable)
+
+	_, err := NewBuilder(zap.NewNop().Sugar()).
+		WithRemoteConfig(&RemoteConfig{URL: server.URL, PublicKey: publicKey}).
+		Build()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no cached config exists")
+}
+
+func TestBuildAgentWithRemoteAndLocalConfig(t *testing.T) {
+	_, err := NewBuilder(zap.NewNop().Sugar()).
+		WithConfig(&Config{}).
+		WithRemoteConfig(&RemoteConfig{URL: "http://localhost"}).
+		Build()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not both")
+}
+
+func TestPollRemoteConfig(t *testing.T) {
+	server, publicKey := newRemoteConfigServer(t)
+	server.serve("1", "pipeline:\n  - id: first\n    type: noop\n")
+
+	agent, err := NewBuilder(zap.NewNop().Sugar()).
+		WithRemoteConfig(&RemoteConfig{URL: server.URL, PublicKey: publicKey}).
+		WithDefaultOutput(testutil.NewFakeOutput(t)).
+		Build()
+	require.NoError(t, err)
+	require.NoError(t, agent.Start())
+	defer agent.Stop()
+
+	// An unchanged config is not applied again
+	require.NoError(t, agent.pollRemoteConfig(context.Background()))
+	require.Equal(t, "first", agent.Config().Pipeline[0].ID())
+
+	server.serve("2", "pipeline:\n  - id: second\n    type: noop\n")
+	require.NoError(t, agent.Reload())
+	require.Equal(t, "second", agent.Config().Pipeline[0].ID())
+	require.Equal(t, `"2"`, agent.remoteVersion.etag)
+
+	// An older config is not applied, even though it is validly signed
+	server.serve("1", "pipeline:\n  - id: first\n    type: noop\n")
+	err = agent.pollRemoteConfig(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "config version 1 is older than the current version 2")
+	require.Equal(t, "second", agent.Config().Pipeline[0].ID())
+
+	// The current pipeline keeps running when the server can not be reached
+	server.setStatus(http.StatusBadGateway)
+	require.Error(t, agent.pollRemoteConfig(context.Background()))
+	require.Equal(t, "second", agent.Config().Pipeline[0].ID())
+}
+
+func TestParsePublicKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	parsed, err := ParsePublicKey([]byte(base64.StdEncoding.EncodeToString(publicKey) + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, publicKey, parsed)
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	parsed, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	require.Equal(t, publicKey, parsed)
+
+	_, err = ParsePublicKey([]byte("c2hvcnQ="))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "public key must be 32 bytes, got 5")
+}
+
+func TestLoadRemoteConfigStubDatabase(t *testing.T) {
+	version, err := loadRemoteConfig(database.NewStubDatabase())
+	require.NoError(t, err)
+	require.Nil(t, version)
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"sort"
+
+	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/operator"
+)
+
+const (
+	// OperatorRunning is the state of an operator that is running
+	OperatorRunning = "running"
+	// OperatorPaused is the state of an operator that has been paused
+	OperatorPaused = "paused"
+	// OperatorStopped is the state of an operator in an agent that is not running
+	OperatorStopped = "stopped"
+)
+
+// OperatorStatus describes the runtime state of an operator in the agent's pipeline
+type OperatorStatus struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	Outputs      []string               `json:"outputs,omitempty"`
+	State        string                 `json:"state"`
+	Backpressure bool                   `json:"backpressure"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+}
+
+// Config returns the config of the agent's current pipeline
+func (a *LogAgent) Config() *Config {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.config
+}
+
+// Render renders the agent's current pipeline as a dot graph
+func (a *LogAgent) Render() ([]byte, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.pipeline.Render()
+}
+
+// OperatorStatuses returns the status of each operator in the agent's pipeline, sorted by id
+func (a *LogAgent) OperatorStatuses() []OperatorStatus {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	operators := a.pipeline.Operators()
+	statuses := make([]OperatorStatus, 0, len(operators))
+	for _, op := range operators {
+		statuses = append(statuses, a.operatorStatus(op))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// OperatorStatus returns the status of the operator with the supplied id, and
+// false if there is no such operator in the agent's pipeline
+func (a *LogAgent) OperatorStatus(id string) (OperatorStatus, bool) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	op, ok := a.findOperator(id)
+	if !ok {
+		return OperatorStatus{}, false
+	}
+	return a.operatorStatus(op), true
+}
+
+// SetOperatorPaused pauses or resumes the operator with the supplied id
+func (a *LogAgent) SetOperatorPaused(id string, paused bool) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if !a.started || a.stopped {
+		return errors.NewError("operators can only be paused while the agent is running", "")
+	}
+
+	op, ok := a.findOperator(id)
+	if !ok {
+		return errors.NewError("operator does not exist", "", "operator_id", id)
+	}
+
+	pauser, ok := op.(operator.Pauser)
+	if !ok {
+		return errors.NewError("operator can not be paused", "only inputs can be paused", "operator_id", id)
+	}
+
+	if paused {
+		pauser.Pause()
+		a.Infow("Paused operator", "operator_id", id)
+	} else {
+		pauser.Resume()
+		a.Infow("Resumed operator", "operator_id", id)
+	}
+	return nil
+}
+
+// findOperator returns the operator with the supplied id. The agent lock must be
+// held when calling this.
+func (a *LogAgent) findOperator(id string) (operator.Operator, bool) {
+	for _, op := range a.pipeline.Operators() {
+		if op.ID() == id {
+			return op, true
+		}
+	}
+	return nil, false
+}
+
+// operatorStatus returns the status of an operator. The agent lock must be held
+// when calling this.
+func (a *LogAgent) operatorStatus(op operator.Operator) OperatorStatus {
+	status := OperatorStatus{
+		ID:    op.ID(),
+		Type:  op.Type(),
+		State: OperatorRunning,
+	}
+
+	for _, output := range op.Outputs() {
+		status.Outputs = append(status.Outputs, output.ID())
+	}
+
+	if !a.started || a.stopped {
+		status.State = OperatorStopped
+	} else if pauser, ok := op.(operator.Pauser); ok && pauser.Paused() {
+		status.State = OperatorPaused
+	}
+
+	if reporter, ok := op.(operator.BackpressureReporter); ok {
+		if signal := reporter.Backpressure(); signal != nil {
+			status.Backpressure = signal.Pressured()
+		}
+	}
+
+	if inspector, ok := op.(operator.Inspector); ok {
+		status.Details = inspector.Inspect()
+	}
+
+	return status
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type pausableOperator struct {
+	*testutil.Operator
+	paused bool
+}
+
+func (p *pausableOperator) Pause()       { p.paused = true }
+func (p *pausableOperator) Resume()      { p.paused = false }
+func (p *pausableOperator) Paused() bool { return p.paused }
+
+type bufferedOperator struct {
+	*testutil.Operator
+	signal *operator.Backpressure
+}
+
+func (b *bufferedOperator) Backpressure() *operator.Backpressure {
+	return b.signal
+}
+
+func (b *bufferedOperator) Inspect() map[string]interface{} {
+	return map[string]interface{}{"buffered_entries": 5}
+}
+
+func newStatusTestAgent() (*LogAgent, *pausableOperator, *bufferedOperator) {
+	output := &bufferedOperator{testutil.NewMockOperator("output"), operator.NewBackpressure()}
+	output.On("Type").Return("test_output")
+	output.On("Outputs").Return(nil)
+
+	input := &pausableOperator{Operator: testutil.NewMockOperator("input")}
+	input.On("Type").Return("test_input")
+	input.On("Outputs").Return([]operator.Operator{output})
+
+	pipeline := &testutil.Pipeline{}
+	pipeline.On("Operators").Return([]operator.Operator{output, input})
+
+	agent := &LogAgent{
+		SugaredLogger: zap.NewNop().Sugar(),
+		pipeline:      pipeline,
+		started:       true,
+	}
+	return agent, input, output
+}
+
+func TestOperatorStatuses(t *testing.T) {
+	agent, input, output := newStatusTestAgent()
+	input.Pause()
+	output.signal.Set(true)
+
+	expected := []OperatorStatus{
+		{
+			ID:      "input",
+			Type:    "test_input",
+			Outputs: []string{"output"},
+			State:   OperatorPaused,
+		},
+		{
+			ID:           "output",
+			Type:         "test_output",
+			State:        OperatorRunning,
+			Backpressure: true,
+			Details:      map[string]interface{}{"buffered_entries": 5},
+		},
+	}
+	require.Equal(t, expected, agent.OperatorStatuses())
+
+	agent.stopped = true
+	status, ok := agent.OperatorStatus("input")
+	require.True(t, ok)
+	require.Equal(t, OperatorStopped, status.State)
+
+	_, ok = agent.OperatorStatus("missing")
+	require.False(t, ok)
+}
+
+func TestSetOperatorPaused(t *testing.T) {
+	t.Run("Input", func(t *testing.T) {
+		agent, input, _ := newStatusTestAgent()
+		require.NoError(t, agent.SetOperatorPaused("input", true))
+		require.True(t, input.Paused())
+		require.NoError(t, agent.SetOperatorPaused("input", false))
+		require.False(t, input.Paused())
+	})
+
+	t.Run("NotPausable", func(t *testing.T) {
+		agent, _, _ := newStatusTestAgent()
+		err := agent.SetOperatorPaused("output", true)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can not be paused")
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		agent, _, _ := newStatusTestAgent()
+		err := agent.SetOperatorPaused("missing", true)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not exist")
+	})
+
+	t.Run("NotRunning", func(t *testing.T) {
+		agent, _, _ := newStatusTestAgent()
+		agent.started = false
+		err := agent.SetOperatorPaused("input", true)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "while the agent is running")
+	})
+}
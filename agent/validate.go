@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/observiq/stanza/operator"
+)
+
+// ConfigError is a problem found in a config file, along with its position
+type ConfigError struct {
+	File       string
+	Line       int
+	OperatorID string
+	Err        error
+}
+
+// Error returns the error message of the problem, prefixed with its position
+func (e ConfigError) Error() string {
+	parts := make([]string, 0, 3)
+	switch {
+	case e.File != "" && e.Line > 0:
+		parts = append(parts, fmt.Sprintf("%s:%d", e.File, e.Line))
+	case e.File != "":
+		parts = append(parts, e.File)
+	}
+	if e.OperatorID != "" {
+		parts = append(parts, e.OperatorID)
+	}
+	parts = append(parts, e.Err.Error())
+	return strings.Join(parts, ": ")
+}
+
+// configPosition is the position of a pipeline item in the config files
+type configPosition struct {
+	file string
+	line int
+}
+
+// ValidateConfigFromGlobs reads the config files matching the globs and builds the
+// pipeline they describe without starting it. Rather than stopping at the first
+// error, it returns every problem found, with the file and line it was found at,
+// ordered by position.
+func ValidateConfigFromGlobs(globs []string, bc operator.BuildContext) []ConfigError {
+	config, positions, problems := loadConfigFromGlobs(globs)
+
+	for _, problem := range config.Pipeline.Validate(bc) {
+		configProblem := ConfigError{OperatorID: problem.OperatorID, Err: problem.Err}
+		if problem.Index >= 0 {
+			configProblem.File = positions[problem.Index].file
+			configProblem.Line = positions[problem.Index].line
+		}
+		problems = append(problems, configProblem)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
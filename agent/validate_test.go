@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/observiq/stanza/operator/builtin/input/generate"
+	_ "github.com/observiq/stanza/operator/builtin/output/drop"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfigFromGlobs(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	first := filepath.Join(tempDir, "a.yaml")
+	firstContents := `shutdown_timeout: 5s
+unknown_key: true
+pipeline:
+  - type: noop
+    unknown_field: 1
+  - id: second
+    type: noop
+    if: "invalid ==="
+  - id: third
+    type: noop
+    output: missing
+`
+	require.NoError(t, ioutil.WriteFile(first, []byte(firstContents), 0755))
+
+	second := filepath.Join(tempDir, "b.yaml")
+	secondContents := `pipeline:
+  - id: fourth
+    type: noop
+  - type: unknown_type
+`
+	require.NoError(t, ioutil.WriteFile(second, []byte(secondContents), 0755))
+
+	problems := ValidateConfigFromGlobs([]string{filepath.Join(tempDir, "*.yaml")}, testutil.NewBuildContext(t))
+	messages := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+
+	require.Len(t, messages, 5, messages)
+	require.Equal(t, first+":2: yaml: unmarshal errors: field unknown_key not found in type agent.Config", messages[0])
+	require.Equal(t, first+":4: $.noop: unmarshal to noop: yaml: unmarshal errors: field unknown_field not found in type noop.NoopOperatorConfig", messages[1])
+	require.Contains(t, messages[2], first+":6: $.second: failed to compile expression")
+	require.Equal(t, first+":9: $.third: operator '$.missing' does not exist", messages[3])
+	require.Equal(t, second+":4: $.unknown_type: unsupported type 'unknown_type'", messages[4])
+}
+
+func TestValidateConfigFromGlobsValid(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	configFile := filepath.Join(tempDir, "config.yaml")
+	configContents := `
+pipeline:
+  - type: generate_input
+    entry:
+      record: test
+  - type: noop
+  - type: drop_output
+`
+	require.NoError(t, ioutil.WriteFile(configFile, []byte(configContents), 0755))
+
+	problems := ValidateConfigFromGlobs([]string{configFile}, testutil.NewBuildContext(t))
+	require.Empty(t, problems)
+}
+
+func TestValidateConfigFromGlobsNoMatches(t *testing.T) {
+	tempDir := testutil.NewTempDir(t)
+	problems := ValidateConfigFromGlobs([]string{filepath.Join(tempDir, "*.yaml")}, testutil.NewBuildContext(t))
+	require.Len(t, problems, 1)
+	require.Equal(t, "No config files found", problems[0].Error())
+}
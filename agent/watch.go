@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchConfigFiles polls the agent's config files, and the files they include, at
+// the supplied interval and reloads the agent whenever a file is added, removed,
+// or modified. It blocks until the context is cancelled.
+func (a *LogAgent) WatchConfigFiles(ctx context.Context, interval time.Duration) {
+	if len(a.configFiles) == 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := configFileStates(a.watchedConfigFiles())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := configFileStates(a.watchedConfigFiles())
+		if current.equal(previous) {
+			continue
+		}
+		previous = current
+
+		a.Info("Detected change in config files")
+		// Errors are logged by Reload, and the current pipeline keeps running
+		_ = a.Reload()
+	}
+}
+
+// watchedConfigFiles returns the globs of the agent's config files, along with the
+// patterns of the files included by its current config
+func (a *LogAgent) watchedConfigFiles() []string {
+	globs := append([]string{}, a.configFiles...)
+	if config := a.Config(); config != nil {
+		globs = append(globs, config.includes...)
+	}
+	return globs
+}
+
+// fileStates maps file paths to their modification time and size
+type fileStates map[string]fileState
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// configFileStates returns the current state of all files matching the globs.
+func configFileStates(globs []string) fileStates {
+	states := make(fileStates)
+	for _, glob := range globs {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			states[match] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
+
+func (s fileStates) equal(other fileStates) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for path, state := range s {
+		otherState, ok := other[path]
+		if !ok || !state.modTime.Equal(otherState.modTime) || state.size != otherState.size {
+			return false
+		}
+	}
+	return true
+}
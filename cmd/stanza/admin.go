@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/observiq/stanza/agent"
+	"go.uber.org/zap"
+)
+
+// adminAgent is the part of the agent exposed by the admin API
+type adminAgent interface {
+	Config() *agent.Config
+	Render() ([]byte, error)
+	OperatorStatuses() []agent.OperatorStatus
+	OperatorStatus(id string) (agent.OperatorStatus, bool)
+	SetOperatorPaused(id string, paused bool) error
+	healthAgent
+}
+
+// startAdmin will serve the agent's admin API on the configured address and port.
+// It returns a wait group that is done once the server has shut down.
+func startAdmin(ctx context.Context, flags *RootFlags, a adminAgent, logger *zap.SugaredLogger) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	if flags.AdminPort == 0 {
+		return wg
+	}
+
+	srv := http.Server{
+		Addr:              adminAddress(flags),
+		Handler:           newAdminHandler(a),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("Admin server failed", zap.Error(err))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warnw("Errored shutting down admin server", zap.Error(err))
+		}
+	}()
+
+	return wg
+}
+
+// adminAddress returns the address the admin API listens on. The API has no
+// authentication, so it is only served on the loopback interface unless
+// another address is set explicitly.
+func adminAddress(flags *RootFlags) string {
+	host := flags.AdminAddress
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(flags.AdminPort))
+}
+
+// newAdminHandler returns an http handler that serves the admin API of the agent
+func newAdminHandler(a adminAgent) http.Handler {
+	mux := http.NewServeMux()
+	handleChecks(mux, a)
+
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, a.Config())
+	})
+
+	mux.HandleFunc("/graph", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		dotGraph, err := a.Render()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		_, _ = w.Write(append(dotGraph, '\n'))
+	})
+
+	mux.HandleFunc("/operators", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, a.OperatorStatuses())
+	})
+
+	mux.HandleFunc("/operators/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/operators/")
+		action := ""
+		if i := strings.LastIndex(id, "/"); i != -1 {
+			id, action = id[:i], id[i+1:]
+		}
+
+		if _, ok := a.OperatorStatus(id); !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("operator '%s' does not exist", id))
+			return
+		}
+
+		switch action {
+		case "":
+			if !allowMethod(w, r, http.MethodGet) {
+				return
+			}
+		case "pause", "resume":
+			if !allowMethod(w, r, http.MethodPost) {
+				return
+			}
+			if err := a.SetOperatorPaused(id, action == "pause"); err != nil {
+				writeError(w, http.StatusConflict, err)
+				return
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		status, _ := a.OperatorStatus(id)
+		writeJSON(w, http.StatusOK, status)
+	})
+
+	return mux
+}
+
+// allowMethod responds with an error and returns false if the request does not use the supplied method
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+	return false
+}
+
+// writeCheck writes the result of a health or readiness check, responding with
+// 503 Service Unavailable if any check failed
+func writeCheck(w http.ResponseWriter, problems map[string]string) {
+	if len(problems) == 0 {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"status":   "failing",
+		"problems": problems,
+	})
+}
+
+// writeJSON writes a value to the response as JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error to the response as JSON
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/agent"
+	"github.com/observiq/stanza/pipeline"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+type fakeAdminAgent struct {
+	statuses  map[string]agent.OperatorStatus
+	health    map[string]string
+	readiness map[string]string
+}
+
+func (f *fakeAdminAgent) Config() *agent.Config {
+	return &agent.Config{Pipeline: pipeline.Config{}}
+}
+
+func (f *fakeAdminAgent) Render() ([]byte, error) {
+	return []byte("strict digraph G {}"), nil
+}
+
+func (f *fakeAdminAgent) OperatorStatuses() []agent.OperatorStatus {
+	return []agent.OperatorStatus{f.statuses["input"], f.statuses["output"]}
+}
+
+func (f *fakeAdminAgent) OperatorStatus(id string) (agent.OperatorStatus, bool) {
+	status, ok := f.statuses[id]
+	return status, ok
+}
+
+func (f *fakeAdminAgent) SetOperatorPaused(id string, paused bool) error {
+	if id != "input" {
+		return fmt.Errorf("operator can not be paused")
+	}
+	status := f.statuses[id]
+	status.State = agent.OperatorRunning
+	if paused {
+		status.State = agent.OperatorPaused
+	}
+	f.statuses[id] = status
+	return nil
+}
+
+func (f *fakeAdminAgent) Health() map[string]string {
+	return f.health
+}
+
+func (f *fakeAdminAgent) Readiness() map[string]string {
+	return f.readiness
+}
+
+func newFakeAdminAgent() *fakeAdminAgent {
+	return &fakeAdminAgent{
+		statuses: map[string]agent.OperatorStatus{
+			"input":  {ID: "input", Type: "file_input", State: agent.OperatorRunning, Outputs: []string{"output"}},
+			"output": {ID: "output", Type: "stdout", State: agent.OperatorRunning},
+		},
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	cases := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Config", http.MethodGet, "/config", http.StatusOK, `{"pipeline":[]}`},
+		{"Graph", http.MethodGet, "/graph", http.StatusOK, "strict digraph G {}\n"},
+		{"Operators", http.MethodGet, "/operators", http.StatusOK, `[{"id":"input","type":"file_input","outputs":["output"],"state":"running","backpressure":false},{"id":"output","type":"stdout","state":"running","backpressure":false}]`},
+		{"Operator", http.MethodGet, "/operators/output", http.StatusOK, `{"id":"output","type":"stdout","state":"running","backpressure":false}`},
+		{"OperatorMissing", http.MethodGet, "/operators/missing", http.StatusNotFound, `{"error":"operator 'missing' does not exist"}`},
+		{"Pause", http.MethodPost, "/operators/input/pause", http.StatusOK, `{"id":"input","type":"file_input","outputs":["output"],"state":"paused","backpressure":false}`},
+		{"Resume", http.MethodPost, "/operators/input/resume", http.StatusOK, `{"id":"input","type":"file_input","outputs":["output"],"state":"running","backpressure":false}`},
+		{"PauseNotPausable", http.MethodPost, "/operators/output/pause", http.StatusConflict, `{"error":"operator can not be paused"}`},
+		{"PauseWrongMethod", http.MethodGet, "/operators/input/pause", http.StatusMethodNotAllowed, `{"error":"method GET is not allowed"}`},
+		{"UnknownAction", http.MethodPost, "/operators/input/restart", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := newAdminHandler(newFakeAdminAgent())
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatus, rec.Code)
+			if json.Valid([]byte(tc.expectedBody)) {
+				require.JSONEq(t, tc.expectedBody, rec.Body.String())
+			} else {
+				require.Equal(t, tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminAPIOperatorIDWithNamespace(t *testing.T) {
+	a := newFakeAdminAgent()
+	a.statuses["$.plugin.input"] = agent.OperatorStatus{ID: "$.plugin.input", Type: "file_input", State: agent.OperatorRunning}
+	handler := newAdminHandler(a)
+
+	req := httptest.NewRequest(http.MethodGet, "/operators/$.plugin.input", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdminAPIHealthChecks(t *testing.T) {
+	a := newFakeAdminAgent()
+	a.readiness = map[string]string{"$.google_cloud_output": "failed to flush entries: unreachable"}
+	handler := newAdminHandler(a)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"status":"failing","problems":{"$.google_cloud_output":"failed to flush entries: unreachable"}}`, rec.Body.String())
+}
+
+func TestAdminAddress(t *testing.T) {
+	require.Equal(t, "127.0.0.1:8080", adminAddress(&RootFlags{AdminPort: 8080}))
+	require.Equal(t, "127.0.0.1:8080", adminAddress(&RootFlags{AdminPort: 8080, AdminAddress: "127.0.0.1"}))
+	require.Equal(t, "0.0.0.0:8080", adminAddress(&RootFlags{AdminPort: 8080, AdminAddress: "0.0.0.0"}))
+	require.Equal(t, "[::1]:8080", adminAddress(&RootFlags{AdminPort: 8080, AdminAddress: "::1"}))
+}
+
+func TestStartHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := newFakeAdminAgent()
+	a.readiness = map[string]string{"agent": "agent has not started"}
+	wg := startHealth(ctx, &RootFlags{HealthPort: 18081}, a, zaptest.NewLogger(t).Sugar())
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = http.Get("http://127.0.0.1:18081/healthz")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err := http.Get("http://127.0.0.1:18081/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// Only the probes are served, not the rest of the admin API
+	resp, err = http.Get("http://127.0.0.1:18081/config")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// healthAgent is the part of the agent exposed by the health probes
+type healthAgent interface {
+	Health() map[string]string
+	Readiness() map[string]string
+}
+
+// startHealth will serve the agent's health and readiness probes on the configured port.
+// The probes expose nothing but the result of the checks, so unlike the admin API they
+// are served on all interfaces, where a kubelet can reach them.
+// It returns a wait group that is done once the server has shut down.
+func startHealth(ctx context.Context, flags *RootFlags, a healthAgent, logger *zap.SugaredLogger) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	if flags.HealthPort == 0 {
+		return wg
+	}
+
+	mux := http.NewServeMux()
+	handleChecks(mux, a)
+
+	srv := http.Server{
+		Addr:              fmt.Sprintf(":%d", flags.HealthPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("Health server failed", zap.Error(err))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warnw("Errored shutting down health server", zap.Error(err))
+		}
+	}()
+
+	return wg
+}
+
+// handleChecks registers the /healthz and /readyz probes of the agent on the mux
+func handleChecks(mux *http.ServeMux, a healthAgent) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeCheck(w, a.Health())
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeCheck(w, a.Readiness())
+	})
+}
@@ -13,8 +13,10 @@ import (
 	_ "github.com/observiq/stanza/operator/builtin/input/k8sevent"
 	_ "github.com/observiq/stanza/operator/builtin/input/stanza"
 	_ "github.com/observiq/stanza/operator/builtin/input/stdin"
+	_ "github.com/observiq/stanza/operator/builtin/input/syslog"
 	_ "github.com/observiq/stanza/operator/builtin/input/tcp"
 	_ "github.com/observiq/stanza/operator/builtin/input/udp"
+	_ "github.com/observiq/stanza/operator/builtin/input/unix"
 
 	_ "github.com/observiq/stanza/operator/builtin/parser/csv"
 	_ "github.com/observiq/stanza/operator/builtin/parser/json"
@@ -27,20 +29,27 @@ import (
 	_ "github.com/observiq/stanza/operator/builtin/parser/xml"
 
 	_ "github.com/observiq/stanza/operator/builtin/transformer/add"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/aggregate"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/copy"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/dedupe"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/filter"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/flatten"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/geoip"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/hostmetadata"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/k8smetadata"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/lookup"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/metadata"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/move"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/noop"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/ratelimit"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/recombine"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/redact"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/remove"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/restructure"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/retain"
 	_ "github.com/observiq/stanza/operator/builtin/transformer/router"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/sampler"
+	_ "github.com/observiq/stanza/operator/builtin/transformer/throttle"
 
 	_ "github.com/observiq/stanza/operator/builtin/output/count"
 	_ "github.com/observiq/stanza/operator/builtin/output/drop"
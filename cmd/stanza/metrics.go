@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/observiq/stanza/metrics"
+	"go.uber.org/zap"
+)
+
+// startMetrics will serve the agent's prometheus metrics on the configured port.
+// It returns a wait group that is done once the server has shut down.
+func startMetrics(ctx context.Context, flags *RootFlags, logger *zap.SugaredLogger) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	if flags.MetricsPort == 0 {
+		return wg
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	srv := http.Server{
+		Addr:              fmt.Sprintf(":%d", flags.MetricsPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorw("Metrics server failed", zap.Error(err))
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warnw("Errored shutting down metrics server", zap.Error(err))
+		}
+	}()
+
+	return wg
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 
@@ -29,6 +30,14 @@ type RootFlags struct {
 	DatabaseFile       string
 	ConfigFiles        []string
 	PluginDir          string
+	ReloadInterval     time.Duration
+	RemoteConfig       string
+	RemoteConfigKey    string
+	RemotePollInterval time.Duration
+	MetricsPort        int
+	AdminPort          int
+	AdminAddress       string
+	HealthPort         int
 	PprofPort          int
 	CPUProfile         string
 	CPUProfileDuration time.Duration
@@ -66,6 +75,14 @@ func NewRootCmd() *cobra.Command {
 	rootFlagSet.StringSliceVarP(&rootFlags.ConfigFiles, "config", "c", []string{defaultConfig()}, "path to a config file")
 	rootFlagSet.StringVar(&rootFlags.PluginDir, "plugin_dir", defaultPluginDir(), "path to the plugin directory")
 	rootFlagSet.StringVar(&rootFlags.DatabaseFile, "database", "", "path to the stanza offset database")
+	rootFlagSet.IntVar(&rootFlags.MetricsPort, "metrics_port", 0, "listen port for the prometheus /metrics endpoint (disabled if 0)")
+	rootFlagSet.IntVar(&rootFlags.AdminPort, "admin_port", 0, "listen port for the admin API (disabled if 0)")
+	rootFlagSet.StringVar(&rootFlags.AdminAddress, "admin_address", "127.0.0.1", "listen address for the admin API. Set to 0.0.0.0 to serve it on all interfaces")
+	rootFlagSet.IntVar(&rootFlags.HealthPort, "health_port", 0, "listen port for the /healthz and /readyz probes, served on all interfaces (disabled if 0)")
+	rootFlagSet.DurationVar(&rootFlags.ReloadInterval, "reload_interval", 0, "interval at which to check config files for changes and reload the pipeline (disabled if 0)")
+	rootFlagSet.StringVar(&rootFlags.RemoteConfig, "remote_config", "", "URL to fetch the agent config from, instead of the config files")
+	rootFlagSet.StringVar(&rootFlags.RemoteConfigKey, "remote_config_key", "", "path to the ed25519 public key that remote configs are signed with")
+	rootFlagSet.DurationVar(&rootFlags.RemotePollInterval, "remote_poll_interval", time.Minute, "interval at which to fetch the remote config and apply any changes (disabled if 0)")
 
 	// Profiling flags
 	rootFlagSet.IntVar(&rootFlags.PprofPort, "pprof_port", 0, "listen port for pprof profiling")
@@ -85,6 +102,8 @@ func NewRootCmd() *cobra.Command {
 	}
 
 	root.AddCommand(NewGraphCommand(rootFlags))
+	root.AddCommand(NewValidateCommand(rootFlags))
+	root.AddCommand(NewTestCommand(rootFlags))
 	root.AddCommand(NewVersionCommand())
 	root.AddCommand(NewOffsetsCmd(rootFlags))
 
@@ -107,11 +126,21 @@ func runRoot(command *cobra.Command, _ []string, flags *RootFlags) {
 		_ = logger.Sync()
 	}()
 
-	agent, err := agent.NewBuilder(logger).
-		WithConfigFiles(flags.ConfigFiles).
+	builder := agent.NewBuilder(logger).
 		WithPluginDir(flags.PluginDir).
-		WithDatabaseFile(flags.DatabaseFile).
-		Build()
+		WithDatabaseFile(flags.DatabaseFile)
+	if flags.RemoteConfig != "" {
+		remote, err := newRemoteConfig(flags)
+		if err != nil {
+			logger.Errorw("Failed to configure remote config", zap.Any("error", err))
+			os.Exit(1)
+		}
+		builder = builder.WithRemoteConfig(remote)
+	} else {
+		builder = builder.WithConfigFiles(flags.ConfigFiles)
+	}
+
+	agent, err := builder.Build()
 	if err != nil {
 		logger.Errorw("Failed to build agent", zap.Any("error", err))
 		os.Exit(1)
@@ -125,6 +154,12 @@ func runRoot(command *cobra.Command, _ []string, flags *RootFlags) {
 	}
 
 	profilingWg := startProfiling(ctx, flags, logger)
+	metricsWg := startMetrics(ctx, flags, logger)
+	adminWg := startAdmin(ctx, flags, agent, logger)
+	healthWg := startHealth(ctx, flags, agent, logger)
+
+	go agent.WatchConfigFiles(ctx, flags.ReloadInterval)
+	go agent.WatchRemoteConfig(ctx)
 
 	err = service.Run()
 	if err != nil {
@@ -133,6 +168,31 @@ func runRoot(command *cobra.Command, _ []string, flags *RootFlags) {
 	}
 
 	profilingWg.Wait()
+	metricsWg.Wait()
+	adminWg.Wait()
+	healthWg.Wait()
+}
+
+// newRemoteConfig creates the remote config source described by the flags
+func newRemoteConfig(flags *RootFlags) (*agent.RemoteConfig, error) {
+	if flags.RemoteConfigKey == "" {
+		return nil, fmt.Errorf("--remote_config_key must be set to verify remote configs")
+	}
+
+	keyBytes, err := ioutil.ReadFile(flags.RemoteConfigKey) // #nosec - the key file is specified by the user
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %s", err)
+	}
+	publicKey, err := agent.ParsePublicKey(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &agent.RemoteConfig{
+		URL:          flags.RemoteConfig,
+		PublicKey:    publicKey,
+		PollInterval: flags.RemotePollInterval,
+	}, nil
 }
 
 func startProfiling(ctx context.Context, flags *RootFlags, logger *zap.SugaredLogger) *sync.WaitGroup {
@@ -54,10 +54,19 @@ func newAgentService(ctx context.Context, agent *agent.LogAgent, cancel context.
 		Option: service.KeyValue{
 			"RunWait": func() {
 				var sigChan = make(chan os.Signal, 3)
-				signal.Notify(sigChan, syscall.SIGTERM, os.Interrupt)
-				select {
-				case <-sigChan:
-				case <-ctx.Done():
+				signal.Notify(sigChan, syscall.SIGTERM, os.Interrupt, syscall.SIGHUP)
+				for {
+					select {
+					case sig := <-sigChan:
+						if sig == syscall.SIGHUP {
+							// Errors are logged by the agent, and the current pipeline keeps running
+							_ = agent.Reload()
+							continue
+						}
+						return
+					case <-ctx.Done():
+						return
+					}
 				}
 			},
 		},
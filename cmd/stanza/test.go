@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/observiq/stanza/agent"
+	"github.com/observiq/stanza/database"
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/plugin"
+	"github.com/spf13/cobra"
+)
+
+// TestFlags are the flags that can be supplied when running the test command
+type TestFlags struct {
+	*RootFlags
+	Fixture  string
+	Expected string
+	Raw      bool
+	Timeout  time.Duration
+}
+
+// NewTestCommand creates a command for running fixture entries through the pipeline
+func NewTestCommand(rootFlags *RootFlags) *cobra.Command {
+	testFlags := &TestFlags{RootFlags: rootFlags}
+
+	test := &cobra.Command{
+		Use:   "test",
+		Args:  cobra.NoArgs,
+		Short: "Run fixture entries through the pipeline and compare the output to the expected entries",
+		Run: func(command *cobra.Command, args []string) {
+			mismatches, err := runTest(testFlags)
+			exitOnErr("Failed to test pipeline", err)
+			if mismatches > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	testFlagSet := test.Flags()
+	testFlagSet.StringVar(&testFlags.Fixture, "fixture", "", "path to a file of input entries, one JSON entry per line")
+	testFlagSet.StringVar(&testFlags.Expected, "expected", "", "path to a file of expected output entries, one JSON entry per line (if not set, the output is printed)")
+	testFlagSet.BoolVar(&testFlags.Raw, "raw", false, "read each line of the fixture as the record of an entry, rather than as a JSON entry")
+	testFlagSet.DurationVar(&testFlags.Timeout, "timeout", 10*time.Second, "how long to wait for the pipeline to process the fixture")
+	_ = test.MarkFlagRequired("fixture")
+
+	return test
+}
+
+// runTest runs the fixture through the pipeline. If an expected output file is set,
+// it writes the differences to stdout and returns the number of entries that did not
+// match. Otherwise, it writes the output entries to stdout.
+func runTest(flags *TestFlags) (int, error) {
+	logger := newLogger(*flags.RootFlags).Sugar()
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	cfg, err := agent.NewConfigFromGlobs(flags.ConfigFiles)
+	if err != nil {
+		return 0, fmt.Errorf("read configs from glob: %s", err)
+	}
+
+	if errs := plugin.RegisterPlugins(flags.PluginDir, operator.DefaultRegistry); len(errs) != 0 {
+		logger.Errorw("Got errors parsing plugins", "errors", errs)
+	}
+
+	fixture, err := readFixture(flags.Fixture, flags.Raw)
+	if err != nil {
+		return 0, err
+	}
+
+	buildContext := operator.NewBuildContext(database.NewStubDatabase(), logger)
+	harness, err := cfg.Pipeline.BuildHarness(buildContext)
+	if err != nil {
+		return 0, fmt.Errorf("build operator pipeline: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flags.Timeout)
+	defer cancel()
+	output, err := harness.Run(ctx, fixture)
+	if err != nil {
+		return 0, fmt.Errorf("run pipeline: %s", err)
+	}
+
+	if flags.Expected == "" {
+		for _, e := range output {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return 0, err
+			}
+			fmt.Fprintln(stdout, string(line))
+		}
+		return 0, nil
+	}
+
+	expected, err := readExpected(flags.Expected)
+	if err != nil {
+		return 0, err
+	}
+	return diffEntries(expected, output)
+}
+
+// readFixture reads the entries in a fixture file. Entries without a timestamp
+// are given the current time.
+func readFixture(path string, raw bool) ([]*entry.Entry, error) {
+	entries := make([]*entry.Entry, 0)
+	err := readLines(path, func(line []byte) error {
+		e := entry.New()
+		if raw {
+			e.Record = string(line)
+		} else if err := json.Unmarshal(line, e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %s", err)
+	}
+	return entries, nil
+}
+
+// readExpected reads the entries in an expected output file
+func readExpected(path string) ([]map[string]interface{}, error) {
+	entries := make([]map[string]interface{}, 0)
+	err := readLines(path, func(line []byte) error {
+		e := make(map[string]interface{})
+		if err := json.Unmarshal(line, &e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read expected output: %s", err)
+	}
+	return entries, nil
+}
+
+// readLines calls f with each line of a file, skipping empty lines
+func readLines(path string, f func([]byte) error) error {
+	file, err := os.Open(path) // #nosec - fixtures load based on user specified paths
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := f(scanner.Bytes()); err != nil {
+			return fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// diffEntries writes the differences between the expected and actual entries to
+// stdout, and returns the number of entries that differ. Only the fields present
+// in an expected entry are compared, so that fields like the timestamp can be left
+// out when they are not deterministic.
+func diffEntries(expected []map[string]interface{}, actual []*entry.Entry) (int, error) {
+	mismatches := 0
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		var expectedJSON, actualJSON []byte
+		matched := i < len(expected) && i < len(actual)
+		if i < len(expected) {
+			expectedJSON, _ = json.Marshal(expected[i])
+		}
+
+		if i < len(actual) {
+			fields, err := entryFields(actual[i])
+			if err != nil {
+				return 0, err
+			}
+			if i < len(expected) {
+				for key := range fields {
+					if _, ok := expected[i][key]; !ok {
+						delete(fields, key)
+					}
+				}
+				matched = matched && reflect.DeepEqual(expected[i], fields)
+			}
+			actualJSON, _ = json.Marshal(fields)
+		}
+
+		if matched {
+			continue
+		}
+		mismatches++
+		fmt.Fprintf(stdout, "entry %d:\n  expected: %s\n  actual:   %s\n", i+1, orNone(expectedJSON), orNone(actualJSON))
+	}
+
+	if mismatches == 0 {
+		fmt.Fprintf(stdout, "All %d entries matched\n", len(expected))
+	} else {
+		fmt.Fprintf(stdout, "%d entries did not match (expected %d entries, got %d)\n", mismatches, len(expected), len(actual))
+	}
+	return mismatches, nil
+}
+
+// entryFields returns the fields of an entry as they would be decoded from JSON
+func entryFields(e *entry.Entry) (map[string]interface{}, error) {
+	marshalled, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(marshalled, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// orNone returns the JSON of an entry, or a placeholder if there is no entry
+func orNone(marshalled []byte) string {
+	if marshalled == nil {
+		return "<none>"
+	}
+	return string(marshalled)
+}
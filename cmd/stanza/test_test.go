@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func pipelineTest(t *testing.T, fixture, expected string) (*TestFlags, *bytes.Buffer) {
+	tempDir := testutil.NewTempDir(t)
+	config := `
+pipeline:
+  - type: generate_input
+  - type: regex_parser
+    regex: '^(?P<level>\w+) (?P<message>.*)$'
+  - type: stdout
+`
+	configPath := filepath.Join(tempDir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0666))
+
+	fixturePath := filepath.Join(tempDir, "fixture.txt")
+	require.NoError(t, ioutil.WriteFile(fixturePath, []byte(fixture), 0666))
+
+	flags := &TestFlags{
+		RootFlags: &RootFlags{
+			ConfigFiles: []string{configPath},
+			PluginDir:   tempDir,
+		},
+		Fixture: fixturePath,
+		Raw:     true,
+		Timeout: 5 * time.Second,
+	}
+
+	if expected != "" {
+		flags.Expected = filepath.Join(tempDir, "expected.jsonl")
+		require.NoError(t, ioutil.WriteFile(flags.Expected, []byte(expected), 0666))
+	}
+
+	// replace stdout
+	buf := bytes.NewBuffer([]byte{})
+	stdout = buf
+	return flags, buf
+}
+
+func TestPipelineTestMatch(t *testing.T) {
+	fixture := "INFO first\nWARN second\n"
+	expected := `{"record":{"level":"INFO","message":"first"}}
+{"record":{"level":"WARN","message":"second"}}
+`
+	flags, buf := pipelineTest(t, fixture, expected)
+
+	mismatches, err := runTest(flags)
+	require.NoError(t, err)
+	require.Equal(t, 0, mismatches)
+	require.Equal(t, "All 2 entries matched\n", buf.String())
+}
+
+func TestPipelineTestMismatch(t *testing.T) {
+	fixture := "INFO first\nWARN second\n"
+	expected := `{"record":{"level":"INFO","message":"first"}}
+{"record":{"level":"ERROR","message":"second"}}
+{"record":{"level":"INFO","message":"third"}}
+`
+	flags, buf := pipelineTest(t, fixture, expected)
+
+	mismatches, err := runTest(flags)
+	require.NoError(t, err)
+	require.Equal(t, 2, mismatches)
+
+	expectedOutput := `entry 2:
+  expected: {"record":{"level":"ERROR","message":"second"}}
+  actual:   {"record":{"level":"WARN","message":"second"}}
+entry 3:
+  expected: {"record":{"level":"INFO","message":"third"}}
+  actual:   <none>
+2 entries did not match (expected 3 entries, got 2)
+`
+	require.Equal(t, expectedOutput, buf.String())
+}
+
+func TestPipelineTestPrintOutput(t *testing.T) {
+	flags, buf := pipelineTest(t, "INFO first\n", "")
+
+	mismatches, err := runTest(flags)
+	require.NoError(t, err)
+	require.Equal(t, 0, mismatches)
+	require.Contains(t, buf.String(), `"record":{"level":"INFO","message":"first"}`)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/observiq/stanza/agent"
+	"github.com/observiq/stanza/database"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/plugin"
+	"github.com/spf13/cobra"
+)
+
+// NewValidateCommand creates a command for validating the config files and plugins
+func NewValidateCommand(rootFlags *RootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Args:  cobra.NoArgs,
+		Short: "Validate the config files and plugins, reporting every error found",
+		Run: func(command *cobra.Command, args []string) {
+			if problems := runValidate(rootFlags); problems > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// runValidate writes every problem found in the config files and plugins to stdout,
+// and returns the number of problems
+func runValidate(flags *RootFlags) int {
+	logger := newLogger(*flags).Sugar()
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	problems := make([]error, 0)
+	problems = append(problems, plugin.RegisterPlugins(flags.PluginDir, operator.DefaultRegistry)...)
+
+	buildContext := operator.NewBuildContext(database.NewStubDatabase(), logger)
+	for _, problem := range agent.ValidateConfigFromGlobs(flags.ConfigFiles, buildContext) {
+		problems = append(problems, problem)
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem.Error())
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(stdout, "Configuration is valid")
+	} else {
+		fmt.Fprintf(stdout, "Found %d problem(s)\n", len(problems))
+	}
+	return len(problems)
+}
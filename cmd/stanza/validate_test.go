@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func validateTest(t *testing.T, config string) (int, string) {
+	tempDir := testutil.NewTempDir(t)
+	configPath := filepath.Join(tempDir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0666))
+
+	rootFlags := &RootFlags{
+		ConfigFiles: []string{configPath},
+		PluginDir:   tempDir,
+	}
+
+	// replace stdout
+	buf := bytes.NewBuffer([]byte{})
+	stdout = buf
+
+	problems := runValidate(rootFlags)
+	return problems, buf.String()
+}
+
+func TestValidateValid(t *testing.T) {
+	config := `
+pipeline:
+  - type: generate_input
+    entry:
+      record: test
+  - type: stdout
+`
+	problems, output := validateTest(t, config)
+	require.Equal(t, 0, problems)
+	require.Equal(t, "Configuration is valid\n", output)
+}
+
+func TestValidateInvalid(t *testing.T) {
+	config := `pipeline:
+  - type: generate_input
+    output: missing
+  - type: regex_parser
+    regex: "("
+  - type: stdout
+`
+	problems, output := validateTest(t, config)
+	require.Equal(t, 2, problems)
+	require.Contains(t, output, "config.yaml:2: $.generate_input: operator '$.missing' does not exist\n")
+	require.Contains(t, output, "config.yaml:4: $.regex_parser: ")
+	require.Contains(t, output, "Found 2 problem(s)\n")
+}
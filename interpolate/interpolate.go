@@ -0,0 +1,207 @@
+// Package interpolate substitutes references to environment variables and files
+// in config files, so that secrets do not need to be stored in the config itself.
+//
+// A reference has the form ${env:NAME} or ${file:/path/to/file}. A default can be
+// supplied with ${env:NAME:-default}, which is used if the variable is unset or
+// empty, or if the file does not exist. A reference can be marked as required with
+// ${env:NAME:?message}, which fails with the message if the variable is unset or
+// empty, or if the file does not exist. A literal ${ can be written as $${.
+//
+// The package level functions substitute every kind of reference. An Interpolator
+// can reject the kinds that a config from an untrusted source must not use.
+package interpolate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvKind is the kind of a reference to an environment variable
+	EnvKind = "env"
+	// FileKind is the kind of a reference to the contents of a file
+	FileKind = "file"
+)
+
+var referenceRegex = regexp.MustCompile(`\$\$\{|\$\{(env|file):([^}]*)\}`)
+
+// Interpolator substitutes references. The zero value substitutes every kind of reference.
+type Interpolator struct {
+	// DisableFiles rejects ${file:...} references, so that a config can not read
+	// the contents of local files into the values it sends elsewhere
+	DisableFiles bool
+}
+
+// String substitutes the references in a string. It returns true if any
+// reference was substituted.
+func String(s string) (string, bool, error) {
+	return Interpolator{}.String(s)
+}
+
+// Node substitutes the references in the scalar values of a YAML document in place.
+// See Interpolator.Node for details.
+func Node(node *yaml.Node, secrets Secrets) error {
+	return Interpolator{}.Node(node, secrets)
+}
+
+// Bytes substitutes the references in the values of a YAML document. If the
+// document contains no references, it is returned unchanged.
+func Bytes(contents []byte, secrets Secrets) ([]byte, error) {
+	return Interpolator{}.Bytes(contents, secrets)
+}
+
+// String substitutes the references in a string. It returns true if any
+// reference was substituted.
+func (i Interpolator) String(s string) (string, bool, error) {
+	return i.substitute(s, nil)
+}
+
+// substitute substitutes the references in a string, and adds the values found
+// for each reference to secrets
+func (i Interpolator) substitute(s string, secrets Secrets) (string, bool, error) {
+	var substituted bool
+	var err error
+	result := referenceRegex.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		if err != nil {
+			return match
+		}
+
+		parts := referenceRegex.FindStringSubmatch(match)
+		var value string
+		var found bool
+		value, found, err = i.resolve(parts[1], parts[2])
+		if found {
+			secrets.Add(value, match)
+		}
+		substituted = true
+		return value
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return result, substituted, nil
+}
+
+// resolve returns the value of a single reference, and whether the value was found
+// rather than taken from a default
+func (i Interpolator) resolve(kind, reference string) (string, bool, error) {
+	name, modifier, argument := splitReference(reference)
+	if name == "" {
+		return "", false, fmt.Errorf("reference ${%s:%s} is missing a name", kind, reference)
+	}
+	if kind == FileKind && i.DisableFiles {
+		return "", false, fmt.Errorf("reference ${%s:%s} is not allowed, because file references are disabled", kind, reference)
+	}
+
+	var value string
+	var found bool
+	switch kind {
+	case EnvKind:
+		value, found = os.LookupEnv(name)
+		found = found && value != ""
+	case FileKind:
+		contents, err := ioutil.ReadFile(name) // #nosec - secret files are specified by the user in their config
+		switch {
+		case err == nil:
+			value, found = strings.TrimRight(string(contents), "\r\n"), true
+		case os.IsNotExist(err):
+		default:
+			return "", false, fmt.Errorf("read file for ${file:%s}: %s", name, err)
+		}
+	}
+
+	switch {
+	case found:
+		return value, true, nil
+	case modifier == ":-":
+		return argument, false, nil
+	case modifier == ":?":
+		if argument == "" {
+			argument = "is required"
+		}
+		return "", false, fmt.Errorf("${%s:%s}: %s", kind, name, argument)
+	case kind == FileKind:
+		return "", false, fmt.Errorf("file for ${file:%s} does not exist", name)
+	default:
+		return "", false, nil
+	}
+}
+
+// splitReference splits a reference into the name of the variable or file, the
+// modifier if there is one, and the argument to the modifier
+func splitReference(reference string) (name, modifier, argument string) {
+	for i := 0; i+1 < len(reference); i++ {
+		if reference[i] != ':' {
+			continue
+		}
+		if next := reference[i+1]; next == '-' || next == '?' {
+			return reference[:i], reference[i : i+2], reference[i+2:]
+		}
+	}
+	return reference, "", ""
+}
+
+// Node substitutes the references in the scalar values of a YAML document in place.
+// Unquoted values are resolved to a type by their substituted contents, so that a
+// reference can supply a number or a boolean. The values resolved for each scalar,
+// and for each reference within it, are added to secrets.
+func (i Interpolator) Node(node *yaml.Node, secrets Secrets) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := i.Node(child, secrets); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for j := 1; j < len(node.Content); j += 2 {
+			if err := i.Node(node.Content[j], secrets); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		value, substituted, err := i.substitute(node.Value, secrets)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", node.Line, err)
+		}
+		if !substituted {
+			node.Value = value
+			return nil
+		}
+
+		secrets.Add(value, node.Value)
+		node.Value = value
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
+
+// Bytes substitutes the references in the values of a YAML document. If the
+// document contains no references, it is returned unchanged.
+func (i Interpolator) Bytes(contents []byte, secrets Secrets) ([]byte, error) {
+	if !strings.Contains(string(contents), "${") {
+		return contents, nil
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return nil, err
+	}
+	if err := i.Node(&document, secrets); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return contents, nil
+	}
+	return yaml.Marshal(&document)
+}
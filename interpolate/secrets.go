@@ -0,0 +1,90 @@
+package interpolate
+
+import (
+	"sort"
+	"strings"
+)
+
+// minEmbeddedLength is the length below which a resolved value is only masked where it
+// makes up a whole string. Shorter values, such as ports and booleans, would otherwise
+// mask unrelated text that happens to contain them.
+const minEmbeddedLength = 6
+
+// Secrets records the values resolved from references, so that they can be masked
+// when a config is logged or rendered. It maps each resolved value to the text it
+// was resolved from.
+type Secrets map[string]string
+
+// Add records a value resolved from text containing references. Empty values
+// are not recorded, since there is nothing to mask.
+func (s Secrets) Add(resolved, text string) {
+	if s == nil || resolved == "" {
+		return
+	}
+	s[resolved] = text
+}
+
+// Merge records the values of other
+func (s Secrets) Merge(other Secrets) {
+	for resolved, text := range other {
+		s.Add(resolved, text)
+	}
+}
+
+// Mask replaces the strings in a value decoded from JSON or YAML that were resolved
+// from references with the text of the references. Resolved values that appear within
+// a larger string, such as a password in a URL, are replaced within it. Maps and slices
+// are masked in place.
+func (s Secrets) Mask(v interface{}) interface{} {
+	if len(s) == 0 {
+		return v
+	}
+	return s.mask(v, s.replacer())
+}
+
+// replacer returns a replacer for the resolved values that are masked within larger
+// strings. Longer values are listed first, so that they take precedence over any
+// value they contain.
+func (s Secrets) replacer() *strings.Replacer {
+	resolved := make([]string, 0, len(s))
+	for value := range s {
+		if len(value) >= minEmbeddedLength {
+			resolved = append(resolved, value)
+		}
+	}
+	sort.Slice(resolved, func(i, j int) bool {
+		if len(resolved[i]) != len(resolved[j]) {
+			return len(resolved[i]) > len(resolved[j])
+		}
+		return resolved[i] < resolved[j]
+	})
+
+	pairs := make([]string, 0, 2*len(resolved))
+	for _, value := range resolved {
+		pairs = append(pairs, value, s[value])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func (s Secrets) mask(v interface{}, replacer *strings.Replacer) interface{} {
+	switch value := v.(type) {
+	case string:
+		if text, ok := s[value]; ok {
+			return text
+		}
+		return replacer.Replace(value)
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = s.mask(item, replacer)
+		}
+	case map[interface{}]interface{}:
+		for key, item := range value {
+			value[key] = s.mask(item, replacer)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = s.mask(item, replacer)
+		}
+	}
+	return v
+}
@@ -0,0 +1,314 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "stanza"
+
+// Registry is the registry that holds all stanza metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	entriesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "entries_received_total",
+		Help:      "Number of entries received by an operator.",
+	}, []string{"operator_id"})
+
+	entriesEmitted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "entries_emitted_total",
+		Help:      "Number of entries written by an operator to its outputs.",
+	}, []string{"operator_id"})
+
+	entriesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "entries_dropped_total",
+		Help:      "Number of entries dropped by an operator.",
+	}, []string{"operator_id"})
+
+	entryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "errors_total",
+		Help:      "Number of entries an operator returned an error for while processing.",
+	}, []string{"operator_id"})
+
+	processDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "process_duration_seconds",
+		Help:      "Time taken by an operator to process an entry, including synchronous downstream operators.",
+		Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{"operator_id"})
+
+	fanoutQueued = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "fanout",
+		Name:      "queue_entries",
+		Help:      "Number of entries queued for an output of an operator with asynchronous fan-out.",
+	}, []string{"operator_id", "output_id"})
+
+	fanoutDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "fanout",
+		Name:      "entries_dropped_total",
+		Help:      "Number of entries dropped because the fan-out queue for an output was full or stopped.",
+	}, []string{"operator_id", "output_id"})
+
+	bufferEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "buffer",
+		Name:      "entries",
+		Help:      "Number of entries held in an operator's buffer that have not been flushed.",
+	}, []string{"operator_id"})
+
+	bufferFill = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "buffer",
+		Name:      "fill_ratio",
+		Help:      "Fraction of an operator's buffer capacity in use, observed each time it changes.",
+		Buckets:   []float64{.1, .2, .3, .4, .5, .6, .7, .8, .9, 1},
+	}, []string{"operator_id"})
+
+	bufferBackpressure = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "buffer",
+		Name:      "backpressure",
+		Help:      "Whether an operator's buffer is signalling backpressure upstream (1) or not (0).",
+	}, []string{"operator_id"})
+
+	flushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "flusher",
+		Name:      "flushes_total",
+		Help:      "Number of chunk flush attempts, by result.",
+	}, []string{"operator_id", "result"})
+
+	flushRetries = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "flusher",
+		Name:      "chunk_retries",
+		Help:      "Number of retries needed before a chunk was flushed or dropped.",
+		Buckets:   []float64{0, 1, 2, 3, 5, 10, 20, 50, 100},
+	}, []string{"operator_id"})
+
+	chunksDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "flusher",
+		Name:      "chunks_dropped_total",
+		Help:      "Number of chunks dropped after exhausting all retries.",
+	}, []string{"operator_id"})
+)
+
+// OperatorVec is a metric vector with an operator_id label
+type OperatorVec interface {
+	prometheus.Collector
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// operatorVecs are the registered vectors with series to delete when an operator is removed
+var (
+	operatorVecs    []OperatorVec
+	operatorVecsMux sync.Mutex
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	MustRegisterOperatorVecs(
+		entriesReceived,
+		entriesEmitted,
+		entriesDropped,
+		entryErrors,
+		processDuration,
+		fanoutQueued,
+		fanoutDropped,
+		bufferEntries,
+		bufferFill,
+		bufferBackpressure,
+		flushes,
+		flushRetries,
+		chunksDropped,
+	)
+}
+
+// MustRegisterOperatorVecs registers metric vectors with an operator_id label in the
+// Registry, so that their series are deleted with those of the operator. Packages of
+// operators with metrics of their own register them with this in init.
+func MustRegisterOperatorVecs(vecs ...OperatorVec) {
+	operatorVecsMux.Lock()
+	defer operatorVecsMux.Unlock()
+	for _, vec := range vecs {
+		Registry.MustRegister(vec)
+		operatorVecs = append(operatorVecs, vec)
+	}
+}
+
+// DeleteOperator deletes every series of the operator with the supplied id, so that an
+// operator removed from the pipeline is no longer reported
+func DeleteOperator(operatorID string) {
+	operatorVecsMux.Lock()
+	defer operatorVecsMux.Unlock()
+	labels := prometheus.Labels{"operator_id": operatorID}
+	for _, vec := range operatorVecs {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
+// Handler returns an http handler that serves the metrics in the Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Operator holds the metrics of a single operator. A nil *Operator is valid
+// and records nothing.
+type Operator struct {
+	received        prometheus.Counter
+	emitted         prometheus.Counter
+	dropped         prometheus.Counter
+	errors          prometheus.Counter
+	processDuration prometheus.Observer
+}
+
+// NewOperator returns the metrics of the operator with the supplied id
+func NewOperator(operatorID string) *Operator {
+	return &Operator{
+		received:        entriesReceived.WithLabelValues(operatorID),
+		emitted:         entriesEmitted.WithLabelValues(operatorID),
+		dropped:         entriesDropped.WithLabelValues(operatorID),
+		errors:          entryErrors.WithLabelValues(operatorID),
+		processDuration: processDuration.WithLabelValues(operatorID),
+	}
+}
+
+// Processed records an entry processed by the operator, the time processing
+// started, and the resulting error
+func (o *Operator) Processed(start time.Time, err error) {
+	if o == nil {
+		return
+	}
+	o.received.Inc()
+	o.processDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		o.errors.Inc()
+	}
+}
+
+// Emitted records an entry written by the operator
+func (o *Operator) Emitted() {
+	if o == nil {
+		return
+	}
+	o.emitted.Inc()
+}
+
+// Dropped records an entry dropped by the operator
+func (o *Operator) Dropped() {
+	if o == nil {
+		return
+	}
+	o.dropped.Inc()
+}
+
+// FanoutBranch holds the metrics of the queue between an operator and one of its
+// outputs when entries are fanned out asynchronously. A nil *FanoutBranch is valid
+// and records nothing.
+type FanoutBranch struct {
+	queued  prometheus.Gauge
+	dropped prometheus.Counter
+}
+
+// NewFanoutBranch returns the metrics of the fan-out queue from an operator to an output
+func NewFanoutBranch(operatorID, outputID string) *FanoutBranch {
+	return &FanoutBranch{
+		queued:  fanoutQueued.WithLabelValues(operatorID, outputID),
+		dropped: fanoutDropped.WithLabelValues(operatorID, outputID),
+	}
+}
+
+// Queued adds n to the number of queued entries
+func (f *FanoutBranch) Queued(n int) {
+	if f == nil {
+		return
+	}
+	f.queued.Add(float64(n))
+}
+
+// Dropped records n entries dropped because the queue was full or stopped
+func (f *FanoutBranch) Dropped(n int) {
+	if f == nil {
+		return
+	}
+	f.dropped.Add(float64(n))
+}
+
+// BufferEntries returns the gauge of unflushed entries in an operator's buffer
+func BufferEntries(operatorID string) prometheus.Gauge {
+	return bufferEntries.WithLabelValues(operatorID)
+}
+
+// BufferBackpressure returns the gauge of whether an operator's buffer is signalling backpressure
+func BufferBackpressure(operatorID string) prometheus.Gauge {
+	return bufferBackpressure.WithLabelValues(operatorID)
+}
+
+// BufferFill returns the histogram of the fraction of an operator's buffer capacity in use
+func BufferFill(operatorID string) prometheus.Observer {
+	return bufferFill.WithLabelValues(operatorID)
+}
+
+// Flusher holds the metrics of a single operator's flusher. A nil *Flusher
+// is valid and records nothing.
+type Flusher struct {
+	succeeded     prometheus.Counter
+	failed        prometheus.Counter
+	retries       prometheus.Observer
+	chunksDropped prometheus.Counter
+}
+
+// NewFlusher returns the flusher metrics of the operator with the supplied id
+func NewFlusher(operatorID string) *Flusher {
+	return &Flusher{
+		succeeded:     flushes.WithLabelValues(operatorID, "success"),
+		failed:        flushes.WithLabelValues(operatorID, "failure"),
+		retries:       flushRetries.WithLabelValues(operatorID),
+		chunksDropped: chunksDropped.WithLabelValues(operatorID),
+	}
+}
+
+// Attempted records the result of a single flush attempt
+func (f *Flusher) Attempted(err error) {
+	if f == nil {
+		return
+	}
+	if err != nil {
+		f.failed.Inc()
+		return
+	}
+	f.succeeded.Inc()
+}
+
+// Finished records the number of retries a chunk took before it was either
+// flushed or dropped
+func (f *Flusher) Finished(retries int, dropped bool) {
+	if f == nil {
+		return
+	}
+	f.retries.Observe(float64(retries))
+	if dropped {
+		f.chunksDropped.Inc()
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNilMetrics(t *testing.T) {
+	var operator *Operator
+	operator.Processed(time.Now(), nil)
+	operator.Emitted()
+	operator.Dropped()
+
+	var flusher *Flusher
+	flusher.Attempted(nil)
+	flusher.Finished(1, true)
+
+	var branch *FanoutBranch
+	branch.Queued(1)
+	branch.Dropped(1)
+}
+
+func TestOperatorMetrics(t *testing.T) {
+	operator := NewOperator("$.test_operator")
+	operator.Processed(time.Now(), nil)
+	operator.Processed(time.Now(), errors.New("failed"))
+	operator.Emitted()
+	operator.Dropped()
+
+	require.Equal(t, 2.0, testutil.ToFloat64(entriesReceived.WithLabelValues("$.test_operator")))
+	require.Equal(t, 1.0, testutil.ToFloat64(entryErrors.WithLabelValues("$.test_operator")))
+	require.Equal(t, 1.0, testutil.ToFloat64(entriesEmitted.WithLabelValues("$.test_operator")))
+	require.Equal(t, 1.0, testutil.ToFloat64(entriesDropped.WithLabelValues("$.test_operator")))
+}
+
+func TestFlusherMetrics(t *testing.T) {
+	flusher := NewFlusher("$.test_flusher")
+	flusher.Attempted(errors.New("failed"))
+	flusher.Attempted(nil)
+	flusher.Finished(1, false)
+	flusher.Finished(3, true)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(flushes.WithLabelValues("$.test_flusher", "success")))
+	require.Equal(t, 1.0, testutil.ToFloat64(flushes.WithLabelValues("$.test_flusher", "failure")))
+	require.Equal(t, 1.0, testutil.ToFloat64(chunksDropped.WithLabelValues("$.test_flusher")))
+}
+
+func TestBufferFill(t *testing.T) {
+	fill := BufferFill("$.test_buffer")
+	fill.Observe(0.25)
+	fill.Observe(0.95)
+
+	require.Equal(t, 1, testutil.CollectAndCount(bufferFill, "stanza_buffer_fill_ratio"))
+}
+
+func TestDeleteOperator(t *testing.T) {
+	NewOperator("$.test_deleted").Emitted()
+	NewFanoutBranch("$.test_deleted", "$.output").Queued(1)
+	NewOperator("$.test_kept").Emitted()
+
+	DeleteOperator("$.test_deleted")
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	require.NotContains(t, recorder.Body.String(), "$.test_deleted")
+	require.Contains(t, recorder.Body.String(), `stanza_operator_entries_emitted_total{operator_id="$.test_kept"} 1`)
+}
+
+func TestHandler(t *testing.T) {
+	NewOperator("$.test_handler").Emitted()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `stanza_operator_entries_emitted_total{operator_id="$.test_handler"} 1`)
+}
@@ -0,0 +1,63 @@
+package operator
+
+import "sync"
+
+// Backpressure is a signal raised by an operator, such as an output with a full buffer,
+// to indicate that operators upstream of it should slow down.
+type Backpressure struct {
+	mux       sync.Mutex
+	pressured bool
+	released  chan struct{}
+}
+
+// NewBackpressure creates a new backpressure signal that is not raised
+func NewBackpressure() *Backpressure {
+	released := make(chan struct{})
+	close(released)
+	return &Backpressure{released: released}
+}
+
+// Set raises or releases the backpressure signal
+func (b *Backpressure) Set(pressured bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if pressured == b.pressured {
+		return
+	}
+
+	b.pressured = pressured
+	if pressured {
+		b.released = make(chan struct{})
+	} else {
+		close(b.released)
+	}
+}
+
+// Pressured returns true if the backpressure signal is raised
+func (b *Backpressure) Pressured() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.pressured
+}
+
+// Released returns a channel that is closed once the backpressure signal is released.
+// If the signal is not raised, the channel is already closed.
+func (b *Backpressure) Released() <-chan struct{} {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.released
+}
+
+// BackpressureReporter is an operator that raises backpressure signals
+type BackpressureReporter interface {
+	// Backpressure returns the backpressure signal of the operator
+	Backpressure() *Backpressure
+}
+
+// BackpressureReceiver is an operator that reacts to the backpressure signals
+// of the operators downstream of it
+type BackpressureReceiver interface {
+	// SetBackpressure sets the backpressure signals of the operators downstream of the operator
+	SetBackpressure([]*Backpressure)
+}
@@ -0,0 +1,38 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackpressure(t *testing.T) {
+	t.Run("NotPressured", func(t *testing.T) {
+		bp := NewBackpressure()
+		require.False(t, bp.Pressured())
+		require.True(t, isClosed(bp.Released()))
+	})
+
+	t.Run("Pressured", func(t *testing.T) {
+		bp := NewBackpressure()
+		bp.Set(true)
+		require.True(t, bp.Pressured())
+
+		released := bp.Released()
+		require.False(t, isClosed(released))
+
+		bp.Set(true)
+		bp.Set(false)
+		require.False(t, bp.Pressured())
+		require.True(t, isClosed(released))
+	})
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
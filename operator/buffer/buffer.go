@@ -21,6 +21,9 @@ type Buffer interface {
 	MaxChunkSize() uint
 	SetMaxChunkDelay(time.Duration)
 	SetMaxChunkSize(uint)
+	Backpressure() *operator.Backpressure
+	Unflushed() int
+	Healthy() error
 }
 
 // Config is a struct that wraps a Builder
@@ -9,11 +9,14 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/metrics"
 	"github.com/observiq/stanza/operator"
 	"github.com/observiq/stanza/operator/helper"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -34,6 +37,13 @@ type DiskBufferConfig struct {
 
 	MaxChunkDelay helper.Duration `json:"max_delay"   yaml:"max_delay"`
 	MaxChunkSize  uint            `json:"max_chunk_size" yaml:"max_chunk_size"`
+
+	// HighWatermark is the fraction of max_size at which the buffer signals backpressure
+	// to the operators upstream of it. Defaults to 0.8.
+	HighWatermark float64 `json:"high_watermark,omitempty" yaml:"high_watermark,omitempty"`
+	// LowWatermark is the fraction of max_size at which the buffer releases backpressure.
+	// Defaults to 0.5.
+	LowWatermark float64 `json:"low_watermark,omitempty" yaml:"low_watermark,omitempty"`
 }
 
 // NewDiskBufferConfig creates a new default disk buffer config
@@ -48,7 +58,7 @@ func NewDiskBufferConfig() *DiskBufferConfig {
 }
 
 // Build creates a new Buffer from a DiskBufferConfig
-func (c DiskBufferConfig) Build(_ operator.BuildContext, _ string) (Buffer, error) {
+func (c DiskBufferConfig) Build(context operator.BuildContext, pluginID string) (Buffer, error) {
 	maxSize := c.MaxSize
 	if maxSize == 0 {
 		maxSize = 1 << 32
@@ -57,18 +67,47 @@ func (c DiskBufferConfig) Build(_ operator.BuildContext, _ string) (Buffer, erro
 	if c.Path == "" {
 		return nil, fmt.Errorf("missing required field 'path'")
 	}
+
+	watermarks, err := newWatermarks(c.HighWatermark, c.LowWatermark, int64(maxSize), context.PrependNamespace(pluginID))
+	if err != nil {
+		return nil, err
+	}
+
 	b := NewDiskBuffer(int64(maxSize))
+	if context.Validating {
+		// Opening the buffer creates and compacts its files, so only the path is checked
+		info, err := os.Stat(c.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("path '%s' is not a directory", c.Path)
+		}
+		return b, nil
+	}
+
 	if err := b.Open(c.Path, c.Sync); err != nil {
 		return nil, err
 	}
 	b.maxChunkSize = c.MaxChunkSize
 	b.maxChunkDelay = c.MaxChunkDelay.Raw()
+	b.entries = metrics.BufferEntries(context.PrependNamespace(pluginID))
+	b.unflushed = b.metadata.unreadCount
+	b.entries.Set(float64(b.metadata.unreadCount))
+	b.watermarks = watermarks
+	b.watermarks.add(atomic.LoadInt64(&b.usedBytes))
 	return b, nil
 }
 
 // DiskBuffer is a buffer for storing entries on disk until they are flushed to their
 // final destination.
 type DiskBuffer struct {
+	// usedBytes is the number of bytes acquired from the diskSizeSemaphore
+	usedBytes int64
+
+	// unflushed is the number of entries that have been added but not flushed
+	unflushed int64
+
 	// Metadata holds information about the current state of the buffered entries
 	metadata *Metadata
 
@@ -105,6 +144,11 @@ type DiskBuffer struct {
 	maxChunkSize  uint
 
 	reconfigMutex sync.RWMutex
+
+	// entries is a gauge of the number of unflushed entries in the buffer
+	entries prometheus.Gauge
+
+	watermarks *watermarks
 }
 
 // NewDiskBuffer creates a new DiskBuffer
@@ -143,6 +187,7 @@ func (d *DiskBuffer) Open(path string, sync bool) error {
 	if ok := d.diskSizeSemaphore.TryAcquire(info.Size()); !ok {
 		return fmt.Errorf("current on-disk size is larger than max size")
 	}
+	d.addUsedBytes(info.Size())
 
 	// First, if there is a dead range from a previous incomplete compaction, delete it
 	if err = d.deleteDeadRange(); err != nil {
@@ -186,6 +231,7 @@ func (d *DiskBuffer) Add(ctx context.Context, newEntry *entry.Entry) error {
 	if err = d.diskSizeSemaphore.Acquire(ctx, int64(buf.Len())); err != nil {
 		return err
 	}
+	d.addUsedBytes(int64(buf.Len()))
 
 	d.Lock()
 	defer d.Unlock()
@@ -200,10 +246,43 @@ func (d *DiskBuffer) Add(ctx context.Context, newEntry *entry.Entry) error {
 	}
 
 	d.addUnreadCount(1)
+	d.addEntries(1)
 
 	return nil
 }
 
+// addEntries adds n to the count and gauge of unflushed entries
+func (d *DiskBuffer) addEntries(n int) {
+	atomic.AddInt64(&d.unflushed, int64(n))
+	if n < 0 {
+		d.watermarks.flushed()
+	}
+	if d.entries != nil {
+		d.entries.Add(float64(n))
+	}
+}
+
+// Unflushed returns the number of entries in the buffer that have not been flushed
+func (d *DiskBuffer) Unflushed() int {
+	return int(atomic.LoadInt64(&d.unflushed))
+}
+
+// Healthy returns an error if the buffer is above its high watermark and has stopped flushing
+func (d *DiskBuffer) Healthy() error {
+	return d.watermarks.healthy()
+}
+
+// addUsedBytes adds n to the number of bytes used on disk and to the usage of the watermarks
+func (d *DiskBuffer) addUsedBytes(n int64) {
+	atomic.AddInt64(&d.usedBytes, n)
+	d.watermarks.add(n)
+}
+
+// Backpressure returns the signal raised when the buffer reaches its high watermark
+func (d *DiskBuffer) Backpressure() *operator.Backpressure {
+	return d.watermarks.signal()
+}
+
 // addUnreadCount adds i to the unread count and notifies any callers of
 // ReadWait that an entry has been added. The disk buffer lock must be held when
 // calling this.
@@ -364,6 +443,7 @@ func (dc *diskClearer) MarkAllAsFlushed() error {
 		dc.buffer.flushedBytes += entry.length
 	}
 	dc.buffer.Unlock()
+	dc.buffer.addEntries(-len(dc.readEntries))
 	return dc.buffer.checkCompact()
 }
 
@@ -378,6 +458,7 @@ func (dc *diskClearer) MarkRangeAsFlushed(start, end uint) error {
 		dc.buffer.flushedBytes += entry.length
 	}
 	dc.buffer.Unlock()
+	dc.buffer.addEntries(-int(end - start))
 	return dc.buffer.checkCompact()
 }
 
@@ -596,6 +677,7 @@ func (d *DiskBuffer) deleteDeadRange() error {
 	}
 
 	d.diskSizeSemaphore.Release(d.metadata.deadRangeLength)
+	d.addUsedBytes(-d.metadata.deadRangeLength)
 
 	return d.metadata.setDeadRange(0, 0)
 }
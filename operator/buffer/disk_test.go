@@ -3,7 +3,9 @@ package buffer
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
@@ -273,6 +275,25 @@ func TestDiskBufferBuild(t *testing.T) {
 		require.Equal(t, diskBuffer.flushedBytes, int64(0))
 		require.Len(t, diskBuffer.copyBuffer, 1<<16)
 	})
+
+	t.Run("Validating", func(t *testing.T) {
+		bc := testutil.NewBuildContext(t)
+		bc.Validating = true
+
+		cfg := NewDiskBufferConfig()
+		cfg.Path = testutil.NewTempDir(t)
+		_, err := cfg.Build(bc, "test")
+		require.NoError(t, err)
+
+		// The buffer files are not created while validating
+		files, err := ioutil.ReadDir(cfg.Path)
+		require.NoError(t, err)
+		require.Empty(t, files)
+
+		cfg.Path = filepath.Join(cfg.Path, "missing")
+		_, err = cfg.Build(bc, "test")
+		require.Error(t, err)
+	})
 }
 
 func BenchmarkDiskBuffer(b *testing.B) {
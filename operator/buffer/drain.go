@@ -0,0 +1,29 @@
+package buffer
+
+import (
+	"context"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether a buffer has been flushed
+var drainPollInterval = 10 * time.Millisecond
+
+// Drain waits until every entry in the buffer has been flushed, or the context is done.
+// It returns the number of entries that were not flushed.
+func Drain(ctx context.Context, b Buffer) int {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		unflushed := b.Unflushed()
+		if unflushed == 0 {
+			return 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return unflushed
+		case <-ticker.C:
+		}
+	}
+}
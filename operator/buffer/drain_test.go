@@ -0,0 +1,46 @@
+package buffer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain(t *testing.T) {
+	t.Run("Flushed", func(t *testing.T) {
+		b := newMemoryBuffer(t)
+		writeN(t, b, 3, 0)
+		clearer := readN(t, b, 3, 0)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			clearer.MarkAllAsFlushed()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.Equal(t, 0, Drain(ctx, b))
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		b := newMemoryBuffer(t)
+		writeN(t, b, 3, 0)
+		readN(t, b, 1, 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.Equal(t, 3, Drain(ctx, b))
+	})
+
+	t.Run("DiskBuffer", func(t *testing.T) {
+		b := openBuffer(t)
+		writeN(t, b, 3, 0)
+		require.Equal(t, 3, b.Unflushed())
+
+		clearer := readN(t, b, 2, 0)
+		require.NoError(t, clearer.MarkAllAsFlushed())
+		require.Equal(t, 1, b.Unflushed())
+	})
+}
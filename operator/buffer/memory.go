@@ -12,8 +12,10 @@ import (
 
 	"github.com/observiq/stanza/database"
 	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/metrics"
 	"github.com/observiq/stanza/operator"
 	"github.com/observiq/stanza/operator/helper"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.etcd.io/bbolt"
 	"golang.org/x/sync/semaphore"
 )
@@ -24,6 +26,13 @@ type MemoryBufferConfig struct {
 	MaxEntries    int             `json:"max_entries" yaml:"max_entries"`
 	MaxChunkDelay helper.Duration `json:"max_delay"   yaml:"max_delay"`
 	MaxChunkSize  uint            `json:"max_chunk_size" yaml:"max_chunk_size"`
+
+	// HighWatermark is the fraction of max_entries at which the buffer signals backpressure
+	// to the operators upstream of it. Defaults to 0.8.
+	HighWatermark float64 `json:"high_watermark,omitempty" yaml:"high_watermark,omitempty"`
+	// LowWatermark is the fraction of max_entries at which the buffer releases backpressure.
+	// Defaults to 0.5.
+	LowWatermark float64 `json:"low_watermark,omitempty" yaml:"low_watermark,omitempty"`
 }
 
 // NewMemoryBufferConfig creates a new default MemoryBufferConfig
@@ -39,6 +48,11 @@ func NewMemoryBufferConfig() *MemoryBufferConfig {
 // Build builds a MemoryBufferConfig into a Buffer, loading any entries that were previously unflushed
 // back into memory
 func (c MemoryBufferConfig) Build(context operator.BuildContext, pluginID string) (Buffer, error) {
+	watermarks, err := newWatermarks(c.HighWatermark, c.LowWatermark, int64(c.MaxEntries), context.PrependNamespace(pluginID))
+	if err != nil {
+		return nil, err
+	}
+
 	mb := &MemoryBuffer{
 		db:            context.Database,
 		pluginID:      pluginID,
@@ -47,10 +61,15 @@ func (c MemoryBufferConfig) Build(context operator.BuildContext, pluginID string
 		inFlight:      make(map[uint64]*entry.Entry, c.MaxEntries),
 		maxChunkDelay: c.MaxChunkDelay.Raw(),
 		maxChunkSize:  c.MaxChunkSize,
+		entries:       metrics.BufferEntries(context.PrependNamespace(pluginID)),
+		watermarks:    watermarks,
 	}
 	if err := mb.loadFromDB(); err != nil {
 		return nil, err
 	}
+	mb.unflushed = int64(len(mb.buf))
+	mb.entries.Set(float64(len(mb.buf)))
+	mb.watermarks.add(int64(len(mb.buf)))
 
 	return mb, nil
 }
@@ -60,6 +79,7 @@ func (c MemoryBufferConfig) Build(context operator.BuildContext, pluginID string
 // lost entries if shut down uncleanly.
 type MemoryBuffer struct {
 	entryID       uint64
+	unflushed     int64
 	db            database.Database
 	pluginID      string
 	buf           chan *entry.Entry
@@ -69,6 +89,8 @@ type MemoryBuffer struct {
 	maxChunkDelay time.Duration
 	maxChunkSize  uint
 	reconfigMutex sync.RWMutex
+	entries       prometheus.Gauge
+	watermarks    *watermarks
 }
 
 // Add inserts an entry into the memory database, blocking until there is space
@@ -78,9 +100,37 @@ func (m *MemoryBuffer) Add(ctx context.Context, e *entry.Entry) error {
 	}
 
 	m.buf <- e
+	m.addEntries(1)
 	return nil
 }
 
+// addEntries adds n to the count and gauge of unflushed entries and to the usage of the watermarks
+func (m *MemoryBuffer) addEntries(n int) {
+	atomic.AddInt64(&m.unflushed, int64(n))
+	if n < 0 {
+		m.watermarks.flushed()
+	}
+	if m.entries != nil {
+		m.entries.Add(float64(n))
+	}
+	m.watermarks.add(int64(n))
+}
+
+// Backpressure returns the signal raised when the buffer reaches its high watermark
+func (m *MemoryBuffer) Backpressure() *operator.Backpressure {
+	return m.watermarks.signal()
+}
+
+// Unflushed returns the number of entries in the buffer that have not been flushed
+func (m *MemoryBuffer) Unflushed() int {
+	return int(atomic.LoadInt64(&m.unflushed))
+}
+
+// Healthy returns an error if the buffer is above its high watermark and has stopped flushing
+func (m *MemoryBuffer) Healthy() error {
+	return m.watermarks.healthy()
+}
+
 // Read reads entries until either there are no entries left in the buffer
 // or the destination slice is full. The returned function must be called
 // once the entries are flushed to remove them from the memory buffer.
@@ -186,6 +236,7 @@ func (mc *memoryClearer) MarkAllAsFlushed() error {
 	}
 	mc.buffer.inFlightMux.Unlock()
 	mc.buffer.sem.Release(int64(len(mc.ids)))
+	mc.buffer.addEntries(-len(mc.ids))
 	return nil
 }
 
@@ -200,6 +251,7 @@ func (mc *memoryClearer) MarkRangeAsFlushed(start, end uint) error {
 	}
 	mc.buffer.inFlightMux.Unlock()
 	mc.buffer.sem.Release(int64(end - start))
+	mc.buffer.addEntries(-int(end - start))
 	return nil
 }
 
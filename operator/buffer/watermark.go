@@ -0,0 +1,117 @@
+package buffer
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/observiq/stanza/metrics"
+	"github.com/observiq/stanza/operator"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultHighWatermark = 0.8
+	defaultLowWatermark  = 0.5
+)
+
+// wedgedTimeout is how long a buffer may stay above its high watermark without
+// flushing any entries before it is reported as unhealthy
+var wedgedTimeout = 5 * time.Minute
+
+// watermarks raises a backpressure signal when the usage of a buffer reaches its high
+// watermark, and releases it once the usage falls to its low watermark. A nil
+// *watermarks is valid and does nothing.
+type watermarks struct {
+	used         int64
+	lastFlush    int64
+	high         int64
+	low          int64
+	capacity     int64
+	backpressure *operator.Backpressure
+	gauge        prometheus.Gauge
+	fill         prometheus.Observer
+}
+
+// newWatermarks creates watermarks at the supplied fractions of a buffer's capacity. Zero
+// fractions are replaced by their defaults.
+func newWatermarks(highFraction, lowFraction float64, capacity int64, operatorID string) (*watermarks, error) {
+	if highFraction == 0 {
+		highFraction = defaultHighWatermark
+	}
+	if lowFraction == 0 {
+		lowFraction = defaultLowWatermark
+	}
+
+	if highFraction < 0 || highFraction > 1 {
+		return nil, fmt.Errorf("high_watermark must be between 0 and 1, got %v", highFraction)
+	}
+	if lowFraction < 0 || lowFraction >= highFraction {
+		return nil, fmt.Errorf("low_watermark must be at least 0 and less than high_watermark, got %v", lowFraction)
+	}
+
+	gauge := metrics.BufferBackpressure(operatorID)
+	gauge.Set(0)
+
+	return &watermarks{
+		lastFlush:    time.Now().UnixNano(),
+		high:         int64(highFraction * float64(capacity)),
+		low:          int64(lowFraction * float64(capacity)),
+		capacity:     capacity,
+		backpressure: operator.NewBackpressure(),
+		gauge:        gauge,
+		fill:         metrics.BufferFill(operatorID),
+	}, nil
+}
+
+// add adds n to the usage of the buffer, records the fraction of its capacity in use,
+// and raises or releases backpressure if a watermark is crossed
+func (w *watermarks) add(n int64) {
+	if w == nil {
+		return
+	}
+
+	used := atomic.AddInt64(&w.used, n)
+	if w.capacity > 0 {
+		w.fill.Observe(float64(used) / float64(w.capacity))
+	}
+
+	switch {
+	case used >= w.high:
+		w.backpressure.Set(true)
+		w.gauge.Set(1)
+	case used <= w.low:
+		w.backpressure.Set(false)
+		w.gauge.Set(0)
+	}
+}
+
+// flushed records that entries were flushed from the buffer
+func (w *watermarks) flushed() {
+	if w == nil {
+		return
+	}
+	atomic.StoreInt64(&w.lastFlush, time.Now().UnixNano())
+}
+
+// healthy returns an error if the buffer has been above its high watermark
+// without flushing any entries for longer than the wedged timeout
+func (w *watermarks) healthy() error {
+	if w == nil || !w.backpressure.Pressured() {
+		return nil
+	}
+
+	sinceFlush := time.Since(time.Unix(0, atomic.LoadInt64(&w.lastFlush)))
+	if sinceFlush > wedgedTimeout {
+		return fmt.Errorf("buffer is above its high watermark and has not flushed any entries in %s", sinceFlush.Round(time.Second))
+	}
+	return nil
+}
+
+// signal returns the backpressure signal of the watermarks
+func (w *watermarks) signal() *operator.Backpressure {
+	if w == nil {
+		return nil
+	}
+	return w.backpressure
+}
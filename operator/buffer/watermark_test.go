@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWatermarks(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		w, err := newWatermarks(0, 0, 100, "$.test")
+		require.NoError(t, err)
+		require.Equal(t, int64(80), w.high)
+		require.Equal(t, int64(50), w.low)
+	})
+
+	t.Run("HighOutOfRange", func(t *testing.T) {
+		_, err := newWatermarks(1.5, 0.5, 100, "$.test")
+		require.Error(t, err)
+	})
+
+	t.Run("LowAboveHigh", func(t *testing.T) {
+		_, err := newWatermarks(0.5, 0.6, 100, "$.test")
+		require.Error(t, err)
+	})
+}
+
+func TestMemoryBufferBackpressure(t *testing.T) {
+	cfg := NewMemoryBufferConfig()
+	cfg.MaxEntries = 10
+	cfg.HighWatermark = 0.8
+	cfg.LowWatermark = 0.5
+	b, err := cfg.Build(testutil.NewBuildContext(t), "test")
+	require.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, b.Add(context.Background(), entry.New()))
+	}
+	require.False(t, b.Backpressure().Pressured())
+
+	require.NoError(t, b.Add(context.Background(), entry.New()))
+	require.True(t, b.Backpressure().Pressured())
+
+	dst := make([]*entry.Entry, 2)
+	clearer, n, err := b.Read(dst)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.NoError(t, clearer.MarkAllAsFlushed())
+	require.True(t, b.Backpressure().Pressured())
+
+	dst = make([]*entry.Entry, 1)
+	clearer, _, err = b.Read(dst)
+	require.NoError(t, err)
+	require.NoError(t, clearer.MarkAllAsFlushed())
+	require.False(t, b.Backpressure().Pressured())
+}
+
+func TestWatermarksHealthy(t *testing.T) {
+	w, err := newWatermarks(0.8, 0.5, 10, "$.test")
+	require.NoError(t, err)
+
+	w.add(9)
+	require.NoError(t, w.healthy())
+
+	w.lastFlush = time.Now().Add(-2 * wedgedTimeout).UnixNano()
+	require.Error(t, w.healthy())
+
+	w.flushed()
+	require.NoError(t, w.healthy())
+
+	w.lastFlush = time.Now().Add(-2 * wedgedTimeout).UnixNano()
+	w.add(-5)
+	require.NoError(t, w.healthy())
+}
@@ -17,6 +17,11 @@ type BuildContext struct {
 	Namespace        string
 	DefaultOutputIDs []string
 	PluginDepth      int
+
+	// Validating is set when operators are built only to validate a config, and
+	// are discarded without being started. Builders must not open, create, or
+	// modify files and other resources while validating.
+	Validating bool
 }
 
 // PrependNamespace adds the current namespace of the build context to the
@@ -60,6 +65,7 @@ func (bc BuildContext) Copy() BuildContext {
 		Namespace:        bc.Namespace,
 		DefaultOutputIDs: bc.DefaultOutputIDs,
 		PluginDepth:      bc.PluginDepth,
+		Validating:       bc.Validating,
 	}
 }
 
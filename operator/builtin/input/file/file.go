@@ -9,6 +9,7 @@ import (
 	"os"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/observiq/stanza/entry"
@@ -16,8 +17,15 @@ import (
 	"go.uber.org/zap"
 )
 
+// stuckPollTimeout is how long a single poll may run before the input is reported as unhealthy
+var stuckPollTimeout = 5 * time.Minute
+
 // InputOperator is an operator that monitors files for entries
 type InputOperator struct {
+	// pollStart is the time, in unix nanoseconds, that the running poll started,
+	// or zero if no poll is running
+	pollStart int64
+
 	helper.InputOperator
 
 	finder                Finder
@@ -48,6 +56,10 @@ type InputOperator struct {
 
 	encoding helper.Encoding
 
+	// knownFileStatus is a snapshot of the known files, taken after each poll
+	knownFileStatus    []KnownFile
+	knownFileStatusMux sync.Mutex
+
 	wg         sync.WaitGroup
 	firstCheck bool
 	cancel     context.CancelFunc
@@ -63,6 +75,7 @@ func (f *InputOperator) Start() error {
 	if err := f.loadLastPollFiles(); err != nil {
 		return fmt.Errorf("read known files from database: %s", err)
 	}
+	f.updateKnownFileStatus()
 
 	// Start polling goroutine
 	f.startPoller(ctx)
@@ -98,11 +111,32 @@ func (f *InputOperator) startPoller(ctx context.Context) {
 			case <-globTicker.C:
 			}
 
+			// Pause polling while a downstream buffer is signalling backpressure
+			if err := f.WaitForCapacity(ctx); err != nil {
+				return
+			}
+
+			atomic.StoreInt64(&f.pollStart, time.Now().UnixNano())
 			f.poll(ctx)
+			atomic.StoreInt64(&f.pollStart, 0)
 		}
 	}()
 }
 
+// Healthy returns an error if a poll has been running for too long, unless the
+// input is paused or waiting for downstream backpressure to be released
+func (f *InputOperator) Healthy() error {
+	pollStart := atomic.LoadInt64(&f.pollStart)
+	if pollStart == 0 || f.Paused() || f.Backpressured() {
+		return nil
+	}
+
+	if running := time.Since(time.Unix(0, pollStart)); running > stuckPollTimeout {
+		return fmt.Errorf("poll has been running for %s", running.Round(time.Second))
+	}
+	return nil
+}
+
 // poll checks all the watched paths for new entries
 func (f *InputOperator) poll(ctx context.Context) {
 	f.maxBatchFiles = f.MaxConcurrentFiles / 2
@@ -180,6 +214,7 @@ func (f *InputOperator) poll(ctx context.Context) {
 
 	f.saveCurrent(readers)
 	f.syncLastPollFiles()
+	f.updateKnownFileStatus()
 }
 
 // makeReaders takes a list of paths, then creates readers from each of those paths,
@@ -351,6 +386,34 @@ func (f *InputOperator) syncLastPollFiles() {
 	}
 }
 
+// KnownFile describes a file that the input has read from
+type KnownFile struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+// updateKnownFileStatus takes a snapshot of the known files and their offsets
+func (f *InputOperator) updateKnownFileStatus() {
+	status := make([]KnownFile, 0, len(f.knownFiles))
+	for _, reader := range f.knownFiles {
+		status = append(status, KnownFile{
+			Path:   reader.fileLabels.Path,
+			Offset: reader.Offset,
+		})
+	}
+
+	f.knownFileStatusMux.Lock()
+	defer f.knownFileStatusMux.Unlock()
+	f.knownFileStatus = status
+}
+
+// Inspect reports the files known to the input, and their offsets as of the last poll
+func (f *InputOperator) Inspect() map[string]interface{} {
+	f.knownFileStatusMux.Lock()
+	defer f.knownFileStatusMux.Unlock()
+	return map[string]interface{}{"known_files": f.knownFileStatus}
+}
+
 // syncLastPollFiles loads the most recent set of files to the database
 func (f *InputOperator) loadLastPollFiles() error {
 	err := f.persist.Load()
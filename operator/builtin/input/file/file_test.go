@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -950,3 +951,34 @@ func TestEncodings(t *testing.T) {
 		})
 	}
 }
+
+func TestInspectKnownFiles(t *testing.T) {
+	t.Parallel()
+	operator, logReceived, tempDir := newTestFileOperator(t, nil, nil)
+	require.Equal(t, map[string]interface{}{"known_files": []KnownFile(nil)}, operator.Inspect())
+
+	temp := openTemp(t, tempDir)
+	writeString(t, temp, "testlog1\n")
+
+	operator.poll(context.Background())
+	defer operator.Stop()
+	waitForMessage(t, logReceived, "testlog1")
+
+	expected := []KnownFile{{Path: temp.Name(), Offset: 9}}
+	require.Equal(t, map[string]interface{}{"known_files": expected}, operator.Inspect())
+}
+
+func TestHealthyStuckPoll(t *testing.T) {
+	t.Parallel()
+	operator, _, _ := newTestFileOperator(t, nil, nil)
+	require.NoError(t, operator.Healthy())
+
+	atomic.StoreInt64(&operator.pollStart, time.Now().UnixNano())
+	require.NoError(t, operator.Healthy())
+
+	atomic.StoreInt64(&operator.pollStart, time.Now().Add(-2*stuckPollTimeout).UnixNano())
+	require.Error(t, operator.Healthy())
+
+	operator.Pause()
+	require.NoError(t, operator.Healthy())
+}
@@ -2,6 +2,7 @@ package forward
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"net"
 	"net/http"
@@ -34,10 +35,31 @@ type ForwardInputConfig struct {
 	ReadTimeout        helper.Duration `json:"read_timeout"   yaml:"read_timeout"`
 }
 
-// TLSConfig is a configuration struct for forward input TLS
+// TLSConfig is a configuration struct for forward input TLS, which is enabled
+// whenever it is set
 type TLSConfig struct {
-	CertFile string `json:"cert_file" yaml:"cert_file"`
-	KeyFile  string `json:"key_file"  yaml:"key_file"`
+	helper.TLSServerConfig `yaml:",inline"`
+
+	// CertFile is the file path for the certificate. Deprecated in favor of certificate
+	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+
+	// KeyFile is the file path for the private key. Deprecated in favor of private_key
+	KeyFile string `json:"key_file,omitempty"  yaml:"key_file,omitempty"`
+}
+
+// build will build the tls.Config of the forward input's server
+func (c TLSConfig) build(logger *zap.SugaredLogger) (*tls.Config, error) {
+	config := c.TLSServerConfig
+	config.Enable = true
+	if config.Certificate == "" {
+		config.Certificate = c.CertFile
+	}
+	if config.PrivateKey == "" {
+		config.PrivateKey = c.KeyFile
+	}
+
+	// TLS 1.0 is the default version implemented by cypto/tls https://pkg.go.dev/crypto/tls#Config
+	return config.Build(logger, tls.VersionTLS10)
 }
 
 // Build will build a forward input operator.
@@ -47,14 +69,22 @@ func (c *ForwardInputConfig) Build(context operator.BuildContext) ([]operator.Op
 		return nil, err
 	}
 
+	var tlsConfig *tls.Config
+	if c.TLS != nil {
+		tlsConfig, err = c.TLS.build(inputOperator.SugaredLogger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	forwardInput := &ForwardInput{
 		InputOperator: inputOperator,
-		tls:           c.TLS,
 	}
 
 	forwardInput.srv = &http.Server{
 		Addr:        c.ListenAddress,
 		Handler:     forwardInput,
+		TLSConfig:   tlsConfig,
 		ReadTimeout: c.ReadTimeout.Duration,
 		// ReadHeaderTimeout defaults to ReadTimeout, but Gosec fails
 		// if this value is not set. For simplicity, only ReadTimeout
@@ -71,7 +101,9 @@ type ForwardInput struct {
 
 	srv *http.Server
 	ln  net.Listener
-	tls *TLSConfig
+
+	// serveStatus holds the error that stopped the server from serving
+	serveStatus helper.HealthStatus
 }
 
 // Start will start generating log entries.
@@ -90,19 +122,28 @@ func (f *ForwardInput) Start() error {
 	f.ln = ln
 
 	go func() {
-		if f.tls != nil {
-			err = f.srv.ServeTLS(ln, f.tls.CertFile, f.tls.KeyFile)
+		if f.srv.TLSConfig != nil {
+			err = f.srv.ServeTLS(ln, "", "")
 		} else {
 			err = f.srv.Serve(ln)
 		}
 		if err != nil && err != http.ErrServerClosed {
 			f.Errorw("Serve error", zap.Error(err))
+			f.serveStatus.Set(err)
 		}
 	}()
 
 	return nil
 }
 
+// Healthy returns an error if the server failed to serve
+func (f *ForwardInput) Healthy() error {
+	if err := f.serveStatus.Err(); err != nil {
+		return errors.Wrap(err, "serve")
+	}
+	return nil
+}
+
 // Stop will stop generating logs.
 func (f *ForwardInput) Stop() error {
 	return f.srv.Shutdown(context.Background())
@@ -117,7 +158,11 @@ func (f *ForwardInput) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	subject, verified := helper.TLSClientSubject(req.TLS)
 	for _, entry := range entries {
+		if verified {
+			entry.AddLabel(helper.TLSClientSubjectLabel, subject)
+		}
 		f.Write(req.Context(), entry)
 	}
 }
@@ -1,6 +1,7 @@
 package httpevents
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -8,7 +9,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/observiq/stanza/operator/builtin/input/tcp"
 	"github.com/observiq/stanza/operator/helper"
 	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/require"
@@ -172,7 +172,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  key,
@@ -193,7 +193,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  key,
@@ -215,7 +215,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  key,
@@ -237,7 +237,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  key,
@@ -259,7 +259,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  key,
@@ -276,7 +276,7 @@ func TestBuildOperator(t *testing.T) {
 			func() (*HTTPInputConfig, func() error, error) {
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      false,
 					Certificate: "/tmp/crt",
 					PrivateKey:  "/tmp/key",
@@ -298,7 +298,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  key,
@@ -320,7 +320,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: "",
 					PrivateKey:  key,
@@ -342,7 +342,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  "",
@@ -364,7 +364,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: "/tmp/some-invalid-path",
 					PrivateKey:  key,
@@ -386,7 +386,7 @@ func TestBuildOperator(t *testing.T) {
 
 				cfg := NewHTTPInputConfig("test_id")
 				cfg.ListenAddress = "localhost:0"
-				cfg.TLS = tcp.TLSConfig{
+				cfg.TLS = helper.TLSServerConfig{
 					Enable:      true,
 					Certificate: crt,
 					PrivateKey:  "/invalid/path",
@@ -573,8 +573,14 @@ func TestBuildOperator(t *testing.T) {
 			require.NoError(t, err)
 
 			require.Equal(t, cfg.ListenAddress, op.server.Addr)
-			require.NotEmpty(t, op.server.TLSConfig.MinVersion)
-			require.NotEmpty(t, op.server.TLSConfig.Certificates)
+			if cfg.TLS.Enable {
+				require.NotEmpty(t, op.server.TLSConfig.MinVersion)
+				cert, err := op.server.TLSConfig.GetCertificate(&tls.ClientHelloInfo{})
+				require.NoError(t, err)
+				require.NotEmpty(t, cert.Certificate)
+			} else {
+				require.Nil(t, op.server.TLSConfig)
+			}
 			require.Equal(t, cfg.ReadTimeout.Duration, op.server.ReadTimeout)
 			require.Equal(t, cfg.ReadTimeout.Duration, op.server.ReadHeaderTimeout)
 			require.Equal(t, cfg.WriteTimeout.Duration, op.server.WriteTimeout)
@@ -34,6 +34,9 @@ type HTTPInput struct {
 
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
+
+	// serveStatus holds the error that stopped the http server from serving
+	serveStatus helper.HealthStatus
 }
 
 // Start will start listening for log entries over http.
@@ -51,6 +54,14 @@ func (t *HTTPInput) Stop() error {
 	return nil
 }
 
+// Healthy returns an error if the http server failed to serve
+func (t *HTTPInput) Healthy() error {
+	if err := t.serveStatus.Err(); err != nil {
+		return fmt.Errorf("http server failed: %w", err)
+	}
+	return nil
+}
+
 // goListenn will listen for http connections.
 func (t *HTTPInput) goListen(ctx context.Context) {
 	entryCreateMethods := []string{"POST", "PUT"}
@@ -88,11 +99,13 @@ func (t *HTTPInput) goListen(ctx context.Context) {
 		if t.tls {
 			if err := t.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 				t.Errorf("http server failed: %s", err)
+				t.serveStatus.Set(err)
 				return
 			}
 		} else {
 			if err := t.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				t.Errorf("http server failed: %s", err)
+				t.serveStatus.Set(err)
 				return
 			}
 		}
@@ -158,6 +171,9 @@ func (t *HTTPInput) addLabels(req *http.Request, entry *entry.Entry) {
 	if err := addProtoLabels(req.Proto, entry); err != nil {
 		t.Errorf("failed to set protocol and protocol_version labels: %s", err)
 	}
+	if subject, ok := helper.TLSClientSubject(req.TLS); ok {
+		entry.AddLabel(helper.TLSClientSubjectLabel, subject)
+	}
 }
 
 func addPeerLabels(remoteAddr string, entry *entry.Entry) error {
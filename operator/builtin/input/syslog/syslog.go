@@ -0,0 +1,222 @@
+package syslog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/builtin/input/tcp"
+	"github.com/observiq/stanza/operator/builtin/input/udp"
+	syslogparser "github.com/observiq/stanza/operator/builtin/parser/syslog"
+	"github.com/observiq/stanza/operator/helper"
+)
+
+func init() {
+	operator.Register("syslog_input", func() operator.Builder { return NewSyslogInputConfig("") })
+}
+
+// NewSyslogInputConfig creates a new syslog input config with default values
+func NewSyslogInputConfig(operatorID string) *SyslogInputConfig {
+	return &SyslogInputConfig{
+		InputConfig: helper.NewInputConfig(operatorID, "syslog_input"),
+		Protocol:    "auto",
+		Location:    "UTC",
+	}
+}
+
+// SyslogInputConfig is the configuration of a syslog input operator
+type SyslogInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	TCP       *TCPConfig `json:"tcp,omitempty"        yaml:"tcp,omitempty"`
+	UDP       *UDPConfig `json:"udp,omitempty"        yaml:"udp,omitempty"`
+	TLS       *TLSConfig `json:"tls,omitempty"        yaml:"tls,omitempty"`
+	Protocol  string     `json:"protocol,omitempty"   yaml:"protocol,omitempty"`
+	Location  string     `json:"location,omitempty"   yaml:"location,omitempty"`
+	AddLabels bool       `json:"add_labels,omitempty" yaml:"add_labels,omitempty"`
+}
+
+// TCPConfig is the configuration of a syslog input's tcp listener
+type TCPConfig struct {
+	ListenAddress string          `json:"listen_address,omitempty"  yaml:"listen_address,omitempty"`
+	MaxBufferSize helper.ByteSize `json:"max_buffer_size,omitempty" yaml:"max_buffer_size,omitempty"`
+}
+
+// UDPConfig is the configuration of a syslog input's udp listener
+type UDPConfig struct {
+	ListenAddress string `json:"listen_address,omitempty" yaml:"listen_address,omitempty"`
+}
+
+// TLSConfig is the configuration of a syslog input's tls listener, which is
+// enabled whenever it is set
+type TLSConfig struct {
+	TCPConfig              `yaml:",inline"`
+	helper.TLSServerConfig `yaml:",inline"`
+}
+
+// Build will build a syslog input operator
+func (c SyslogInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.TCP == nil && c.UDP == nil && c.TLS == nil {
+		return nil, fmt.Errorf("at least one of 'tcp', 'udp' or 'tls' is required")
+	}
+
+	switch c.Protocol {
+	case "auto", "rfc3164", "rfc5424":
+	default:
+		return nil, fmt.Errorf("invalid protocol '%s', must be one of 'auto', 'rfc3164' or 'rfc5424'", c.Protocol)
+	}
+
+	parserConfig := syslogparser.NewSyslogParserConfig(c.ID())
+	parserConfig.Protocol = c.Protocol
+	parserConfig.Location = c.Location
+	parserConfig.ParseFrom = c.WriteTo
+	parserConfig.ParseTo = c.WriteTo
+	parsers, err := parserConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	syslogInput := &SyslogInput{
+		InputOperator: inputOperator,
+		parser:        parsers[0].(*syslogparser.SyslogParser),
+	}
+
+	if c.TCP != nil {
+		listener, err := c.buildTCP(context, *c.TCP, nil)
+		if err != nil {
+			return nil, fmt.Errorf("tcp: %w", err)
+		}
+		syslogInput.listeners = append(syslogInput.listeners, listener)
+	}
+
+	if c.TLS != nil {
+		tlsConfig := c.TLS.TLSServerConfig
+		tlsConfig.Enable = true
+		listener, err := c.buildTCP(context, c.TLS.TCPConfig, &tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		syslogInput.listeners = append(syslogInput.listeners, listener)
+	}
+
+	if c.UDP != nil {
+		udpConfig := udp.NewUDPInputConfig(c.ID())
+		udpConfig.InputConfig = c.InputConfig
+		udpConfig.ListenAddress = c.UDP.ListenAddress
+		udpConfig.AddLabels = c.AddLabels
+		listener, err := buildListener(context, udpConfig)
+		if err != nil {
+			return nil, fmt.Errorf("udp: %w", err)
+		}
+		syslogInput.listeners = append(syslogInput.listeners, listener)
+	}
+
+	// Listeners write each message to the syslog input, which parses it
+	for _, listener := range syslogInput.listeners {
+		switch l := listener.(type) {
+		case *tcp.TCPInput:
+			l.OutputOperators = []operator.Operator{syslogInput}
+		case *udp.UDPInput:
+			l.OutputOperators = []operator.Operator{syslogInput}
+		}
+	}
+
+	return []operator.Operator{syslogInput}, nil
+}
+
+// buildTCP builds a tcp listener, which detects octet counting and newline framing
+func (c SyslogInputConfig) buildTCP(context operator.BuildContext, cfg TCPConfig, tlsConfig *helper.TLSServerConfig) (operator.Operator, error) {
+	tcpConfig := tcp.NewTCPInputConfig(c.ID())
+	tcpConfig.InputConfig = c.InputConfig
+	tcpConfig.ListenAddress = cfg.ListenAddress
+	tcpConfig.MaxBufferSize = cfg.MaxBufferSize
+	tcpConfig.Framing = tcp.AutoFraming
+	tcpConfig.AddLabels = c.AddLabels
+	if tlsConfig != nil {
+		tcpConfig.TLS = *tlsConfig
+	}
+	return buildListener(context, tcpConfig)
+}
+
+func buildListener(context operator.BuildContext, builder operator.Builder) (operator.Operator, error) {
+	ops, err := builder.Build(context)
+	if err != nil {
+		return nil, err
+	}
+	return ops[0], nil
+}
+
+// SyslogInput is an operator that receives syslog messages over tcp, udp and tls,
+// and parses them
+type SyslogInput struct {
+	helper.InputOperator
+	parser    *syslogparser.SyslogParser
+	listeners []operator.Operator
+}
+
+// Start will start the listeners
+func (s *SyslogInput) Start() error {
+	for i, listener := range s.listeners {
+		if err := listener.Start(); err != nil {
+			for _, started := range s.listeners[:i] {
+				_ = started.Stop()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop will stop the listeners
+func (s *SyslogInput) Stop() error {
+	var firstErr error
+	for _, listener := range s.listeners {
+		if err := listener.Stop(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Healthy returns an error if any listener is unhealthy
+func (s *SyslogInput) Healthy() error {
+	for _, listener := range s.listeners {
+		if checker, ok := listener.(operator.HealthChecker); ok {
+			if err := checker.Healthy(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Process will parse a message received by a listener, and write it to the outputs
+// of the syslog input. Messages that cannot be parsed are written unparsed.
+func (s *SyslogInput) Process(ctx context.Context, entry *entry.Entry) error {
+	if err := s.parser.ParseEntry(ctx, entry); err == nil {
+		s.addResource(entry)
+	}
+	s.Write(ctx, entry)
+	return nil
+}
+
+// addResource copies the hostname and appname of a parsed message to its resource
+func (s *SyslogInput) addResource(e *entry.Entry) {
+	value, _ := e.Get(s.WriteTo)
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if hostname, ok := record["hostname"].(string); ok {
+		e.AddResourceKey("host.name", hostname)
+	}
+	if appname, ok := record["appname"].(string); ok {
+		e.AddResourceKey("service.name", appname)
+	}
+}
@@ -0,0 +1,206 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rfc3164Message = "<34>Jan 12 06:30:00 web-01 apache_server: test message"
+	rfc5424Message = `<86>1 2015-08-05T21:58:59.693Z web-02 SecureAuth0 23108 ID52020 [SecureAuth@27389 PEN="27389"] test message`
+)
+
+// freeAddress returns a local address that is not in use by the network
+func freeAddress(t *testing.T, network string) string {
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+		return conn.LocalAddr().String()
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func startSyslogInput(t *testing.T, cfg *SyslogInputConfig) *testutil.FakeOutput {
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	syslogInput := ops[0].(*SyslogInput)
+
+	fake := testutil.NewFakeOutput(t)
+	syslogInput.OutputOperators = []operator.Operator{fake}
+
+	require.NoError(t, syslogInput.Start())
+	t.Cleanup(func() { require.NoError(t, syslogInput.Stop()) })
+	return fake
+}
+
+func expectSyslogEntry(t *testing.T, fake *testutil.FakeOutput, hostname, appname, message string) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		record, ok := e.Record.(map[string]interface{})
+		require.True(t, ok, "expected a parsed record, got %v", e.Record)
+		require.Equal(t, hostname, record["hostname"])
+		require.Equal(t, appname, record["appname"])
+		require.Equal(t, message, record["message"])
+		require.Equal(t, hostname, e.Resource["host.name"])
+		require.Equal(t, appname, e.Resource["service.name"])
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	return nil
+}
+
+func TestSyslogInputBuild(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(*SyslogInputConfig)
+		expected string
+	}{
+		{
+			"NoListener",
+			func(cfg *SyslogInputConfig) {},
+			"at least one of 'tcp', 'udp' or 'tls' is required",
+		},
+		{
+			"InvalidProtocol",
+			func(cfg *SyslogInputConfig) {
+				cfg.UDP = &UDPConfig{ListenAddress: "127.0.0.1:0"}
+				cfg.Protocol = "rfc822"
+			},
+			"invalid protocol 'rfc822'",
+		},
+		{
+			"InvalidLocation",
+			func(cfg *SyslogInputConfig) {
+				cfg.UDP = &UDPConfig{ListenAddress: "127.0.0.1:0"}
+				cfg.Location = "Mars/Olympus_Mons"
+			},
+			"unknown time zone",
+		},
+		{
+			"InvalidTCPAddress",
+			func(cfg *SyslogInputConfig) { cfg.TCP = &TCPConfig{ListenAddress: "localhost"} },
+			"tcp: failed to resolve listen_address",
+		},
+		{
+			"MissingTLSCertificate",
+			func(cfg *SyslogInputConfig) { cfg.TLS = &TLSConfig{TCPConfig: TCPConfig{ListenAddress: "127.0.0.1:0"}} },
+			"tls: missing required parameter 'certificate'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewSyslogInputConfig("test_input")
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestSyslogInputTCP(t *testing.T) {
+	cfg := NewSyslogInputConfig("test_input")
+	cfg.TCP = &TCPConfig{ListenAddress: freeAddress(t, "tcp")}
+	fake := startSyslogInput(t, cfg)
+
+	conn, err := net.Dial("tcp", cfg.TCP.ListenAddress)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Octet counted and newline framed messages are accepted on the same connection
+	_, err = fmt.Fprintf(conn, "%d %s%s\n", len(rfc5424Message), rfc5424Message, rfc3164Message)
+	require.NoError(t, err)
+
+	e := expectSyslogEntry(t, fake, "web-02", "SecureAuth0", "test message")
+	require.Equal(t, time.Date(2015, 8, 5, 21, 58, 59, 693000000, time.UTC), e.Timestamp.UTC())
+	require.Equal(t, entry.Info, e.Severity)
+	expectSyslogEntry(t, fake, "web-01", "apache_server", "test message")
+}
+
+func TestSyslogInputUDP(t *testing.T) {
+	cfg := NewSyslogInputConfig("test_input")
+	cfg.UDP = &UDPConfig{ListenAddress: freeAddress(t, "udp")}
+	cfg.AddLabels = true
+	fake := startSyslogInput(t, cfg)
+
+	conn, err := net.Dial("udp", cfg.UDP.ListenAddress)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(rfc3164Message))
+	require.NoError(t, err)
+	e := expectSyslogEntry(t, fake, "web-01", "apache_server", "test message")
+	require.Equal(t, "IP.UDP", e.Labels["net.transport"])
+}
+
+func TestSyslogInputTLS(t *testing.T) {
+	ca := testutil.NewCertificateAuthority(t, "server-ca")
+	certPEM, keyPEM := ca.Issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	dir := testutil.NewTempDir(t)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+	cfg := NewSyslogInputConfig("test_input")
+	cfg.TCP = &TCPConfig{ListenAddress: freeAddress(t, "tcp")}
+	cfg.TLS = &TLSConfig{
+		TCPConfig: TCPConfig{ListenAddress: freeAddress(t, "tcp")},
+		TLSServerConfig: helper.TLSServerConfig{
+			Certificate: certFile,
+			PrivateKey:  keyFile,
+		},
+	}
+	fake := startSyslogInput(t, cfg)
+
+	conn, err := tls.Dial("tcp", cfg.TLS.ListenAddress, &tls.Config{
+		RootCAs:    ca.CertPool(),
+		ServerName: "localhost",
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = fmt.Fprintf(conn, "%d %s", len(rfc5424Message), rfc5424Message)
+	require.NoError(t, err)
+	expectSyslogEntry(t, fake, "web-02", "SecureAuth0", "test message")
+
+	// The plain tcp listener is served alongside the tls listener
+	plain, err := net.Dial("tcp", cfg.TCP.ListenAddress)
+	require.NoError(t, err)
+	defer plain.Close()
+
+	_, err = fmt.Fprintf(plain, "%s\n", rfc3164Message)
+	require.NoError(t, err)
+	expectSyslogEntry(t, fake, "web-01", "apache_server", "test message")
+}
+
+func TestSyslogInputInvalidMessage(t *testing.T) {
+	cfg := NewSyslogInputConfig("test_input")
+	cfg.UDP = &UDPConfig{ListenAddress: freeAddress(t, "udp")}
+	fake := startSyslogInput(t, cfg)
+
+	conn, err := net.Dial("udp", cfg.UDP.ListenAddress)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("not a syslog message"))
+	require.NoError(t, err)
+	fake.ExpectRecord(t, "not a syslog message")
+}
@@ -0,0 +1,244 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"golang.org/x/text/encoding"
+)
+
+const (
+	// NewlineFraming splits messages on newlines, or on the multiline patterns if set
+	NewlineFraming = "newline"
+	// OctetCountingFraming reads messages prefixed with their length, as described by RFC 6587
+	OctetCountingFraming = "octet_counting"
+	// AutoFraming detects octet counting or newline framing for each message
+	AutoFraming = "auto"
+	// NULFraming splits messages on NUL bytes
+	NULFraming = "nul"
+
+	// maxLengthDigits is the maximum number of digits of an octet count
+	maxLengthDigits = 10
+)
+
+// newSplitFunc returns the split function of a framing
+func newSplitFunc(context operator.BuildContext, framing string, multiline helper.MultilineConfig, maxSize int) (bufio.SplitFunc, error) {
+	if framing != NewlineFraming && (multiline.LineStartPattern != "" || multiline.LineEndPattern != "") {
+		return nil, fmt.Errorf("multiline is only supported with framing '%s'", NewlineFraming)
+	}
+
+	switch framing {
+	case NewlineFraming:
+		return multiline.Build(context, encoding.Nop, true)
+	case OctetCountingFraming:
+		return skipNewlines(octetCountingSplitFunc(maxSize)), nil
+	case AutoFraming:
+		newlineSplit, err := helper.NewNewlineSplitFunc(encoding.Nop, true)
+		if err != nil {
+			return nil, err
+		}
+		return skipNewlines(autoSplitFunc(octetCountingSplitFunc(maxSize), newlineSplit)), nil
+	case NULFraming:
+		return nulSplitFunc, nil
+	default:
+		return nil, fmt.Errorf("invalid framing '%s', must be one of '%s', '%s', '%s' or '%s'",
+			framing, NewlineFraming, OctetCountingFraming, AutoFraming, NULFraming)
+	}
+}
+
+// octetCountingSplitFunc splits messages of the form "MSG-LEN SP MSG", where
+// MSG-LEN is the length of MSG in bytes
+func octetCountingSplitFunc(maxSize int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		space := bytes.IndexByte(data, ' ')
+		if space < 0 {
+			prefix := data
+			if len(prefix) > maxLengthDigits {
+				prefix = prefix[:maxLengthDigits+1]
+			}
+			if err := checkLength(prefix); err != nil {
+				return 0, nil, err
+			}
+			if atEOF {
+				return 0, nil, fmt.Errorf("connection closed within the length of a message")
+			}
+			return 0, nil, nil
+		}
+
+		if err := checkLength(data[:space]); err != nil {
+			return 0, nil, err
+		}
+		if space == 0 {
+			return 0, nil, fmt.Errorf("message length is missing")
+		}
+
+		length, err := strconv.Atoi(string(data[:space]))
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid message length %q", data[:space])
+		}
+		if length > maxSize {
+			return 0, nil, fmt.Errorf("message length %d exceeds max_buffer_size of %d bytes", length, maxSize)
+		}
+
+		end := space + 1 + length
+		if len(data) < end {
+			if atEOF {
+				return 0, nil, fmt.Errorf("connection closed within a message of %d bytes", length)
+			}
+			return 0, nil, nil
+		}
+		return end, data[space+1 : end], nil
+	}
+}
+
+// checkLength returns an error if a prefix of a message length is invalid
+func checkLength(length []byte) error {
+	if len(length) > maxLengthDigits {
+		return fmt.Errorf("message length %q is too long", length)
+	}
+	for _, c := range length {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("invalid message length %q, expected octet counting framing", length)
+		}
+	}
+	return nil
+}
+
+// autoSplitFunc uses octet counting for messages that start with a length, a
+// space, and the "<" that starts the priority of a syslog message, and newline
+// framing for other messages, including those that start with "<"
+func autoSplitFunc(octetCounting, newline bufio.SplitFunc) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		for i, c := range data {
+			switch {
+			case c >= '0' && c <= '9' && i < maxLengthDigits:
+				continue
+			case c == ' ' && i > 0 && i+1 < len(data):
+				if data[i+1] == '<' {
+					return octetCounting(data, atEOF)
+				}
+				return newline(data, atEOF)
+			case c == ' ' && i > 0:
+				// The byte after the space is not known yet
+				return waitOrNewline(newline, data, atEOF)
+			default:
+				return newline(data, atEOF)
+			}
+		}
+
+		// The message starts with digits, so wait until its framing is known
+		return waitOrNewline(newline, data, atEOF)
+	}
+}
+
+// waitOrNewline requests more data, or splits the remaining data on newlines if
+// no more data is expected
+func waitOrNewline(newline bufio.SplitFunc, data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF {
+		return newline(data, atEOF)
+	}
+	return 0, nil, nil
+}
+
+// skipNewlines wraps a split function to ignore newlines between messages,
+// which some senders append to octet counted messages
+func skipNewlines(split bufio.SplitFunc) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		skipped := 0
+		for skipped < len(data) && (data[skipped] == '\n' || data[skipped] == '\r') {
+			skipped++
+		}
+		if skipped > 0 {
+			return skipped, nil, nil
+		}
+		return split(data, atEOF)
+	}
+}
+
+// nulSplitFunc splits messages on NUL bytes
+func nulSplitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	// Flush if no more data is expected
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// errIdle is returned by an idleFlush when its connection has been idle for the
+// flush period while a message was pending
+var errIdle = errors.New("connection is idle")
+
+// idleFlush reads messages from a connection, and flushes a partial message once the
+// connection has been idle for the flush period. Newline framing holds the last log
+// of a connection while it waits for more of its lines, so without a flush period the
+// last log is not written until the sender writes another log or closes the connection.
+// It is only used by the goroutine reading the connection.
+type idleFlush struct {
+	conn   net.Conn
+	split  bufio.SplitFunc
+	period time.Duration
+
+	// pending is set while the split function is waiting for more data
+	pending bool
+	// deadline is set while the connection has a read deadline
+	deadline bool
+}
+
+// newIdleFlush wraps a connection and its split function to flush partial messages
+// once the connection has been idle for the period
+func newIdleFlush(conn net.Conn, split bufio.SplitFunc, period time.Duration) *idleFlush {
+	return &idleFlush{conn: conn, split: split, period: period}
+}
+
+// Read reads from the connection. If a message is pending and nothing is read within
+// the flush period, it returns errIdle, which ends the scan of the connection like the
+// end of a connection, so that the pending message is flushed. The connection is then
+// read by a new scanner.
+func (f *idleFlush) Read(p []byte) (int, error) {
+	switch {
+	case f.pending:
+		if err := f.conn.SetReadDeadline(time.Now().Add(f.period)); err != nil {
+			return 0, err
+		}
+		f.deadline = true
+	case f.deadline:
+		if err := f.conn.SetReadDeadline(time.Time{}); err != nil {
+			return 0, err
+		}
+		f.deadline = false
+	}
+
+	n, err := f.conn.Read(p)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() && f.pending {
+		f.pending = false
+		return n, errIdle
+	}
+	return n, err
+}
+
+// Split splits the data with the wrapped split function, recording whether it is
+// waiting for more data
+func (f *idleFlush) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = f.split(data, atEOF)
+	f.pending = err == nil && !atEOF && advance == 0 && token == nil && len(data) > 0
+	return advance, token, err
+}
@@ -3,9 +3,9 @@ package tcp
 import (
 	"bufio"
 	"context"
-	"crypto/rand"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
@@ -26,6 +26,10 @@ const (
 	// DefaultMaxBufferSize is the max buffer sized used
 	// if MaxBufferSize is not set
 	DefaultMaxBufferSize = 1024 * 1024
+
+	// defaultForceFlushPeriod is how long a connection may be idle before a
+	// partial log is written
+	defaultForceFlushPeriod = 500 * time.Millisecond
 )
 
 func init() {
@@ -35,7 +39,10 @@ func init() {
 // NewTCPInputConfig creates a new TCP input config with default values
 func NewTCPInputConfig(operatorID string) *TCPInputConfig {
 	return &TCPInputConfig{
-		InputConfig: helper.NewInputConfig(operatorID, "tcp_input"),
+		InputConfig:      helper.NewInputConfig(operatorID, "tcp_input"),
+		Framing:          NewlineFraming,
+		Multiline:        helper.NewMultilineConfig(),
+		ForceFlushPeriod: helper.NewDuration(defaultForceFlushPeriod),
 	}
 }
 
@@ -43,26 +50,20 @@ func NewTCPInputConfig(operatorID string) *TCPInputConfig {
 type TCPInputConfig struct {
 	helper.InputConfig `yaml:",inline"`
 
-	MaxBufferSize helper.ByteSize `json:"max_buffer_size,omitempty" yaml:"max_buffer_size,omitempty"`
-	ListenAddress string          `json:"listen_address,omitempty" yaml:"listen_address,omitempty"`
-	TLS           TLSConfig       `json:"tls,omitempty" yaml:"tls,omitempty"`
-	AddLabels     bool            `json:"add_labels,omitempty" yaml:"add_labels,omitempty"`
+	MaxBufferSize helper.ByteSize        `json:"max_buffer_size,omitempty" yaml:"max_buffer_size,omitempty"`
+	ListenAddress string                 `json:"listen_address,omitempty" yaml:"listen_address,omitempty"`
+	TLS           TLSConfig              `json:"tls,omitempty" yaml:"tls,omitempty"`
+	AddLabels     bool                   `json:"add_labels,omitempty" yaml:"add_labels,omitempty"`
+	Framing       string                 `json:"framing,omitempty" yaml:"framing,omitempty"`
+	Multiline     helper.MultilineConfig `json:"multiline,omitempty" yaml:"multiline,omitempty"`
+
+	// ForceFlushPeriod is how long a connection with newline framing may be idle before
+	// the log it holds while waiting for more lines is written. Disabled if 0.
+	ForceFlushPeriod helper.Duration `json:"force_flush_period,omitempty" yaml:"force_flush_period,omitempty"`
 }
 
 // TLSConfig is the configuration for a TLS listener
-type TLSConfig struct {
-	// Enable forces the user of TLS
-	Enable bool `json:"enable,omitempty" yaml:"enable,omitempty"`
-
-	// Certificate is the file path for the certificate
-	Certificate string `json:"certificate,omitempty" yaml:"certificate,omitempty"`
-
-	// PrivateKey is the file path for the private key
-	PrivateKey string `json:"private_key,omitempty" yaml:"private_key,omitempty"`
-
-	// MinVersion is the minimum tls version
-	MinVersion float32 `json:"min_version,omitempty" yaml:"min_version,omitempty"`
-}
+type TLSConfig = helper.TLSServerConfig
 
 // Build will build a tcp input operator.
 func (c TCPInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
@@ -90,36 +91,24 @@ func (c TCPInputConfig) Build(context operator.BuildContext) ([]operator.Operato
 		return nil, fmt.Errorf("failed to resolve listen_address: %s", err)
 	}
 
-	cert := tls.Certificate{}
-	if c.TLS.Enable {
-		if c.TLS.Certificate == "" {
-			return nil, fmt.Errorf("missing required parameter 'certificate', required when TLS is enabled")
-		}
-
-		if c.TLS.PrivateKey == "" {
-			return nil, fmt.Errorf("missing required parameter 'private_key', required when TLS is enabled")
-		}
+	splitFunc, err := newSplitFunc(context, c.Framing, c.Multiline, int(c.MaxBufferSize))
+	if err != nil {
+		return nil, err
+	}
 
-		c, err := tls.LoadX509KeyPair(c.TLS.Certificate, c.TLS.PrivateKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load tls certificate: %w", err)
-		}
-		cert = c
+	if c.ForceFlushPeriod.Raw() < 0 {
+		return nil, fmt.Errorf("invalid value for parameter 'force_flush_period', must not be negative")
+	}
+	// Only newline framing waits for more data once a message could be complete
+	var flushPeriod time.Duration
+	if c.Framing == NewlineFraming {
+		flushPeriod = c.ForceFlushPeriod.Raw()
 	}
 
-	var tlsMinVersion uint16
-	switch c.TLS.MinVersion {
-	case 0, 1.0:
-		// TLS 1.0 is the default version implemented by cypto/tls https://pkg.go.dev/crypto/tls#Config
-		tlsMinVersion = tls.VersionTLS10
-	case 1.1:
-		tlsMinVersion = tls.VersionTLS11
-	case 1.2:
-		tlsMinVersion = tls.VersionTLS12
-	case 1.3:
-		tlsMinVersion = tls.VersionTLS13
-	default:
-		return nil, fmt.Errorf("unsupported tls version: %f", c.TLS.MinVersion)
+	// TLS 1.0 is the default version implemented by cypto/tls https://pkg.go.dev/crypto/tls#Config
+	tlsConfig, err := c.TLS.Build(inputOperator.SugaredLogger, tls.VersionTLS10)
+	if err != nil {
+		return nil, err
 	}
 
 	tcpInput := &TCPInput{
@@ -127,9 +116,9 @@ func (c TCPInputConfig) Build(context operator.BuildContext) ([]operator.Operato
 		address:       c.ListenAddress,
 		maxBufferSize: int(c.MaxBufferSize),
 		addLabels:     c.AddLabels,
-		tlsEnable:     c.TLS.Enable,
-		tlsKeyPair:    cert,
-		tlsMinVersion: tlsMinVersion,
+		splitFunc:     splitFunc,
+		tlsConfig:     tlsConfig,
+		flushPeriod:   flushPeriod,
 		backoff: backoff.Backoff{
 			Min:    100 * time.Millisecond,
 			Max:    3 * time.Second,
@@ -146,14 +135,17 @@ type TCPInput struct {
 	address       string
 	maxBufferSize int
 	addLabels     bool
-	tlsEnable     bool
-	tlsKeyPair    tls.Certificate
-	tlsMinVersion uint16
+	splitFunc     bufio.SplitFunc
+	tlsConfig     *tls.Config
+	flushPeriod   time.Duration
 	backoff       backoff.Backoff
 
 	listener net.Listener
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
+
+	// acceptStatus holds the most recent error accepting a connection, until one is accepted
+	acceptStatus helper.HealthStatus
 }
 
 // Start will start listening for log entries over tcp.
@@ -169,7 +161,7 @@ func (t *TCPInput) Start() error {
 }
 
 func (t *TCPInput) configureListener() error {
-	if !t.tlsEnable {
+	if t.tlsConfig == nil {
 		listener, err := net.Listen("tcp", t.address)
 		if err != nil {
 			return fmt.Errorf("failed to configure tcp listener: %w", err)
@@ -178,15 +170,7 @@ func (t *TCPInput) configureListener() error {
 		return nil
 	}
 
-	// #nosec - User to specify tls minimum version
-	config := tls.Config{
-		Certificates: []tls.Certificate{t.tlsKeyPair},
-		MinVersion:   t.tlsMinVersion,
-	}
-	config.Time = func() time.Time { return time.Now() }
-	config.Rand = rand.Reader
-
-	listener, err := tls.Listen("tcp", t.address, &config)
+	listener, err := tls.Listen("tcp", t.address, t.tlsConfig)
 	if err != nil {
 		return fmt.Errorf("failed to configure tls listener: %w", err)
 	}
@@ -210,11 +194,13 @@ func (t *TCPInput) goListen(ctx context.Context) {
 					return
 				default:
 					t.Debugw("Listener accept error", zap.Error(err))
+					t.acceptStatus.Set(err)
 					time.Sleep(t.backoff.Duration())
 					continue
 				}
 			}
 			t.backoff.Reset()
+			t.acceptStatus.Set(nil)
 
 			t.Debugf("Received connection: %s", conn.RemoteAddr().String())
 			subctx, cancel := context.WithCancel(ctx)
@@ -246,35 +232,43 @@ func (t *TCPInput) goHandleMessages(ctx context.Context, conn net.Conn, cancel c
 		defer t.wg.Done()
 		defer cancel()
 
-		// Initial buffer size is 64k
-		buf := make([]byte, 0, 64*1024)
-		scanner := bufio.NewScanner(conn)
-		scanner.Buffer(buf, t.maxBufferSize*1024)
-		for scanner.Scan() {
-			entry, err := t.NewEntry(scanner.Text())
-			if err != nil {
-				t.Errorw("Failed to create entry", zap.Error(err))
-				continue
+		// The subject of the client's certificate is known once the handshake completes
+		var clientSubject string
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				t.Errorw("TLS handshake failed", zap.Error(err))
+				return
 			}
+			state := tlsConn.ConnectionState()
+			clientSubject, _ = helper.TLSClientSubject(&state)
+		}
 
-			if t.addLabels {
-				entry.AddLabel("net.transport", "IP.TCP")
-				if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
-					entry.AddLabel("net.peer.ip", addr.IP.String())
-					entry.AddLabel("net.peer.port", strconv.FormatInt(int64(addr.Port), 10))
-				}
+		var reader io.Reader = conn
+		split := t.splitFunc
+		if t.flushPeriod > 0 {
+			flush := newIdleFlush(conn, t.splitFunc, t.flushPeriod)
+			reader, split = flush, flush.Split
+		}
 
-				if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
-					entry.AddLabel("net.host.ip", addr.IP.String())
-					entry.AddLabel("net.host.port", strconv.FormatInt(int64(addr.Port), 10))
-				}
+		// Initial buffer size is 64k
+		buf := make([]byte, 0, 64*1024)
+		for {
+			scanner := bufio.NewScanner(reader)
+			scanner.Buffer(buf, t.maxBufferSize*1024)
+			scanner.Split(split)
+			for scanner.Scan() {
+				t.writeMessage(ctx, conn, scanner.Text(), clientSubject)
 			}
 
-			t.Write(ctx, entry)
-		}
-		if err := scanner.Err(); err != nil {
-			// Use of closed network connection is expected if the context is canceled
-			if strings.Contains(err.Error(), "use of closed network connection") {
+			err := scanner.Err()
+			switch {
+			case err == nil:
+				return
+			case err == errIdle:
+				// The pending message was flushed, so the connection is scanned again
+				continue
+			case strings.Contains(err.Error(), "use of closed network connection"):
+				// Use of closed network connection is expected if the context is canceled
 				select {
 				case <-ctx.Done():
 					return
@@ -282,10 +276,47 @@ func (t *TCPInput) goHandleMessages(ctx context.Context, conn net.Conn, cancel c
 				}
 			}
 			t.Errorw("Scanner error", zap.Error(err))
+			return
 		}
 	}()
 }
 
+// writeMessage writes an entry for a message read from a connection
+func (t *TCPInput) writeMessage(ctx context.Context, conn net.Conn, message, clientSubject string) {
+	entry, err := t.NewEntry(message)
+	if err != nil {
+		t.Errorw("Failed to create entry", zap.Error(err))
+		return
+	}
+
+	if clientSubject != "" {
+		entry.AddLabel(helper.TLSClientSubjectLabel, clientSubject)
+	}
+
+	if t.addLabels {
+		entry.AddLabel("net.transport", "IP.TCP")
+		if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			entry.AddLabel("net.peer.ip", addr.IP.String())
+			entry.AddLabel("net.peer.port", strconv.FormatInt(int64(addr.Port), 10))
+		}
+
+		if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+			entry.AddLabel("net.host.ip", addr.IP.String())
+			entry.AddLabel("net.host.port", strconv.FormatInt(int64(addr.Port), 10))
+		}
+	}
+
+	t.Write(ctx, entry)
+}
+
+// Healthy returns an error if the listener is failing to accept connections
+func (t *TCPInput) Healthy() error {
+	if err := t.acceptStatus.Err(); err != nil {
+		return fmt.Errorf("failed to accept connection: %w", err)
+	}
+	return nil
+}
+
 // Stop will stop listening for log entries over TCP.
 func (t *TCPInput) Stop() error {
 	t.cancel()
@@ -1,15 +1,20 @@
 package tcp
 
 import (
+	"bufio"
 	"crypto/tls"
+	"crypto/x509"
 	"net"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/observiq/stanza/entry"
 	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
 	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -114,6 +119,57 @@ func tcpInputTest(input []byte, expected []string) func(t *testing.T) {
 	}
 }
 
+func tcpInputFramingTest(modify func(*TCPInputConfig), input []byte, expected []string) func(t *testing.T) {
+	return func(t *testing.T) {
+		cfg := NewTCPInputConfig("test_id")
+		cfg.ListenAddress = ":0"
+		modify(cfg)
+
+		ops, err := cfg.Build(testutil.NewBuildContext(t))
+		require.NoError(t, err)
+		op := ops[0]
+
+		mockOutput := testutil.Operator{}
+		tcpInput := op.(*TCPInput)
+		tcpInput.InputOperator.OutputOperators = []operator.Operator{&mockOutput}
+
+		entryChan := make(chan *entry.Entry, 1)
+		mockOutput.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			entryChan <- args.Get(1).(*entry.Entry)
+		}).Return(nil)
+
+		err = tcpInput.Start()
+		require.NoError(t, err)
+		defer tcpInput.Stop()
+
+		conn, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write(input)
+		require.NoError(t, err)
+
+		// Closing the write side flushes messages that are terminated by the end of the connection
+		require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+
+		for _, expectedMessage := range expected {
+			select {
+			case entry := <-entryChan:
+				require.Equal(t, expectedMessage, entry.Record)
+			case <-time.After(time.Second):
+				require.FailNow(t, "Timed out waiting for message to be written")
+			}
+		}
+
+		select {
+		case entry := <-entryChan:
+			require.FailNow(t, "Unexpected entry: %s", entry)
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
 func tcpInputLabelsTest(input []byte, expected []string) func(t *testing.T) {
 	return func(t *testing.T) {
 		cfg := NewTCPInputConfig("test_id")
@@ -410,6 +466,10 @@ func TestBuild(t *testing.T) {
 			cfg.ListenAddress = tc.inputRecord.ListenAddress
 			cfg.MaxBufferSize = tc.inputRecord.MaxBufferSize
 			cfg.TLS = tc.inputRecord.TLS
+			if tc.inputRecord.Framing != "" {
+				cfg.Framing = tc.inputRecord.Framing
+			}
+			cfg.Multiline = tc.inputRecord.Multiline
 			_, err := cfg.Build(testutil.NewBuildContext(t))
 			if tc.expectErr {
 				require.Error(t, err)
@@ -425,6 +485,116 @@ func TestTcpInput(t *testing.T) {
 	t.Run("CarriageReturn", tcpInputTest([]byte("message\r\n"), []string{"message"}))
 }
 
+func TestTcpInputFraming(t *testing.T) {
+	framing := func(framing string) func(*TCPInputConfig) {
+		return func(cfg *TCPInputConfig) { cfg.Framing = framing }
+	}
+
+	t.Run("NewlineUnterminated", tcpInputFramingTest(framing(NewlineFraming),
+		[]byte("message1\nmessage2"), []string{"message1", "message2"}))
+	t.Run("OctetCounting", tcpInputFramingTest(framing(OctetCountingFraming),
+		[]byte("8 message110 message\n2\n"), []string{"message1", "message\n2\n"}))
+	t.Run("OctetCountingTrailingNewlines", tcpInputFramingTest(framing(OctetCountingFraming),
+		[]byte("8 message1\n8 message2\r\n"), []string{"message1", "message2"}))
+	t.Run("OctetCountingInvalid", tcpInputFramingTest(framing(OctetCountingFraming),
+		[]byte("message1\n8 message2"), []string{}))
+	t.Run("Auto", tcpInputFramingTest(framing(AutoFraming),
+		[]byte("<13>message1\n13 <13>message\n2<13>message3\n2021-10-01 message4\n200 OK\n15 messages sent\n"),
+		[]string{"<13>message1", "<13>message\n2", "<13>message3", "2021-10-01 message4", "200 OK", "15 messages sent"}))
+	t.Run("NUL", tcpInputFramingTest(framing(NULFraming),
+		[]byte("message\n1\x00message2\x00message3"), []string{"message\n1", "message2", "message3"}))
+	t.Run("MultilineLineStart", tcpInputFramingTest(func(cfg *TCPInputConfig) {
+		cfg.Multiline = helper.MultilineConfig{LineStartPattern: `^\d{4}-\d{2}-\d{2}`}
+	}, []byte("2021-10-01 panic: oops\n\tmain.go:10\n\tmain.go:20\n2021-10-01 starting\n"),
+		[]string{"2021-10-01 panic: oops\n\tmain.go:10\n\tmain.go:20\n", "2021-10-01 starting\n"}))
+}
+
+func TestTcpInputForceFlush(t *testing.T) {
+	cases := []struct {
+		name     string
+		period   time.Duration
+		expected bool
+	}{
+		{"Idle", 100 * time.Millisecond, true},
+		{"Disabled", 0, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewTCPInputConfig("test_id")
+			cfg.ListenAddress = ":0"
+			cfg.Multiline = helper.MultilineConfig{LineStartPattern: `^\d{4}-\d{2}-\d{2}`}
+			cfg.ForceFlushPeriod = helper.NewDuration(tc.period)
+
+			ops, err := cfg.Build(testutil.NewBuildContext(t))
+			require.NoError(t, err)
+			tcpInput := ops[0].(*TCPInput)
+			fake := testutil.NewFakeOutput(t)
+			tcpInput.InputOperator.OutputOperators = []operator.Operator{fake}
+
+			require.NoError(t, tcpInput.Start())
+			defer tcpInput.Stop()
+
+			conn, err := net.Dial("tcp", tcpInput.listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			// The connection stays open, so the last log is only written once it is idle
+			_, err = conn.Write([]byte("2021-10-01 starting\n2021-10-01 panic: oops\n\tmain.go:10\n"))
+			require.NoError(t, err)
+			fake.ExpectRecord(t, "2021-10-01 starting\n")
+
+			if !tc.expected {
+				fake.ExpectNoEntry(t, 5*cfg.ForceFlushPeriod.Raw()+300*time.Millisecond)
+				return
+			}
+			fake.ExpectRecord(t, "2021-10-01 panic: oops\n\tmain.go:10\n")
+
+			// The connection is read as before once the log is flushed
+			_, err = conn.Write([]byte("2021-10-01 recovered\n2021-10-01 done\n"))
+			require.NoError(t, err)
+			fake.ExpectRecord(t, "2021-10-01 recovered\n")
+			fake.ExpectRecord(t, "2021-10-01 done\n")
+		})
+	}
+}
+
+func TestOctetCountingSplitFunc(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected []string
+		err      string
+	}{
+		{"Simple", "5 hello6 world!", []string{"hello", "world!"}, ""},
+		{"Empty", "0 5 hello", []string{"", "hello"}, ""},
+		{"NotANumber", "5 hello<13>world", []string{"hello"}, `invalid message length "<13>world"`},
+		{"TooManyDigits", "12345678901 hello", nil, "is too long"},
+		{"TooLarge", "70000 hello", nil, "exceeds max_buffer_size of 65536 bytes"},
+		{"Truncated", "5 hello10 world", []string{"hello"}, "connection closed within a message of 10 bytes"},
+		{"TruncatedLength", "5 hello10", []string{"hello"}, "connection closed within the length of a message"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(tc.input))
+			scanner.Split(octetCountingSplitFunc(minBufferSize))
+
+			var messages []string
+			for scanner.Scan() {
+				messages = append(messages, scanner.Text())
+			}
+			require.Equal(t, tc.expected, messages)
+			if tc.err == "" {
+				require.NoError(t, scanner.Err())
+				return
+			}
+			require.Error(t, scanner.Err())
+			require.Contains(t, scanner.Err().Error(), tc.err)
+		})
+	}
+}
+
 func TestTcpInputAattributes(t *testing.T) {
 	t.Run("Simple", tcpInputLabelsTest([]byte("message\n"), []string{"message"}))
 	t.Run("CarriageReturn", tcpInputLabelsTest([]byte("message\r\n"), []string{"message"}))
@@ -435,6 +605,62 @@ func TestTLSTcpInput(t *testing.T) {
 	t.Run("CarriageReturn", tlsTCPInputTest([]byte("message\r\n"), []string{"message"}))
 }
 
+func TestMutualTLSTcpInput(t *testing.T) {
+	dir := testutil.NewTempDir(t)
+	serverCA := testutil.NewCertificateAuthority(t, "server-ca")
+	clientCA := testutil.NewCertificateAuthority(t, "client-ca")
+	certPEM, keyPEM := serverCA.Issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+
+	cfg := NewTCPInputConfig("test_id")
+	cfg.ListenAddress = "127.0.0.1:0"
+	cfg.TLS.Enable = true
+	cfg.TLS.Certificate = filepath.Join(dir, "server.crt")
+	cfg.TLS.PrivateKey = filepath.Join(dir, "server.key")
+	cfg.TLS.ClientCA = filepath.Join(dir, "client-ca.crt")
+	require.NoError(t, os.WriteFile(cfg.TLS.Certificate, certPEM, 0600))
+	require.NoError(t, os.WriteFile(cfg.TLS.PrivateKey, keyPEM, 0600))
+	require.NoError(t, os.WriteFile(cfg.TLS.ClientCA, clientCA.CertPEM(), 0600))
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	tcpInput := ops[0].(*TCPInput)
+
+	fake := testutil.NewFakeOutput(t)
+	tcpInput.InputOperator.OutputOperators = []operator.Operator{fake}
+	require.NoError(t, tcpInput.Start())
+	defer tcpInput.Stop()
+
+	conn, err := tls.Dial("tcp", tcpInput.listener.Addr().String(), &tls.Config{
+		RootCAs:      serverCA.CertPool(),
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{clientCA.IssueKeyPair(t, "web-01", x509.ExtKeyUsageClientAuth)},
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("message\n"))
+	require.NoError(t, err)
+
+	select {
+	case e := <-fake.Received:
+		require.Equal(t, "message", e.Record)
+		require.Equal(t, "CN=web-01,O=stanza", e.Labels[helper.TLSClientSubjectLabel])
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for message to be written")
+	}
+
+	// Clients without a certificate are rejected
+	conn, err = tls.Dial("tcp", tcpInput.listener.Addr().String(), &tls.Config{
+		RootCAs:    serverCA.CertPool(),
+		ServerName: "localhost",
+	})
+	if err == nil {
+		defer conn.Close()
+		_, err = conn.Read(make([]byte, 1))
+	}
+	require.Error(t, err)
+}
+
 func BenchmarkTcpInput(b *testing.B) {
 	cfg := NewTCPInputConfig("test_id")
 	cfg.ListenAddress = ":0"
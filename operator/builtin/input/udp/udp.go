@@ -18,8 +18,11 @@ func init() {
 
 // NewUDPInputConfig creates a new UDP input config with default values
 func NewUDPInputConfig(operatorID string) *UDPInputConfig {
+	inputConfig := helper.NewInputConfig(operatorID, "udp_input")
+	// Pausing a udp input only moves the drops into the kernel, where they are not counted
+	inputConfig.Backpressure = helper.BackpressureDrop
 	return &UDPInputConfig{
-		InputConfig: helper.NewInputConfig(operatorID, "udp_input"),
+		InputConfig: inputConfig,
 	}
 }
 
@@ -66,6 +69,9 @@ type UDPInput struct {
 	connection net.PacketConn
 	cancel     context.CancelFunc
 	wg         sync.WaitGroup
+
+	// readStatus holds the most recent error reading from the connection, until a read succeeds
+	readStatus helper.HealthStatus
 }
 
 // Start will start listening for messages on a socket.
@@ -99,8 +105,10 @@ func (u *UDPInput) goHandleMessages(ctx context.Context) {
 				default:
 					u.Errorw("Failed reading messages", zap.Error(err))
 				}
+				u.readStatus.Set(err)
 				break
 			}
+			u.readStatus.Set(nil)
 
 			entry, err := u.NewEntry(message)
 			if err != nil {
@@ -142,6 +150,14 @@ func (u *UDPInput) readMessage() (string, net.Addr, error) {
 	return string(u.buffer[:n]), addr, nil
 }
 
+// Healthy returns an error if the input is failing to read from its connection
+func (u *UDPInput) Healthy() error {
+	if err := u.readStatus.Err(); err != nil {
+		return fmt.Errorf("failed to read from connection: %w", err)
+	}
+	return nil
+}
+
 // Stop will stop listening for udp messages.
 func (u *UDPInput) Stop() error {
 	u.cancel()
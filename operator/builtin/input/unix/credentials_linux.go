@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package unix
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// credentialsSize is the size of the control message holding the credentials of a datagram
+var credentialsSize = unix.CmsgSpace(unix.SizeofUcred)
+
+// credentials identify the process that sent a log
+type credentials struct {
+	pid int32
+	uid uint32
+	gid uint32
+}
+
+// peerCredentials returns the credentials of the process that opened a connection,
+// as recorded by the kernel when it connected (SO_PEERCRED)
+func peerCredentials(conn *net.UnixConn) (*credentials, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var ucred *unix.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if credErr != nil {
+		return nil, credErr
+	}
+	return &credentials{pid: ucred.Pid, uid: ucred.Uid, gid: ucred.Gid}, nil
+}
+
+// enableCredentials requests that the kernel attach the credentials of the sender
+// to each datagram received by the socket (SO_PASSCRED)
+func enableCredentials(conn *net.UnixConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var optErr error
+	err = rawConn.Control(func(fd uintptr) {
+		optErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PASSCRED, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return optErr
+}
+
+// parseCredentials returns the credentials attached to a datagram
+func parseCredentials(oob []byte) (*credentials, error) {
+	messages, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range messages {
+		if message.Header.Level != unix.SOL_SOCKET || message.Header.Type != unix.SCM_CREDENTIALS {
+			continue
+		}
+		ucred, err := unix.ParseUnixCredentials(&message)
+		if err != nil {
+			return nil, err
+		}
+		return &credentials{pid: ucred.Pid, uid: ucred.Uid, gid: ucred.Gid}, nil
+	}
+	return nil, fmt.Errorf("datagram has no credentials")
+}
@@ -0,0 +1,45 @@
+//go:build linux
+// +build linux
+
+package unix
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixInputPeerCredentials(t *testing.T) {
+	for _, socketType := range []string{StreamSocket, DatagramSocket} {
+		t.Run(socketType, func(t *testing.T) {
+			unixInput, fake := startTestUnixInput(t, func(cfg *UnixInputConfig) {
+				cfg.SocketType = socketType
+				cfg.AddLabels = true
+			})
+
+			conn, err := net.Dial(unixInput.network, unixInput.path)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("message1\n"))
+			require.NoError(t, err)
+
+			e := expectEntry(t, fake)
+			require.Equal(t, "message1", e.Record)
+			require.Equal(t, map[string]string{
+				"net.transport": "Unix",
+				PeerPIDLabel:    strconv.Itoa(os.Getpid()),
+				PeerUIDLabel:    strconv.Itoa(os.Getuid()),
+				PeerGIDLabel:    strconv.Itoa(os.Getgid()),
+			}, e.Labels)
+		})
+	}
+}
+
+func TestParseCredentialsMissing(t *testing.T) {
+	_, err := parseCredentials(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+// +build !linux
+
+package unix
+
+import (
+	"fmt"
+	"net"
+)
+
+// credentialsSize is zero, since datagrams only carry credentials on linux
+const credentialsSize = 0
+
+// credentials identify the process that sent a log
+type credentials struct {
+	pid int32
+	uid uint32
+	gid uint32
+}
+
+var errCredentialsUnsupported = fmt.Errorf("peer credentials are only supported on linux")
+
+func peerCredentials(*net.UnixConn) (*credentials, error) {
+	return nil, errCredentialsUnsupported
+}
+
+func enableCredentials(*net.UnixConn) error {
+	return errCredentialsUnsupported
+}
+
+func parseCredentials([]byte) (*credentials, error) {
+	return nil, errCredentialsUnsupported
+}
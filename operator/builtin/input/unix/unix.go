@@ -0,0 +1,385 @@
+package unix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+const (
+	// StreamSocket is a connection oriented socket, on which logs are separated by newlines
+	StreamSocket = "stream"
+	// DatagramSocket is a socket on which each datagram is a log
+	DatagramSocket = "datagram"
+
+	// DefaultMaxBufferSize is the maximum size of a log if max_buffer_size is not set
+	DefaultMaxBufferSize = 1024 * 1024
+
+	// minBufferSize is the initial size of the buffer used to read stream connections
+	minBufferSize = 64 * 1024
+
+	// PeerPIDLabel is the label set to the process id of the sender of a log
+	PeerPIDLabel = "unix.peer.pid"
+	// PeerUIDLabel is the label set to the user id of the sender of a log
+	PeerUIDLabel = "unix.peer.uid"
+	// PeerGIDLabel is the label set to the group id of the sender of a log
+	PeerGIDLabel = "unix.peer.gid"
+)
+
+func init() {
+	operator.Register("unix_input", func() operator.Builder { return NewUnixInputConfig("") })
+}
+
+// NewUnixInputConfig creates a new unix input config with default values
+func NewUnixInputConfig(operatorID string) *UnixInputConfig {
+	return &UnixInputConfig{
+		InputConfig: helper.NewInputConfig(operatorID, "unix_input"),
+		SocketType:  StreamSocket,
+		FileMode:    "0660",
+	}
+}
+
+// UnixInputConfig is the configuration of a unix input operator.
+type UnixInputConfig struct {
+	helper.InputConfig `yaml:",inline"`
+
+	Path          string          `json:"path,omitempty" yaml:"path,omitempty"`
+	SocketType    string          `json:"socket_type,omitempty" yaml:"socket_type,omitempty"`
+	FileMode      string          `json:"file_mode,omitempty" yaml:"file_mode,omitempty"`
+	MaxBufferSize helper.ByteSize `json:"max_buffer_size,omitempty" yaml:"max_buffer_size,omitempty"`
+	AddLabels     bool            `json:"add_labels,omitempty" yaml:"add_labels,omitempty"`
+}
+
+// Build will build a unix input operator.
+func (c UnixInputConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	inputOperator, err := c.InputConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Path == "" {
+		return nil, fmt.Errorf("missing required parameter 'path'")
+	}
+
+	var network string
+	switch c.SocketType {
+	case StreamSocket:
+		network = "unix"
+	case DatagramSocket:
+		network = "unixgram"
+	default:
+		return nil, fmt.Errorf("invalid socket_type '%s', must be one of '%s' or '%s'", c.SocketType, StreamSocket, DatagramSocket)
+	}
+
+	fileMode, err := strconv.ParseUint(c.FileMode, 8, 32)
+	if err != nil || fileMode > 0777 {
+		return nil, fmt.Errorf("invalid file_mode '%s', must be an octal permission such as '0660'", c.FileMode)
+	}
+
+	if c.MaxBufferSize == 0 {
+		c.MaxBufferSize = DefaultMaxBufferSize
+	}
+
+	if c.MaxBufferSize < minBufferSize {
+		return nil, fmt.Errorf("invalid value for parameter 'max_buffer_size', must be equal to or greater than %d bytes", minBufferSize)
+	}
+
+	unixInput := &UnixInput{
+		InputOperator: inputOperator,
+		path:          c.Path,
+		network:       network,
+		fileMode:      os.FileMode(fileMode),
+		maxBufferSize: int(c.MaxBufferSize),
+		addLabels:     c.AddLabels,
+		backoff: backoff.Backoff{
+			Min:    100 * time.Millisecond,
+			Max:    3 * time.Second,
+			Factor: 2,
+			Jitter: false,
+		},
+	}
+	return []operator.Operator{unixInput}, nil
+}
+
+// UnixInput is an operator that listens on a unix domain socket for log entries.
+type UnixInput struct {
+	helper.InputOperator
+	path          string
+	network       string
+	fileMode      os.FileMode
+	maxBufferSize int
+	addLabels     bool
+	backoff       backoff.Backoff
+
+	listener   *net.UnixListener
+	connection *net.UnixConn
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	// readStatus holds the most recent error accepting a connection, or reading a
+	// datagram, until one succeeds
+	readStatus helper.HealthStatus
+}
+
+// Start will start listening for log entries on the socket.
+func (u *UnixInput) Start() error {
+	if err := removeStaleSocket(u.network, u.path); err != nil {
+		return err
+	}
+
+	address := &net.UnixAddr{Name: u.path, Net: u.network}
+	if u.network == "unixgram" {
+		conn, err := net.ListenUnixgram(u.network, address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on socket: %w", err)
+		}
+		u.connection = conn
+	} else {
+		listener, err := net.ListenUnix(u.network, address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on socket: %w", err)
+		}
+		u.listener = listener
+	}
+
+	if err := os.Chmod(u.path, u.fileMode); err != nil {
+		u.close()
+		return fmt.Errorf("failed to set the permissions of the socket file: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cancel = cancel
+
+	if u.connection != nil {
+		if err := enableCredentials(u.connection); err != nil {
+			u.Warnw("Peer credentials will not be added to entries", zap.Error(err))
+		}
+		u.goHandleDatagrams(ctx)
+		return nil
+	}
+	u.goListen(ctx)
+	return nil
+}
+
+// removeStaleSocket removes a socket file left behind by a listener that is no
+// longer running. A socket that is still in use, or a file that is not a socket,
+// is not removed.
+func removeStaleSocket(network, path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check socket file: %w", err)
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("file %s already exists and is not a socket", path)
+	}
+
+	conn, err := net.Dial(network, path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by another listener", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("failed to check whether socket %s is in use: %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket file: %w", err)
+	}
+	return nil
+}
+
+// goListen will listen for connections to a stream socket.
+func (u *UnixInput) goListen(ctx context.Context) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+
+		for {
+			conn, err := u.listener.AcceptUnix()
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					u.Debugw("Listener accept error", zap.Error(err))
+					u.readStatus.Set(err)
+					time.Sleep(u.backoff.Duration())
+					continue
+				}
+			}
+			u.backoff.Reset()
+			u.readStatus.Set(nil)
+
+			subctx, cancel := context.WithCancel(ctx)
+			u.goHandleClose(subctx, conn)
+			u.goHandleMessages(subctx, conn, cancel)
+		}
+	}()
+}
+
+// goHandleClose will wait for the context to finish before closing a connection.
+func (u *UnixInput) goHandleClose(ctx context.Context, conn *net.UnixConn) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+		<-ctx.Done()
+		if err := conn.Close(); err != nil {
+			u.Errorf("Failed to close connection: %s", err)
+		}
+	}()
+}
+
+// goHandleMessages will handle messages from a connection to a stream socket.
+func (u *UnixInput) goHandleMessages(ctx context.Context, conn *net.UnixConn, cancel context.CancelFunc) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+		defer cancel()
+
+		// The credentials of a connection are those of the process that connected
+		var creds *credentials
+		if u.addLabels {
+			var err error
+			if creds, err = peerCredentials(conn); err != nil {
+				u.Debugw("Failed to read peer credentials", zap.Error(err))
+			}
+		}
+
+		buf := make([]byte, 0, minBufferSize)
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(buf, u.maxBufferSize)
+		for scanner.Scan() {
+			u.writeEntry(ctx, scanner.Text(), creds)
+		}
+		if err := scanner.Err(); err != nil {
+			// Use of closed network connection is expected if the context is canceled
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+			}
+			u.Errorw("Scanner error", zap.Error(err))
+		}
+	}()
+}
+
+// goHandleDatagrams will handle the datagrams received by a datagram socket.
+func (u *UnixInput) goHandleDatagrams(ctx context.Context) {
+	u.wg.Add(1)
+
+	go func() {
+		defer u.wg.Done()
+
+		buf := make([]byte, u.maxBufferSize)
+		oob := make([]byte, credentialsSize)
+		for {
+			n, oobn, _, _, err := u.connection.ReadMsgUnix(buf, oob)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+					u.Errorw("Failed reading messages", zap.Error(err))
+				}
+				u.readStatus.Set(err)
+				time.Sleep(u.backoff.Duration())
+				continue
+			}
+			u.backoff.Reset()
+			u.readStatus.Set(nil)
+
+			var creds *credentials
+			if u.addLabels {
+				if creds, err = parseCredentials(oob[:oobn]); err != nil {
+					u.Debugw("Failed to read peer credentials", zap.Error(err))
+				}
+			}
+
+			u.writeEntry(ctx, strings.TrimRight(string(buf[:n]), "\r\n\x00"), creds)
+		}
+	}()
+}
+
+// writeEntry will create an entry from a message, and write it to the outputs
+func (u *UnixInput) writeEntry(ctx context.Context, message string, creds *credentials) {
+	entry, err := u.NewEntry(message)
+	if err != nil {
+		u.Errorw("Failed to create entry", zap.Error(err))
+		return
+	}
+
+	if u.addLabels {
+		entry.AddLabel("net.transport", "Unix")
+		addCredentialLabels(entry, creds)
+	}
+
+	u.Write(ctx, entry)
+}
+
+func addCredentialLabels(e *entry.Entry, creds *credentials) {
+	if creds == nil {
+		return
+	}
+	e.AddLabel(PeerPIDLabel, strconv.FormatInt(int64(creds.pid), 10))
+	e.AddLabel(PeerUIDLabel, strconv.FormatUint(uint64(creds.uid), 10))
+	e.AddLabel(PeerGIDLabel, strconv.FormatUint(uint64(creds.gid), 10))
+}
+
+// Healthy returns an error if the input is failing to accept connections or read datagrams
+func (u *UnixInput) Healthy() error {
+	if err := u.readStatus.Err(); err != nil {
+		return fmt.Errorf("failed to read from socket: %w", err)
+	}
+	return nil
+}
+
+// Stop will stop listening for log entries, and remove the socket file.
+func (u *UnixInput) Stop() error {
+	if u.cancel != nil {
+		u.cancel()
+	}
+	u.close()
+	u.wg.Wait()
+	return nil
+}
+
+// close will close the socket and remove its file
+func (u *UnixInput) close() {
+	if u.listener != nil {
+		if err := u.listener.Close(); err != nil {
+			u.Errorf("Failed to close listener: %s", err)
+		}
+	}
+	if u.connection != nil {
+		if err := u.connection.Close(); err != nil {
+			u.Errorf("Failed to close connection: %s", err)
+		}
+		// Unlike listeners, datagram sockets do not remove their file when closed
+		if err := os.Remove(u.path); err != nil && !os.IsNotExist(err) {
+			u.Errorf("Failed to remove socket file: %s", err)
+		}
+	}
+}
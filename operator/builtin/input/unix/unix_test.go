@@ -0,0 +1,211 @@
+//go:build !windows
+// +build !windows
+
+package unix
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUnixInput(t *testing.T, modify func(*UnixInputConfig)) (*UnixInput, *testutil.FakeOutput) {
+	cfg := NewUnixInputConfig("test_input")
+	cfg.Path = filepath.Join(testutil.NewTempDir(t), "test.sock")
+	if modify != nil {
+		modify(cfg)
+	}
+
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	unixInput := ops[0].(*UnixInput)
+
+	fake := testutil.NewFakeOutput(t)
+	unixInput.OutputOperators = []operator.Operator{fake}
+	return unixInput, fake
+}
+
+func startTestUnixInput(t *testing.T, modify func(*UnixInputConfig)) (*UnixInput, *testutil.FakeOutput) {
+	unixInput, fake := newTestUnixInput(t, modify)
+	require.NoError(t, unixInput.Start())
+	t.Cleanup(func() { require.NoError(t, unixInput.Stop()) })
+	return unixInput, fake
+}
+
+func expectEntry(t *testing.T, fake *testutil.FakeOutput) *entry.Entry {
+	select {
+	case e := <-fake.Received:
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for entry")
+	}
+	return nil
+}
+
+// createStaleSocket creates a socket file that is not in use, as left behind by a
+// process that exited without removing it
+func createStaleSocket(t *testing.T, network, path string) {
+	address := &net.UnixAddr{Name: path, Net: network}
+	if network == "unixgram" {
+		conn, err := net.ListenUnixgram(network, address)
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	} else {
+		listener, err := net.ListenUnix(network, address)
+		require.NoError(t, err)
+		listener.SetUnlinkOnClose(false)
+		require.NoError(t, listener.Close())
+	}
+	require.FileExists(t, path)
+}
+
+func TestUnixInputBuild(t *testing.T) {
+	cases := []struct {
+		name     string
+		modify   func(*UnixInputConfig)
+		expected string
+	}{
+		{
+			"MissingPath",
+			func(cfg *UnixInputConfig) { cfg.Path = "" },
+			"missing required parameter 'path'",
+		},
+		{
+			"InvalidSocketType",
+			func(cfg *UnixInputConfig) { cfg.SocketType = "seqpacket" },
+			"invalid socket_type 'seqpacket'",
+		},
+		{
+			"InvalidFileMode",
+			func(cfg *UnixInputConfig) { cfg.FileMode = "rw-rw----" },
+			"invalid file_mode 'rw-rw----'",
+		},
+		{
+			"FileModeTooLarge",
+			func(cfg *UnixInputConfig) { cfg.FileMode = "1777" },
+			"invalid file_mode '1777'",
+		},
+		{
+			"MaxBufferSizeTooSmall",
+			func(cfg *UnixInputConfig) { cfg.MaxBufferSize = 1024 },
+			"invalid value for parameter 'max_buffer_size'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewUnixInputConfig("test_input")
+			cfg.Path = "/tmp/test.sock"
+			tc.modify(cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestUnixInputStream(t *testing.T) {
+	unixInput, fake := startTestUnixInput(t, nil)
+
+	conn, err := net.Dial("unix", unixInput.path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("message1\nmessage2\n"))
+	require.NoError(t, err)
+	fake.ExpectRecord(t, "message1")
+	fake.ExpectRecord(t, "message2")
+}
+
+func TestUnixInputDatagram(t *testing.T) {
+	unixInput, fake := startTestUnixInput(t, func(cfg *UnixInputConfig) {
+		cfg.SocketType = DatagramSocket
+	})
+
+	conn, err := net.Dial("unixgram", unixInput.path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("message1\n"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("message2\nwith two lines"))
+	require.NoError(t, err)
+	fake.ExpectRecord(t, "message1")
+	fake.ExpectRecord(t, "message2\nwith two lines")
+}
+
+func TestUnixInputFileMode(t *testing.T) {
+	for _, socketType := range []string{StreamSocket, DatagramSocket} {
+		t.Run(socketType, func(t *testing.T) {
+			unixInput, _ := startTestUnixInput(t, func(cfg *UnixInputConfig) {
+				cfg.SocketType = socketType
+				cfg.FileMode = "0606"
+			})
+
+			info, err := os.Stat(unixInput.path)
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0606), info.Mode().Perm())
+		})
+	}
+}
+
+func TestUnixInputSocketFile(t *testing.T) {
+	for _, socketType := range []string{StreamSocket, DatagramSocket} {
+		t.Run(socketType, func(t *testing.T) {
+			t.Run("RemovedOnStop", func(t *testing.T) {
+				unixInput, _ := newTestUnixInput(t, func(cfg *UnixInputConfig) { cfg.SocketType = socketType })
+				require.NoError(t, unixInput.Start())
+				require.FileExists(t, unixInput.path)
+				require.NoError(t, unixInput.Stop())
+				require.NoFileExists(t, unixInput.path)
+			})
+
+			t.Run("Stale", func(t *testing.T) {
+				unixInput, fake := newTestUnixInput(t, func(cfg *UnixInputConfig) { cfg.SocketType = socketType })
+
+				// A socket left behind by a process that exited is replaced
+				createStaleSocket(t, unixInput.network, unixInput.path)
+
+				require.NoError(t, unixInput.Start())
+				defer unixInput.Stop()
+
+				conn, err := net.Dial(unixInput.network, unixInput.path)
+				require.NoError(t, err)
+				defer conn.Close()
+				_, err = conn.Write([]byte("message1\n"))
+				require.NoError(t, err)
+				fake.ExpectRecord(t, "message1")
+			})
+
+			t.Run("InUse", func(t *testing.T) {
+				first, _ := startTestUnixInput(t, func(cfg *UnixInputConfig) { cfg.SocketType = socketType })
+				second, _ := newTestUnixInput(t, func(cfg *UnixInputConfig) {
+					cfg.SocketType = socketType
+					cfg.Path = first.path
+				})
+
+				err := second.Start()
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is in use by another listener")
+				require.FileExists(t, first.path)
+			})
+
+			t.Run("NotSocket", func(t *testing.T) {
+				unixInput, _ := newTestUnixInput(t, func(cfg *UnixInputConfig) { cfg.SocketType = socketType })
+				require.NoError(t, os.WriteFile(unixInput.path, []byte("data"), 0600))
+
+				err := unixInput.Start()
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "already exists and is not a socket")
+				require.FileExists(t, unixInput.path)
+			})
+		})
+	}
+}
@@ -49,7 +49,7 @@ type ElasticOutputConfig struct {
 
 // Build will build an elasticsearch output operator.
 func (c ElasticOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
-	outputOperator, err := c.OutputConfig.Build(bc)
+	outputOperator, err := c.OutputConfig.BuildWithDeadLetter(bc)
 	if err != nil {
 		return nil, err
 	}
@@ -71,22 +71,24 @@ func (c ElasticOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 		)
 	}
 
-	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	newBuffer, err := c.BufferConfig.Build(bc, c.ID())
 	if err != nil {
 		return nil, err
 	}
 
-	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+	newFlusher, err := c.FlusherConfig.Build(bc, outputOperator.ID())
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	elasticOutput := &ElasticOutput{
 		OutputOperator: outputOperator,
-		buffer:         buffer,
+		BufferedOutput: flusher.BufferedOutput{Buffer: newBuffer, Flusher: newFlusher},
 		client:         client,
 		indexField:     c.IndexField,
 		idField:        c.IDField,
-		flusher:        flusher,
 		ctx:            ctx,
 		cancel:         cancel,
 	}
@@ -97,8 +99,7 @@ func (c ElasticOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 // ElasticOutput is an operator that sends entries to elasticsearch.
 type ElasticOutput struct {
 	helper.OutputOperator
-	buffer  buffer.Buffer
-	flusher *flusher.Flusher
+	flusher.BufferedOutput
 
 	client     *elasticsearch.Client
 	indexField *entry.Field
@@ -116,6 +117,7 @@ func (e *ElasticOutput) Start() error {
 		defer e.wg.Done()
 		e.feedFlusher(e.ctx)
 	}()
+	e.Flusher.Start(e.Buffer)
 
 	return nil
 }
@@ -124,13 +126,13 @@ func (e *ElasticOutput) Start() error {
 func (e *ElasticOutput) Stop() error {
 	e.cancel()
 	e.wg.Wait()
-	e.flusher.Stop()
-	return e.buffer.Close()
+	e.Flusher.Stop()
+	return e.Buffer.Close()
 }
 
 // Process adds an entry to the outputs buffer
 func (e *ElasticOutput) Process(ctx context.Context, entry *entry.Entry) error {
-	return e.buffer.Add(ctx, entry)
+	return e.Buffer.Add(ctx, entry)
 }
 
 // ProcessMulti will send entries to elasticsearch.
@@ -188,7 +190,7 @@ func (e *ElasticOutput) createRequest(entries []*entry.Entry) *esapi.BulkRequest
 
 func (e *ElasticOutput) feedFlusher(ctx context.Context) {
 	for {
-		entries, clearer, err := e.buffer.ReadChunk(ctx)
+		entries, clearer, err := e.Buffer.ReadChunk(ctx)
 		if err != nil && err == context.Canceled {
 			return
 		} else if err != nil {
@@ -196,7 +198,7 @@ func (e *ElasticOutput) feedFlusher(ctx context.Context) {
 			continue
 		}
 
-		e.flusher.Do(func(ctx context.Context) error {
+		e.Flusher.Do(func(ctx context.Context) error {
 			req := e.createRequest(entries)
 			res, err := req.Do(ctx, e.client)
 			if err != nil {
@@ -208,19 +210,23 @@ func (e *ElasticOutput) feedFlusher(ctx context.Context) {
 			}
 
 			if res.IsError() {
-				return errors.NewError(
+				err := errors.NewError(
 					"Request to elasticsearch returned a failure code.",
 					"Review status and status code for further details.",
 					"status_code", strconv.Itoa(res.StatusCode),
 					"status", res.Status(),
 				)
+				if !flusher.IsRetryableStatus(res.StatusCode) {
+					return flusher.Permanent(err)
+				}
+				return err
 			}
 
 			if err = clearer.MarkAllAsFlushed(); err != nil {
 				e.Errorw("Failed to mark entries as flushed", zap.Error(err))
 			}
 			return nil
-		})
+		}, e.Flusher.NewDropFunc(e.ctx, &e.OutputOperator, entries, clearer))
 	}
 }
 
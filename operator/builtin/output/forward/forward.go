@@ -40,12 +40,12 @@ type ForwardOutputConfig struct {
 
 // Build will build an forward output operator.
 func (c ForwardOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
-	outputOperator, err := c.OutputConfig.Build(bc)
+	outputOperator, err := c.OutputConfig.BuildWithDeadLetter(bc)
 	if err != nil {
 		return nil, err
 	}
 
-	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	newBuffer, err := c.BufferConfig.Build(bc, c.ID())
 	if err != nil {
 		return nil, err
 	}
@@ -54,14 +54,16 @@ func (c ForwardOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 		return nil, errors.NewError("missing required parameter 'address'", "")
 	}
 
-	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+	newFlusher, err := c.FlusherConfig.Build(bc, outputOperator.ID())
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	forwardOutput := &ForwardOutput{
 		OutputOperator: outputOperator,
-		buffer:         buffer,
-		flusher:        flusher,
+		BufferedOutput: flusher.BufferedOutput{Buffer: newBuffer, Flusher: newFlusher},
 		ctx:            ctx,
 		cancel:         cancel,
 		client:         &http.Client{},
@@ -74,8 +76,7 @@ func (c ForwardOutputConfig) Build(bc operator.BuildContext) ([]operator.Operato
 // ForwardOutput is an operator that sends entries to another stanza instance
 type ForwardOutput struct {
 	helper.OutputOperator
-	buffer  buffer.Buffer
-	flusher *flusher.Flusher
+	flusher.BufferedOutput
 
 	client  *http.Client
 	address string
@@ -92,6 +93,7 @@ func (f *ForwardOutput) Start() error {
 		defer f.wg.Done()
 		f.feedFlusher(f.ctx)
 	}()
+	f.Flusher.Start(f.Buffer)
 
 	return nil
 }
@@ -100,13 +102,13 @@ func (f *ForwardOutput) Start() error {
 func (f *ForwardOutput) Stop() error {
 	f.cancel()
 	f.wg.Wait()
-	f.flusher.Stop()
-	return f.buffer.Close()
+	f.Flusher.Stop()
+	return f.Buffer.Close()
 }
 
 // Process adds an entry to the outputs buffer
 func (f *ForwardOutput) Process(ctx context.Context, entry *entry.Entry) error {
-	return f.buffer.Add(ctx, entry)
+	return f.Buffer.Add(ctx, entry)
 }
 
 // ProcessMulti will send entries to elasticsearch.
@@ -123,7 +125,7 @@ func (f *ForwardOutput) createRequest(ctx context.Context, entries []*entry.Entr
 
 func (f *ForwardOutput) feedFlusher(ctx context.Context) {
 	for {
-		entries, clearer, err := f.buffer.ReadChunk(ctx)
+		entries, clearer, err := f.Buffer.ReadChunk(ctx)
 		if err != nil && err == context.Canceled {
 			return
 		} else if err != nil {
@@ -131,7 +133,7 @@ func (f *ForwardOutput) feedFlusher(ctx context.Context) {
 			continue
 		}
 
-		f.flusher.Do(func(ctx context.Context) error {
+		f.Flusher.Do(func(ctx context.Context) error {
 			req, err := f.createRequest(ctx, entries)
 			if err != nil {
 				f.Errorf("Failed to create request", zap.Error(err))
@@ -155,7 +157,7 @@ func (f *ForwardOutput) feedFlusher(ctx context.Context) {
 				f.Errorw("Failed to mark entries as flushed", zap.Error(err))
 			}
 			return nil
-		})
+		}, f.Flusher.NewDropFunc(f.ctx, &f.OutputOperator, entries, clearer))
 	}
 }
 
@@ -167,12 +169,18 @@ func (f *ForwardOutput) handleResponse(res *http.Response) error {
 	}()
 
 	if !(res.StatusCode >= 200 && res.StatusCode < 300) {
-		body, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return errors.NewError("unexpected status code", "", "status", res.Status)
+		var err error
+		body, readErr := ioutil.ReadAll(res.Body)
+		if readErr != nil {
+			err = errors.NewError("unexpected status code", "", "status", res.Status)
+		} else {
+			err = errors.NewError("unexpected status code", "", "status", res.Status, "body", string(body))
 		}
 
-		return errors.NewError("unexpected status code", "", "status", res.Status, "body", string(body))
+		if !flusher.IsRetryableStatus(res.StatusCode) {
+			return flusher.Permanent(err)
+		}
+		return err
 	}
 	return nil
 }
@@ -67,7 +67,7 @@ type GoogleCloudOutputConfig struct {
 
 // Build will build a google cloud output operator.
 func (c GoogleCloudOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
-	outputOperator, err := c.OutputConfig.Build(bc)
+	outputOperator, err := c.OutputConfig.BuildWithDeadLetter(bc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build output operator: %w", err)
 	}
@@ -92,7 +92,10 @@ func (c GoogleCloudOutputConfig) Build(bc operator.BuildContext) ([]operator.Ope
 		return nil, errors.New("failed to get project id from config or credentials")
 	}
 
-	newFlusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+	newFlusher, err := c.FlusherConfig.Build(bc, outputOperator.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build flusher: %w", err)
+	}
 	clientOptions := c.createClientOptions(credentials, c.UseCompression)
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -114,8 +117,7 @@ func (c GoogleCloudOutputConfig) Build(bc operator.BuildContext) ([]operator.Ope
 
 	googleCloudOutput := &GoogleCloudOutput{
 		OutputOperator: outputOperator,
-		buffer:         newBuffer,
-		flusher:        newFlusher,
+		BufferedOutput: flusher.BufferedOutput{Buffer: newBuffer, Flusher: newFlusher},
 		timeout:        c.Timeout.Raw(),
 		requestBuilder: requestBuilder,
 		clientOptions:  clientOptions,
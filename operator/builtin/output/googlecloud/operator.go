@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/observiq/stanza/entry"
-	"github.com/observiq/stanza/operator/buffer"
 	"github.com/observiq/stanza/operator/flusher"
 	"github.com/observiq/stanza/operator/helper"
 
@@ -16,14 +15,15 @@ import (
 	"google.golang.org/api/option"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 	"google.golang.org/genproto/googleapis/logging/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
 // GoogleCloudOutput is an operator that sends logs to google cloud logging.
 type GoogleCloudOutput struct {
 	helper.OutputOperator
-	buffer  buffer.Buffer
-	flusher *flusher.Flusher
+	flusher.BufferedOutput
 
 	client         Client
 	clientOptions  []option.ClientOption
@@ -56,6 +56,7 @@ func (g *GoogleCloudOutput) Start() error {
 	g.Debug("Completed test connection")
 
 	g.startFlusher()
+	g.Flusher.Start(g.Buffer)
 	g.Debug("Started flusher")
 
 	return nil
@@ -69,10 +70,10 @@ func (g *GoogleCloudOutput) Stop() error {
 	g.wg.Wait()
 	g.Debug("Wait group completed")
 
-	g.flusher.Stop()
+	g.Flusher.Stop()
 	g.Debug("Stopped flusher")
 
-	if err := g.buffer.Close(); err != nil {
+	if err := g.Buffer.Close(); err != nil {
 		return fmt.Errorf("failed to close buffer: %w", err)
 	}
 	g.Debug("Closed buffer")
@@ -92,7 +93,7 @@ func (g *GoogleCloudOutput) Stop() error {
 
 // Process adds an incoming entry to the buffer
 func (g *GoogleCloudOutput) Process(ctx context.Context, e *entry.Entry) error {
-	return g.buffer.Add(ctx, e)
+	return g.Buffer.Add(ctx, e)
 }
 
 // testConnection will attempt to send an entry to google cloud logging
@@ -152,7 +153,7 @@ func (g *GoogleCloudOutput) startFlusher() {
 
 // flushChunk flushes a chunk of entries from the buffer
 func (g *GoogleCloudOutput) flushChunk(ctx context.Context) error {
-	entries, clearer, err := g.buffer.ReadChunk(ctx)
+	entries, clearer, err := g.Buffer.ReadChunk(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to read entries from buffer: %w", err)
 	}
@@ -174,7 +175,7 @@ func (g *GoogleCloudOutput) flushChunk(ctx context.Context) error {
 		return clearer.MarkAllAsFlushed()
 	}
 
-	g.flusher.Do(flushFunc)
+	g.Flusher.Do(flushFunc, g.Flusher.NewDropFunc(g.ctx, &g.OutputOperator, entries, clearer))
 	g.Debugw("Submitted requests to the flusher", "requests", len(requests))
 
 	return nil
@@ -186,9 +187,22 @@ func (g *GoogleCloudOutput) send(ctx context.Context, requests []*logging.WriteL
 		g.Debugw("Sending write request", "total_entries", len(request.Entries), "request_size", proto.Size(request))
 		_, err := g.client.WriteLogEntries(ctx, request)
 		if err != nil {
+			if isPermanentCode(status.Code(err)) {
+				return flusher.Permanent(fmt.Errorf("failed to send write request: %w", err))
+			}
 			return fmt.Errorf("failed to send write request: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// isPermanentCode returns true if a write request that failed with the code will not succeed when retried
+func isPermanentCode(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.NotFound, codes.PermissionDenied, codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented:
+		return true
+	default:
+		return false
+	}
+}
@@ -102,7 +102,7 @@ func TestBufferFailure(t *testing.T) {
 	client.On("Close").Return(nil)
 
 	operator := createTestOperator(t)
-	operator.buffer = buffer
+	operator.Buffer = buffer
 	operator.buildClient = func(ctx context.Context, opts ...option.ClientOption) (Client, error) {
 		return client, nil
 	}
@@ -142,7 +142,7 @@ func TestStopBufferFailure(t *testing.T) {
 	buffer.On("Close").Return(errors.New("failure"))
 
 	operator := createTestOperator(t)
-	operator.buffer = buffer
+	operator.Buffer = buffer
 
 	err := operator.Stop()
 	require.Error(t, err)
@@ -52,7 +52,7 @@ type NewRelicOutputConfig struct {
 
 // Build will build a new NewRelicOutput
 func (c NewRelicOutputConfig) Build(bc operator.BuildContext) ([]operator.Operator, error) {
-	outputOperator, err := c.OutputConfig.Build(bc)
+	outputOperator, err := c.OutputConfig.BuildWithDeadLetter(bc)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +62,7 @@ func (c NewRelicOutputConfig) Build(bc operator.BuildContext) ([]operator.Operat
 		return nil, err
 	}
 
-	buffer, err := c.BufferConfig.Build(bc, c.ID())
+	newBuffer, err := c.BufferConfig.Build(bc, c.ID())
 	if err != nil {
 		return nil, err
 	}
@@ -72,13 +72,15 @@ func (c NewRelicOutputConfig) Build(bc operator.BuildContext) ([]operator.Operat
 		return nil, errors.Wrap(err, "'base_uri' is not a valid URL")
 	}
 
-	flusher := c.FlusherConfig.Build(bc.Logger.SugaredLogger)
+	newFlusher, err := c.FlusherConfig.Build(bc, outputOperator.ID())
+	if err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
 	nro := &NewRelicOutput{
 		OutputOperator: outputOperator,
-		buffer:         buffer,
-		flusher:        flusher,
+		BufferedOutput: flusher.BufferedOutput{Buffer: newBuffer, Flusher: newFlusher},
 		client:         &http.Client{},
 		headers:        headers,
 		url:            url,
@@ -111,8 +113,7 @@ func (c NewRelicOutputConfig) getHeaders() (http.Header, error) {
 // NewRelicOutput is an operator that sends entries to the New Relic Logs platform
 type NewRelicOutput struct {
 	helper.OutputOperator
-	buffer  buffer.Buffer
-	flusher *flusher.Flusher
+	flusher.BufferedOutput
 
 	client       *http.Client
 	url          *url.URL
@@ -136,6 +137,7 @@ func (nro *NewRelicOutput) Start() error {
 		defer nro.wg.Done()
 		nro.feedFlusher(nro.ctx)
 	}()
+	nro.Flusher.Start(nro.Buffer)
 
 	return nil
 }
@@ -144,13 +146,13 @@ func (nro *NewRelicOutput) Start() error {
 func (nro *NewRelicOutput) Stop() error {
 	nro.cancel()
 	nro.wg.Wait()
-	nro.flusher.Stop()
-	return nro.buffer.Close()
+	nro.Flusher.Stop()
+	return nro.Buffer.Close()
 }
 
 // Process adds an entry to the output's buffer
 func (nro *NewRelicOutput) Process(ctx context.Context, entry *entry.Entry) error {
-	return nro.buffer.Add(ctx, entry)
+	return nro.Buffer.Add(ctx, entry)
 }
 
 func (nro *NewRelicOutput) testConnection() error {
@@ -172,7 +174,7 @@ func (nro *NewRelicOutput) testConnection() error {
 
 func (nro *NewRelicOutput) feedFlusher(ctx context.Context) {
 	for {
-		entries, clearer, err := nro.buffer.ReadChunk(ctx)
+		entries, clearer, err := nro.Buffer.ReadChunk(ctx)
 		if err != nil && err == context.Canceled {
 			return
 		} else if err != nil {
@@ -180,7 +182,7 @@ func (nro *NewRelicOutput) feedFlusher(ctx context.Context) {
 			continue
 		}
 
-		nro.flusher.Do(func(ctx context.Context) error {
+		nro.Flusher.Do(func(ctx context.Context) error {
 			req, err := nro.newRequest(ctx, entries)
 			if err != nil {
 				nro.Errorw("Failed to create request from payload", zap.Error(err))
@@ -204,7 +206,7 @@ func (nro *NewRelicOutput) feedFlusher(ctx context.Context) {
 				nro.Errorw("Failed to mark entries as flushed", zap.Error(err))
 			}
 			return nil
-		})
+		}, nro.Flusher.NewDropFunc(nro.ctx, &nro.OutputOperator, entries, clearer))
 	}
 }
 
@@ -239,11 +241,18 @@ func (nro *NewRelicOutput) handleResponse(res *http.Response) error {
 	}()
 
 	if !(res.StatusCode >= 200 && res.StatusCode < 300) {
-		body, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return errors.NewError("unexpected status code", "", "status", res.Status)
+		var err error
+		body, readErr := ioutil.ReadAll(res.Body)
+		if readErr != nil {
+			err = errors.NewError("unexpected status code", "", "status", res.Status)
+		} else {
+			err = errors.NewError("unexpected status code", "", "status", res.Status, "body", string(body))
 		}
-		return errors.NewError("unexpected status code", "", "status", res.Status, "body", string(body))
+
+		if !flusher.IsRetryableStatus(res.StatusCode) {
+			return flusher.Permanent(err)
+		}
+		return err
 	}
 	return nil
 }
@@ -93,6 +93,15 @@ func (s *SyslogParser) Process(ctx context.Context, entry *entry.Entry) error {
 	return s.ParserOperator.ProcessWithCallback(ctx, entry, s.parse, promoteSeverity)
 }
 
+// ParseEntry will parse an entry field as syslog in place, without writing the
+// entry to the parser's outputs.
+func (s *SyslogParser) ParseEntry(ctx context.Context, entry *entry.Entry) error {
+	if err := s.ParserOperator.ParseWith(ctx, entry, s.parse); err != nil {
+		return err
+	}
+	return promoteSeverity(entry)
+}
+
 // parse will parse a value as syslog.
 func (s *SyslogParser) parse(value interface{}) (interface{}, error) {
 	b, err := toBytes(value)
@@ -102,7 +111,12 @@ func (s *SyslogParser) parse(value interface{}) (interface{}, error) {
 
 	b = handleSymbols(b)
 
-	machine, err := buildMachine(s.protocol, s.location)
+	protocol := s.protocol
+	if protocol == "auto" {
+		protocol = detectProtocol(b)
+	}
+
+	machine, err := buildMachine(protocol, s.location)
 	if err != nil {
 		return nil, err
 	}
@@ -205,6 +219,25 @@ func toBytes(value interface{}) ([]byte, error) {
 	}
 }
 
+// detectProtocol returns rfc5424 if a message has a version following its priority,
+// such as "<34>1 ", and rfc3164 otherwise.
+func detectProtocol(b []byte) string {
+	end := bytes.IndexByte(b, '>')
+	if len(b) == 0 || b[0] != '<' || end < 0 {
+		return "rfc3164"
+	}
+
+	version := b[end+1:]
+	digits := 0
+	for digits < len(version) && digits < 3 && version[digits] >= '0' && version[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && version[0] != '0' && digits < len(version) && version[digits] == ' ' {
+		return "rfc5424"
+	}
+	return "rfc3164"
+}
+
 // handleSymbols escapes characters that appear to be symbol characters, but are not.
 // If not escaped properly, go-syslog will fail to parse the entry. Quotes that are
 // escaped will not be affected.
@@ -127,6 +127,48 @@ func TestSyslogParser(t *testing.T) {
 			entry.Critical,
 			"crit",
 		},
+		{
+			"AutoRFC3164",
+			func() *SyslogParserConfig {
+				cfg := basicConfig()
+				cfg.Protocol = "auto"
+				cfg.Location = location["utc"].String()
+				return cfg
+			}(),
+			"<34>Jan 12 06:30:00 1.2.3.4 apache_server: test message",
+			time.Date(time.Now().Year(), 1, 12, 6, 30, 0, 0, location["utc"]),
+			map[string]interface{}{
+				"appname":  "apache_server",
+				"facility": 4,
+				"hostname": "1.2.3.4",
+				"message":  "test message",
+				"priority": 34,
+			},
+			entry.Critical,
+			"crit",
+		},
+		{
+			"AutoRFC5424",
+			func() *SyslogParserConfig {
+				cfg := basicConfig()
+				cfg.Protocol = "auto"
+				return cfg
+			}(),
+			`<86>1 2015-08-05T21:58:59.693Z 192.168.2.132 SecureAuth0 23108 ID52020 - my message`,
+			time.Date(2015, 8, 5, 21, 58, 59, 693000000, time.UTC),
+			map[string]interface{}{
+				"appname":  "SecureAuth0",
+				"facility": 10,
+				"hostname": "192.168.2.132",
+				"message":  "my message",
+				"msg_id":   "ID52020",
+				"priority": 86,
+				"proc_id":  "23108",
+				"version":  1,
+			},
+			entry.Info,
+			"info",
+		},
 		{
 			"RFC3164Detroit",
 			func() *SyslogParserConfig {
@@ -382,6 +424,27 @@ func TestSyslogParser(t *testing.T) {
 	}
 }
 
+func TestDetectProtocol(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"<86>1 2015-08-05T21:58:59.693Z host app - - - message", "rfc5424"},
+		{"<86>12 2015-08-05T21:58:59.693Z host app - - - message", "rfc5424"},
+		{"<34>Jan 12 06:30:00 1.2.3.4 apache_server: test message", "rfc3164"},
+		{"<34>2015-08-05T21:58:59Z host app: message", "rfc3164"},
+		{"<34>0 host app: message", "rfc3164"},
+		{"Jan 12 06:30:00 1.2.3.4 apache_server: test message", "rfc3164"},
+		{"<34", "rfc3164"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, detectProtocol([]byte(tc.input)))
+		})
+	}
+}
+
 func TestHandleSymbols(t *testing.T) {
 	cases := []struct {
 		name           string
@@ -0,0 +1,408 @@
+package aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+)
+
+func init() {
+	operator.Register("aggregate", func() operator.Builder { return NewAggregateConfig("") })
+}
+
+var (
+	timeNow  = time.Now  // allow override for testing
+	randIntn = rand.Intn // allow override for testing
+)
+
+// NewAggregateConfig creates a new aggregate config with default values
+func NewAggregateConfig(operatorID string) *AggregateConfig {
+	return &AggregateConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "aggregate"),
+		Window:            helper.Duration{Duration: time.Minute},
+		Percentiles:       []float64{50, 90, 99},
+		MaxGroups:         10000,
+		MaxSamples:        10000,
+	}
+}
+
+// AggregateConfig is the configuration of an aggregate operator
+type AggregateConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	GroupBy     []entry.Field   `json:"group_by,omitempty"     yaml:"group_by,omitempty"`
+	Fields      []entry.Field   `json:"fields,omitempty"       yaml:"fields,omitempty"`
+	Window      helper.Duration `json:"window,omitempty"       yaml:"window,omitempty"`
+	Percentiles []float64       `json:"percentiles,omitempty"  yaml:"percentiles,omitempty"`
+	PassThrough bool            `json:"pass_through,omitempty" yaml:"pass_through,omitempty"`
+	MaxGroups   int             `json:"max_groups,omitempty"   yaml:"max_groups,omitempty"`
+	MaxSamples  int             `json:"max_samples,omitempty"  yaml:"max_samples,omitempty"`
+}
+
+// Build will build an aggregate operator
+func (c AggregateConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Window.Raw() <= 0:
+		return nil, fmt.Errorf("window must be greater than zero")
+	case c.MaxGroups <= 0:
+		return nil, fmt.Errorf("max_groups must be greater than zero")
+	case c.MaxSamples <= 0:
+		return nil, fmt.Errorf("max_samples must be greater than zero")
+	}
+
+	for _, percentile := range c.Percentiles {
+		if percentile <= 0 || percentile > 100 {
+			return nil, fmt.Errorf("percentiles must be greater than 0 and at most 100, got %g", percentile)
+		}
+	}
+
+	names := make([]string, 0, len(c.Fields))
+	seen := make(map[string]bool)
+	for _, field := range c.Fields {
+		name := fieldName(field)
+		if seen[name] || name == countKey || name == windowStartKey || name == windowEndKey {
+			return nil, fmt.Errorf("field '%s' has the same name as another value in the summary", field)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	aggregateOperator := &AggregateOperator{
+		TransformerOperator: transformerOperator,
+		groupBy:             c.GroupBy,
+		fields:              c.Fields,
+		fieldNames:          names,
+		window:              c.Window.Raw(),
+		percentiles:         c.Percentiles,
+		passThrough:         c.PassThrough,
+		maxGroups:           c.MaxGroups,
+		maxSamples:          c.MaxSamples,
+	}
+
+	return []operator.Operator{aggregateOperator}, nil
+}
+
+const (
+	countKey       = "count"
+	windowStartKey = "window_start"
+	windowEndKey   = "window_end"
+)
+
+// fieldName returns the name a field is summarized under, which is its last key
+func fieldName(field entry.Field) string {
+	if recordField, ok := field.FieldInterface.(entry.RecordField); ok && len(recordField.Keys) > 0 {
+		return recordField.Keys[len(recordField.Keys)-1]
+	}
+	// Label and resource keys are only exposed through the field's string form
+	s := field.String()
+	if i := strings.Index(s, "['"); i >= 0 && strings.HasSuffix(s, "']") {
+		return s[i+2 : len(s)-2]
+	}
+	return s[strings.LastIndex(s, ".")+1:]
+}
+
+// AggregateOperator is an operator that summarizes groups of entries over a window
+type AggregateOperator struct {
+	overflowed int64
+
+	helper.TransformerOperator
+	groupBy     []entry.Field
+	fields      []entry.Field
+	fieldNames  []string
+	window      time.Duration
+	percentiles []float64
+	passThrough bool
+	maxGroups   int
+	maxSamples  int
+
+	mux         sync.Mutex
+	windowStart time.Time
+	groups      map[string]*group
+	order       []string
+
+	// windowOverflowed is whether max_groups was reached in the current window
+	windowOverflowed bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// group is the summary of the entries with the same values of the group_by fields
+type group struct {
+	values []interface{}
+	count  int
+	stats  []*stats
+}
+
+// Start will start the aggregate operator, which writes the summaries of each
+// window as it ends
+func (a *AggregateOperator) Start() error {
+	a.mux.Lock()
+	a.resetWindow(timeNow())
+	a.mux.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.wg.Add(1)
+	go a.flushWindows(ctx)
+	return nil
+}
+
+// Stop will stop the aggregate operator, and write the summaries of the current window
+func (a *AggregateOperator) Stop() error {
+	if a.cancel != nil {
+		a.cancel()
+		a.wg.Wait()
+	}
+	a.Drain(context.Background())
+	return nil
+}
+
+// Drain writes the summaries of the current window, so that they are not lost
+// when the pipeline shuts down
+func (a *AggregateOperator) Drain(ctx context.Context) int {
+	a.flush(ctx, timeNow())
+	return 0
+}
+
+// Process will add the entry to the summary of its group
+func (a *AggregateOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	skip, err := a.Skip(ctx, entry)
+	if err != nil {
+		return a.HandleEntryError(ctx, entry, err)
+	}
+	if skip {
+		a.Write(ctx, entry)
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(a.groupBy))
+	for _, field := range a.groupBy {
+		value, _ := entry.Get(field)
+		values = append(values, value)
+	}
+	key, err := json.Marshal(values)
+	if err != nil {
+		return a.HandleEntryError(ctx, entry, fmt.Errorf("group entry: %s", err))
+	}
+
+	a.mux.Lock()
+	g, ok := a.groups[string(key)]
+	switch {
+	case ok:
+	case len(a.groups) >= a.maxGroups:
+		atomic.AddInt64(&a.overflowed, 1)
+		if !a.windowOverflowed {
+			a.Warnw("Too many groups in window, entries of new groups are written without being aggregated", "max_groups", a.maxGroups)
+		}
+		a.windowOverflowed = true
+	default:
+		g = &group{values: values, stats: make([]*stats, len(a.fields))}
+		for i := range g.stats {
+			g.stats[i] = &stats{}
+		}
+		a.groups[string(key)] = g
+		a.order = append(a.order, string(key))
+	}
+
+	if g != nil {
+		g.count++
+		for i, field := range a.fields {
+			if value, ok := entry.Get(field); ok {
+				if number, ok := toFloat(value); ok {
+					g.stats[i].add(number, a.maxSamples)
+				}
+			}
+		}
+	}
+	a.mux.Unlock()
+
+	// An entry that is not aggregated is written as it is, so that it is not lost
+	if a.passThrough || g == nil {
+		a.Write(ctx, entry)
+	}
+	return nil
+}
+
+// flushWindows writes the summaries of each window as it ends, until the context is done
+func (a *AggregateOperator) flushWindows(ctx context.Context) {
+	defer a.wg.Done()
+
+	for {
+		a.mux.Lock()
+		end := a.windowStart.Add(a.window)
+		a.mux.Unlock()
+
+		timer := time.NewTimer(end.Sub(timeNow()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		a.flush(ctx, end)
+	}
+}
+
+// flush writes the summary of each group in the current window, and starts a new
+// window at the supplied time
+func (a *AggregateOperator) flush(ctx context.Context, end time.Time) {
+	a.mux.Lock()
+	start, groups, order := a.windowStart, a.groups, a.order
+	a.resetWindow(end)
+	a.mux.Unlock()
+
+	for _, key := range order {
+		summary, err := a.summarize(groups[key], start, end)
+		if err != nil {
+			a.Errorw("Failed to create summary", "error", err)
+			continue
+		}
+		a.Write(ctx, summary)
+	}
+}
+
+// resetWindow starts a new window, aligned to a multiple of the window size
+func (a *AggregateOperator) resetWindow(now time.Time) {
+	a.windowStart = now.Truncate(a.window)
+	a.groups = make(map[string]*group)
+	a.order = make([]string, 0)
+	a.windowOverflowed = false
+}
+
+// summarize creates the summary entry of a group. The values of the group_by fields
+// are set on the summary at the same fields.
+func (a *AggregateOperator) summarize(g *group, start, end time.Time) (*entry.Entry, error) {
+	summary := entry.New()
+	summary.Timestamp = end
+	summary.Record = map[string]interface{}{
+		countKey:       g.count,
+		windowStartKey: start.Format(time.RFC3339Nano),
+		windowEndKey:   end.Format(time.RFC3339Nano),
+	}
+
+	for i, field := range a.groupBy {
+		if g.values[i] == nil {
+			continue
+		}
+		if err := summary.Set(field, g.values[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	record := summary.Record.(map[string]interface{})
+	for i, name := range a.fieldNames {
+		if g.stats[i].count > 0 {
+			record[name] = g.stats[i].summary(a.percentiles)
+		}
+	}
+	return summary, nil
+}
+
+// Inspect reports the number of groups in the current window, and the number of
+// entries that were not aggregated because there were too many groups
+func (a *AggregateOperator) Inspect() map[string]interface{} {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return map[string]interface{}{
+		"groups":             len(a.groups),
+		"overflowed_entries": atomic.LoadInt64(&a.overflowed),
+	}
+}
+
+// stats summarizes the values of a numeric field
+type stats struct {
+	count    int
+	sum      float64
+	min, max float64
+	samples  []float64
+}
+
+// add adds a value to the summary. Once maxSamples values are held, values replace
+// held samples at random, so that percentiles are estimated from a uniform sample.
+func (s *stats) add(value float64, maxSamples int) {
+	s.count++
+	s.sum += value
+	if s.count == 1 || value < s.min {
+		s.min = value
+	}
+	if s.count == 1 || value > s.max {
+		s.max = value
+	}
+
+	if len(s.samples) < maxSamples {
+		s.samples = append(s.samples, value)
+		return
+	}
+	if i := randIntn(s.count); i < maxSamples {
+		s.samples[i] = value
+	}
+}
+
+// summary returns the summary of the values
+func (s *stats) summary(percentiles []float64) map[string]interface{} {
+	summary := map[string]interface{}{
+		"count": s.count,
+		"sum":   s.sum,
+		"min":   s.min,
+		"max":   s.max,
+		"avg":   s.sum / float64(s.count),
+	}
+
+	sort.Float64s(s.samples)
+	for _, percentile := range percentiles {
+		rank := int(math.Ceil(percentile/100*float64(len(s.samples)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		summary["p"+strconv.FormatFloat(percentile, 'f', -1, 64)] = s.samples[rank]
+	}
+	return summary
+}
+
+// toFloat converts a numeric value, or a string containing a number, to a float
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
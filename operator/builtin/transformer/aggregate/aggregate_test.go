@@ -0,0 +1,181 @@
+package aggregate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateSummary(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+
+	cfg := NewAggregateConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.GroupBy = []entry.Field{entry.NewLabelField("service")}
+	cfg.Fields = []entry.Field{entry.NewRecordField("duration_ms")}
+	cfg.Window = helper.Duration{Duration: time.Hour}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	aggregate := ops[0].(*AggregateOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, aggregate.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, aggregate.Start())
+
+	process := func(service string, duration interface{}) {
+		e := entry.New()
+		e.Record = map[string]interface{}{"duration_ms": duration}
+		e.AddLabel("service", service)
+		require.NoError(t, aggregate.Process(context.Background(), e))
+	}
+
+	for i := 1; i <= 100; i++ {
+		process("api", i)
+	}
+	process("web", "2.5")
+	process("web", "slow")
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+	require.Equal(t, 2, aggregate.Inspect()["groups"])
+
+	require.NoError(t, aggregate.Stop())
+	summaries := fake.ReceivedEntries()
+	require.Len(t, summaries, 2)
+
+	api := summaries[0]
+	require.Equal(t, "api", api.Labels["service"])
+	require.Equal(t, clock.Now(), api.Timestamp)
+	require.Equal(t, map[string]interface{}{
+		"count":        100,
+		"window_start": "1970-01-01T00:00:00Z",
+		"window_end":   "1970-01-01T00:16:40Z",
+		"duration_ms": map[string]interface{}{
+			"count": 100,
+			"sum":   5050.0,
+			"min":   1.0,
+			"max":   100.0,
+			"avg":   50.5,
+			"p50":   50.0,
+			"p90":   90.0,
+			"p99":   99.0,
+		},
+	}, api.Record)
+
+	// Values that are not numbers are counted, but not summarized
+	web := summaries[1]
+	require.Equal(t, "web", web.Labels["service"])
+	record := web.Record.(map[string]interface{})
+	require.Equal(t, 2, record["count"])
+	require.Equal(t, 1, record["duration_ms"].(map[string]interface{})["count"])
+	require.Equal(t, 2.5, record["duration_ms"].(map[string]interface{})["p99"])
+}
+
+func TestAggregateWindow(t *testing.T) {
+	cfg := NewAggregateConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Window = helper.Duration{Duration: 50 * time.Millisecond}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	aggregate := ops[0].(*AggregateOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, aggregate.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, aggregate.Start())
+	defer aggregate.Stop()
+
+	require.NoError(t, aggregate.Process(context.Background(), entry.New()))
+	var summary *entry.Entry
+	select {
+	case summary = <-fake.Received:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Timed out waiting for summary")
+	}
+
+	record := summary.Record.(map[string]interface{})
+	start, err := time.Parse(time.RFC3339Nano, record["window_start"].(string))
+	require.NoError(t, err)
+	end, err := time.Parse(time.RFC3339Nano, record["window_end"].(string))
+	require.NoError(t, err)
+	require.Equal(t, 50*time.Millisecond, end.Sub(start))
+	require.Equal(t, 0, aggregate.Inspect()["groups"])
+}
+
+func TestAggregatePassThrough(t *testing.T) {
+	cfg := NewAggregateConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.PassThrough = true
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	aggregate := ops[0].(*AggregateOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, aggregate.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, aggregate.Start())
+
+	original := entry.New()
+	original.Record = "request"
+	require.NoError(t, aggregate.Process(context.Background(), original))
+	fake.ExpectEntry(t, original)
+
+	require.Equal(t, 0, aggregate.Drain(context.Background()))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, 1, written[0].Record.(map[string]interface{})["count"])
+	require.NoError(t, aggregate.Stop())
+}
+
+func TestAggregateMaxGroups(t *testing.T) {
+	cfg := NewAggregateConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.GroupBy = []entry.Field{entry.NewLabelField("service")}
+	cfg.MaxGroups = 1
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	aggregate := ops[0].(*AggregateOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, aggregate.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, aggregate.Start())
+
+	entries := make([]*entry.Entry, 0, 4)
+	for _, service := range []string{"api", "web", "api", "db"} {
+		e := entry.New()
+		e.AddLabel("service", service)
+		entries = append(entries, e)
+		require.NoError(t, aggregate.Process(context.Background(), e))
+	}
+	require.Equal(t, int64(2), aggregate.Inspect()["overflowed_entries"])
+
+	// Entries of groups beyond max_groups are written without being aggregated
+	require.Equal(t, []*entry.Entry{entries[1], entries[3]}, fake.ReceivedEntries())
+
+	require.NoError(t, aggregate.Stop())
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, 2, written[0].Record.(map[string]interface{})["count"])
+}
+
+func TestAggregateFieldName(t *testing.T) {
+	require.Equal(t, "latency", fieldName(entry.NewRecordField("http", "latency")))
+	require.Equal(t, "bytes", fieldName(entry.NewLabelField("bytes")))
+	require.Equal(t, "size.bytes", fieldName(entry.NewResourceField("size.bytes")))
+}
+
+func TestAggregateSamples(t *testing.T) {
+	original := randIntn
+	randIntn = func(n int) int { return 0 }
+	defer func() { randIntn = original }()
+
+	s := &stats{}
+	for _, value := range []float64{5, 1, 9, 3} {
+		s.add(value, 2)
+	}
+
+	// Once full, each new value replaces the sample chosen at random
+	summary := s.summary([]float64{100})
+	require.Equal(t, 4, summary["count"])
+	require.Equal(t, 1.0, summary["min"])
+	require.Equal(t, 9.0, summary["max"])
+	require.Equal(t, 3.0, summary["p100"])
+}
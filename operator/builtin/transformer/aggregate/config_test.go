@@ -0,0 +1,92 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "group_by",
+			Expect: func() *AggregateConfig {
+				cfg := defaultCfg()
+				cfg.GroupBy = []entry.Field{entry.NewLabelField("service")}
+				cfg.Fields = []entry.Field{entry.NewRecordField("duration_ms"), entry.NewRecordField("response", "bytes")}
+				return cfg
+			}(),
+		},
+		{
+			Name: "window",
+			Expect: func() *AggregateConfig {
+				cfg := defaultCfg()
+				cfg.Window = helper.Duration{Duration: 10 * time.Second}
+				cfg.Percentiles = []float64{50, 95}
+				return cfg
+			}(),
+		},
+		{
+			Name: "pass_through",
+			Expect: func() *AggregateConfig {
+				cfg := defaultCfg()
+				cfg.PassThrough = true
+				cfg.MaxGroups = 100
+				cfg.MaxSamples = 500
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "window_zero",
+			ExpectErr: "window must be greater than zero",
+		},
+		{
+			Name:      "max_groups_negative",
+			ExpectErr: "max_groups must be greater than zero",
+		},
+		{
+			Name:      "max_samples_negative",
+			ExpectErr: "max_samples must be greater than zero",
+		},
+		{
+			Name:      "percentile_invalid",
+			ExpectErr: "got 150",
+		},
+		{
+			Name:      "field_name_duplicate",
+			ExpectErr: "field 'b.latency' has the same name",
+		},
+		{
+			Name:      "field_name_reserved",
+			ExpectErr: "field '$labels.count' has the same name",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *AggregateConfig {
+	return NewAggregateConfig("aggregate")
+}
@@ -0,0 +1,111 @@
+package dedupe
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+)
+
+// fingerprintCache is a least recently used cache of fingerprints, each of which
+// expires a fixed time after it was first seen
+type fingerprintCache struct {
+	maxEntries int
+	items      map[string]*list.Element
+	order      *list.List
+}
+
+// cacheItem is a fingerprint held in the cache
+type cacheItem struct {
+	fingerprint string
+	expires     time.Time
+
+	// entry and count are the first entry with the fingerprint, and the number
+	// of times it has been seen, when duplicates are collapsed
+	entry *entry.Entry
+	count int
+}
+
+// newFingerprintCache creates a cache that holds up to maxEntries fingerprints
+func newFingerprintCache(maxEntries int) *fingerprintCache {
+	return &fingerprintCache{
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get returns the item with the fingerprint, or nil if it is not in the cache. An
+// item that has expired is removed from the cache and returned as expired, so that
+// an entry it holds can still be written.
+func (c *fingerprintCache) get(fingerprint string, now time.Time) (item, expired *cacheItem) {
+	element, ok := c.items[fingerprint]
+	if !ok {
+		return nil, nil
+	}
+
+	item = element.Value.(*cacheItem)
+	if !now.Before(item.expires) {
+		c.order.Remove(element)
+		delete(c.items, fingerprint)
+		return nil, item
+	}
+
+	c.order.MoveToFront(element)
+	return item, nil
+}
+
+// add adds an item to the cache. If the cache is full, the least recently used
+// item is removed and returned.
+func (c *fingerprintCache) add(item *cacheItem) *cacheItem {
+	c.items[item.fingerprint] = c.order.PushFront(item)
+	if c.order.Len() <= c.maxEntries {
+		return nil
+	}
+
+	oldest := c.order.Back()
+	c.order.Remove(oldest)
+	evicted := oldest.Value.(*cacheItem)
+	delete(c.items, evicted.fingerprint)
+	return evicted
+}
+
+// removeExpired removes and returns the items that have expired
+func (c *fingerprintCache) removeExpired(now time.Time) []*cacheItem {
+	expired := make([]*cacheItem, 0)
+	for element := c.order.Back(); element != nil; {
+		previous := element.Prev()
+		if item := element.Value.(*cacheItem); !now.Before(item.expires) {
+			c.order.Remove(element)
+			delete(c.items, item.fingerprint)
+			expired = append(expired, item)
+		}
+		element = previous
+	}
+	return expired
+}
+
+// removeAll removes and returns every item in the cache, least recently used first
+func (c *fingerprintCache) removeAll() []*cacheItem {
+	items := make([]*cacheItem, 0, c.order.Len())
+	for element := c.order.Back(); element != nil; element = element.Prev() {
+		items = append(items, element.Value.(*cacheItem))
+	}
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	return items
+}
+
+// all returns every item in the cache, least recently used first
+func (c *fingerprintCache) all() []*cacheItem {
+	items := make([]*cacheItem, 0, c.order.Len())
+	for element := c.order.Back(); element != nil; element = element.Prev() {
+		items = append(items, element.Value.(*cacheItem))
+	}
+	return items
+}
+
+// len returns the number of items in the cache
+func (c *fingerprintCache) len() int {
+	return c.order.Len()
+}
@@ -0,0 +1,94 @@
+package dedupe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "fields",
+			Expect: func() *DedupeConfig {
+				cfg := defaultCfg()
+				cfg.Fields = []entry.Field{entry.NewRecordField("message"), entry.NewLabelField("host")}
+				return cfg
+			}(),
+		},
+		{
+			Name: "ttl",
+			Expect: func() *DedupeConfig {
+				cfg := defaultCfg()
+				cfg.TTL = helper.Duration{Duration: 5 * time.Minute}
+				cfg.MaxEntries = 500
+				return cfg
+			}(),
+		},
+		{
+			Name: "collapse",
+			Expect: func() *DedupeConfig {
+				cfg := defaultCfg()
+				cfg.Mode = CollapseMode
+				cfg.CountField = entry.NewRecordField("repeats")
+				return cfg
+			}(),
+		},
+		{
+			Name: "persist",
+			Expect: func() *DedupeConfig {
+				cfg := defaultCfg()
+				cfg.Persist = true
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "ttl_zero",
+			ExpectErr: "ttl must be greater than zero",
+		},
+		{
+			Name:      "max_entries_negative",
+			ExpectErr: "max_entries must be greater than zero",
+		},
+		{
+			Name:      "mode_invalid",
+			ExpectErr: "invalid mode 'merge'",
+		},
+		{
+			Name:      "persist_collapse",
+			ExpectErr: "persist is only supported with mode 'drop'",
+		},
+		{
+			Name:      "count_field_record",
+			ExpectErr: "count_field cannot be the whole record",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *DedupeConfig {
+	return NewDedupeConfig("dedupe")
+}
@@ -0,0 +1,353 @@
+package dedupe
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("dedupe", func() operator.Builder { return NewDedupeConfig("") })
+}
+
+const (
+	// DropMode passes the first entry with a fingerprint, and drops its duplicates
+	DropMode = "drop"
+	// CollapseMode holds the first entry with a fingerprint until its ttl expires,
+	// then writes it with the number of times it was seen
+	CollapseMode = "collapse"
+
+	// fingerprintsKey is the key the seen fingerprints are persisted under
+	fingerprintsKey = "fingerprints"
+)
+
+var timeNow = time.Now // allow override for testing
+
+// NewDedupeConfig creates a new dedupe config with default values
+func NewDedupeConfig(operatorID string) *DedupeConfig {
+	return &DedupeConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "dedupe"),
+		TTL:               helper.Duration{Duration: time.Minute},
+		MaxEntries:        10000,
+		Mode:              DropMode,
+		CountField:        entry.NewLabelField("repeat_count"),
+	}
+}
+
+// DedupeConfig is the configuration of a dedupe operator
+type DedupeConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	Fields     []entry.Field   `json:"fields,omitempty"      yaml:"fields,omitempty"`
+	TTL        helper.Duration `json:"ttl,omitempty"         yaml:"ttl,omitempty"`
+	MaxEntries int             `json:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+	Mode       string          `json:"mode,omitempty"        yaml:"mode,omitempty"`
+	CountField entry.Field     `json:"count_field,omitempty" yaml:"count_field,omitempty"`
+	Persist    bool            `json:"persist,omitempty"     yaml:"persist,omitempty"`
+}
+
+// Build will build a dedupe operator
+func (c DedupeConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.TTL.Raw() <= 0:
+		return nil, fmt.Errorf("ttl must be greater than zero")
+	case c.MaxEntries <= 0:
+		return nil, fmt.Errorf("max_entries must be greater than zero")
+	case c.Mode != DropMode && c.Mode != CollapseMode:
+		return nil, fmt.Errorf("invalid mode '%s', must be one of '%s' or '%s'", c.Mode, DropMode, CollapseMode)
+	case c.Persist && c.Mode == CollapseMode:
+		return nil, fmt.Errorf("persist is only supported with mode '%s'", DropMode)
+	case c.Mode == CollapseMode && c.CountField.FieldInterface == nil:
+		return nil, fmt.Errorf("count_field must be set with mode '%s'", CollapseMode)
+	case c.Mode == CollapseMode && isRecordRoot(c.CountField):
+		return nil, fmt.Errorf("count_field cannot be the whole record, which it would replace")
+	}
+
+	dedupeOperator := &DedupeOperator{
+		TransformerOperator: transformerOperator,
+		fields:              c.Fields,
+		ttl:                 c.TTL.Raw(),
+		maxEntries:          c.MaxEntries,
+		collapse:            c.Mode == CollapseMode,
+		countField:          c.CountField,
+	}
+
+	if c.Persist {
+		dedupeOperator.persist = helper.NewScopedDBPersister(context.Database, c.ID())
+	}
+
+	return []operator.Operator{dedupeOperator}, nil
+}
+
+// DedupeOperator is an operator that removes duplicate entries
+type DedupeOperator struct {
+	helper.TransformerOperator
+	fields     []entry.Field
+	ttl        time.Duration
+	maxEntries int
+	collapse   bool
+	countField entry.Field
+	persist    helper.Persister
+
+	mux    sync.Mutex
+	seen   *fingerprintCache
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start will start the dedupe operator. Persisted fingerprints that have not
+// expired are loaded, and when collapsing duplicates, held entries are written
+// as their ttl expires.
+func (d *DedupeOperator) Start() error {
+	d.seen = newFingerprintCache(d.maxEntries)
+	if err := d.loadFingerprints(); err != nil {
+		return err
+	}
+
+	if !d.collapse {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.wg.Add(1)
+	go d.flushExpired(ctx)
+	return nil
+}
+
+// Stop will stop the dedupe operator. Held entries are written, and the seen
+// fingerprints are persisted.
+func (d *DedupeOperator) Stop() error {
+	if d.cancel != nil {
+		d.cancel()
+		d.wg.Wait()
+	}
+
+	if d.collapse {
+		d.Drain(context.Background())
+		return nil
+	}
+	return d.saveFingerprints()
+}
+
+// Drain writes the entries held while collapsing duplicates
+func (d *DedupeOperator) Drain(ctx context.Context) int {
+	d.mux.Lock()
+	if d.seen == nil || !d.collapse {
+		d.mux.Unlock()
+		return 0
+	}
+	items := d.seen.removeAll()
+	d.mux.Unlock()
+
+	d.writeCollapsed(ctx, items)
+	return 0
+}
+
+// Process will write the entry to the outputs unless it is a duplicate of an entry
+// seen within the ttl
+func (d *DedupeOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	skip, err := d.Skip(ctx, entry)
+	if err != nil {
+		return d.HandleEntryError(ctx, entry, err)
+	}
+	if skip {
+		d.Write(ctx, entry)
+		return nil
+	}
+
+	fingerprint, err := d.fingerprint(entry)
+	if err != nil {
+		return d.HandleEntryError(ctx, entry, err)
+	}
+
+	now := timeNow()
+	d.mux.Lock()
+	item, expired := d.seen.get(fingerprint, now)
+	if item != nil {
+		item.count++
+		d.mux.Unlock()
+		if !d.collapse {
+			d.RecordDropped()
+		}
+		return nil
+	}
+
+	item = &cacheItem{fingerprint: fingerprint, expires: now.Add(d.ttl), count: 1}
+	if d.collapse {
+		item.entry = entry
+	}
+	evicted := d.seen.add(item)
+	d.mux.Unlock()
+
+	if !d.collapse {
+		d.Write(ctx, entry)
+		return nil
+	}
+
+	// An entry held past its ttl, that has not been flushed yet, is written before
+	// the new one is held
+	held := make([]*cacheItem, 0, 2)
+	for _, heldItem := range []*cacheItem{expired, evicted} {
+		if heldItem != nil {
+			held = append(held, heldItem)
+		}
+	}
+	d.writeCollapsed(ctx, held)
+	return nil
+}
+
+// isRecordRoot returns true if the field is the whole record
+func isRecordRoot(field entry.Field) bool {
+	recordField, ok := field.FieldInterface.(entry.RecordField)
+	return ok && len(recordField.Keys) == 0
+}
+
+// fingerprint returns a hash of the fields of the entry that identify duplicates,
+// or of its whole record if no fields are configured
+func (d *DedupeOperator) fingerprint(e *entry.Entry) (string, error) {
+	var value interface{} = e.Record
+	if len(d.fields) > 0 {
+		values := make([]interface{}, 0, len(d.fields))
+		for _, field := range d.fields {
+			fieldValue, _ := e.Get(field)
+			values = append(values, fieldValue)
+		}
+		value = values
+	}
+
+	marshalled, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("fingerprint entry: %s", err)
+	}
+
+	h := fnv.New128a()
+	_, _ = h.Write(marshalled)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// flushExpired writes held entries as their ttl expires, until the context is done
+func (d *DedupeOperator) flushExpired(ctx context.Context) {
+	defer d.wg.Done()
+
+	interval := d.ttl
+	if interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.mux.Lock()
+		expired := d.seen.removeExpired(timeNow())
+		d.mux.Unlock()
+		d.writeCollapsed(context.Background(), expired)
+	}
+}
+
+// writeCollapsed writes held entries, with the number of times each was seen
+func (d *DedupeOperator) writeCollapsed(ctx context.Context, items []*cacheItem) {
+	for _, item := range items {
+		var count interface{} = item.count
+		switch d.countField.FieldInterface.(type) {
+		case entry.LabelField, entry.ResourceField:
+			// Labels and resource values must be strings
+			count = strconv.Itoa(item.count)
+		case entry.RecordField:
+			// Setting a field of a record that is not a map would replace the record
+			if _, ok := item.entry.Record.(map[string]interface{}); !ok && item.entry.Record != nil {
+				d.Warnw("Failed to set repeat count, because the record is not a map", "count_field", d.countField.String())
+				d.Write(ctx, item.entry)
+				continue
+			}
+		}
+
+		if err := item.entry.Set(d.countField, count); err != nil {
+			d.Warnw("Failed to set repeat count", zap.Any("error", err))
+		}
+		d.Write(ctx, item.entry)
+	}
+}
+
+// loadFingerprints adds the persisted fingerprints that have not expired to the cache
+func (d *DedupeOperator) loadFingerprints() error {
+	if d.persist == nil {
+		return nil
+	}
+	if err := d.persist.Load(); err != nil {
+		return fmt.Errorf("load fingerprints: %s", err)
+	}
+
+	raw := d.persist.Get(fingerprintsKey)
+	if raw == nil {
+		return nil
+	}
+
+	expirations := make(map[string]int64)
+	if err := json.Unmarshal(raw, &expirations); err != nil {
+		d.Warnw("Failed to decode persisted fingerprints", zap.Any("error", err))
+		return nil
+	}
+
+	now := timeNow()
+	for fingerprint, expires := range expirations {
+		item := &cacheItem{fingerprint: fingerprint, expires: time.Unix(0, expires), count: 1}
+		if item.expires.After(now) {
+			d.seen.add(item)
+		}
+	}
+	return nil
+}
+
+// saveFingerprints persists the fingerprints in the cache that have not expired
+func (d *DedupeOperator) saveFingerprints() error {
+	if d.persist == nil || d.seen == nil {
+		return nil
+	}
+
+	d.mux.Lock()
+	d.seen.removeExpired(timeNow())
+	expirations := make(map[string]int64, d.seen.len())
+	for _, item := range d.seen.all() {
+		expirations[item.fingerprint] = item.expires.UnixNano()
+	}
+	d.mux.Unlock()
+
+	marshalled, err := json.Marshal(expirations)
+	if err != nil {
+		return err
+	}
+	d.persist.Set(fingerprintsKey, marshalled)
+	return d.persist.Sync()
+}
+
+// Inspect reports the number of fingerprints held by the operator
+func (d *DedupeOperator) Inspect() map[string]interface{} {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.seen == nil {
+		return map[string]interface{}{"fingerprints": 0}
+	}
+	return map[string]interface{}{"fingerprints": d.seen.len()}
+}
@@ -0,0 +1,234 @@
+package dedupe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupeDropRecord(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+	defer dedupe.Stop()
+
+	for _, record := range []string{"a", "b", "a", "a", "b", "c"} {
+		require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: record}))
+	}
+	require.Len(t, fake.ReceivedEntries(), 3)
+
+	// Fingerprints expire after the ttl
+	clock.Advance(time.Minute)
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "a"}))
+	fake.ExpectRecord(t, "a")
+}
+
+func TestDedupeDropFields(t *testing.T) {
+	testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Fields = []entry.Field{entry.NewRecordField("message"), entry.NewLabelField("host")}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+	defer dedupe.Stop()
+
+	process := func(message, host string, attempt int) {
+		e := &entry.Entry{Record: map[string]interface{}{"message": message, "attempt": attempt}}
+		e.AddLabel("host", host)
+		require.NoError(t, dedupe.Process(context.Background(), e))
+	}
+
+	process("started", "a", 1)
+	process("started", "a", 2)
+	process("started", "b", 1)
+	process("stopped", "a", 1)
+	require.Len(t, fake.ReceivedEntries(), 3)
+}
+
+func TestDedupeMaxEntries(t *testing.T) {
+	testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.MaxEntries = 2
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+	defer dedupe.Stop()
+
+	// "a" is used more recently than "b", so "b" is evicted by "c"
+	for _, record := range []string{"a", "b", "a", "c", "a", "b"} {
+		require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: record}))
+	}
+	for _, record := range []string{"a", "b", "c", "b"} {
+		fake.ExpectRecord(t, record)
+	}
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+}
+
+func TestDedupeCollapse(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Mode = CollapseMode
+	cfg.TTL = helper.Duration{Duration: 10 * time.Millisecond}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+	defer dedupe.Stop()
+
+	for _, message := range []string{"retry", "retry", "retry", "once"} {
+		require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"message": message}}))
+	}
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+
+	clock.Advance(10 * time.Millisecond)
+	counts := make(map[interface{}]interface{})
+	require.Eventually(t, func() bool {
+		for _, e := range fake.ReceivedEntries() {
+			record := e.Record.(map[string]interface{})
+			counts[record["message"]] = e.Labels["repeat_count"]
+		}
+		return len(counts) == 2
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, map[interface{}]interface{}{"retry": "3", "once": "1"}, counts)
+}
+
+func TestDedupeCollapseExpiredBeforeFlush(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Mode = CollapseMode
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "a"}))
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "a"}))
+
+	// A duplicate that arrives after the ttl, but before the held entry is flushed,
+	// writes the held entry and is held in its place
+	clock.Advance(time.Minute)
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "a"}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "2", written[0].Labels["repeat_count"])
+
+	require.NoError(t, dedupe.Stop())
+	written = fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "1", written[0].Labels["repeat_count"])
+}
+
+func TestDedupeCollapseRecordCountField(t *testing.T) {
+	testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Mode = CollapseMode
+	cfg.CountField = entry.NewRecordField("repeat_count")
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"message": "retry"}}))
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "raw line"}))
+	require.NoError(t, dedupe.Stop())
+
+	// The count is not set on a record that is not a map, which it would replace
+	records := make([]interface{}, 0)
+	for _, e := range fake.ReceivedEntries() {
+		records = append(records, e.Record)
+	}
+	require.ElementsMatch(t, []interface{}{map[string]interface{}{"message": "retry", "repeat_count": 1}, "raw line"}, records)
+}
+
+func TestDedupeCollapseDrain(t *testing.T) {
+	testutil.NewFakeClock(t, &timeNow)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Mode = CollapseMode
+	cfg.CountField = entry.NewLabelField("repeats")
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "retry"}))
+	}
+	require.Equal(t, 0, dedupe.Drain(context.Background()))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "2", written[0].Labels["repeats"])
+	require.NoError(t, dedupe.Stop())
+}
+
+func TestDedupePersist(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+	bc := testutil.NewBuildContext(t)
+	cfg := NewDedupeConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Persist = true
+
+	ops, err := cfg.Build(bc)
+	require.NoError(t, err)
+	dedupe := ops[0].(*DedupeOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "a"}))
+	fake.ExpectRecord(t, "a")
+	require.NoError(t, dedupe.Stop())
+
+	// Fingerprints seen before a restart are still recognized
+	ops, err = cfg.Build(bc)
+	require.NoError(t, err)
+	dedupe = ops[0].(*DedupeOperator)
+	fake = testutil.NewFakeOutput(t)
+	require.NoError(t, dedupe.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, dedupe.Start())
+	require.Equal(t, 1, dedupe.Inspect()["fingerprints"])
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "a"}))
+	require.NoError(t, dedupe.Process(context.Background(), &entry.Entry{Record: "b"}))
+	fake.ExpectRecord(t, "b")
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+	require.NoError(t, dedupe.Stop())
+
+	// Expired fingerprints are not loaded
+	clock.Advance(time.Minute)
+	ops, err = cfg.Build(bc)
+	require.NoError(t, err)
+	dedupe = ops[0].(*DedupeOperator)
+	require.NoError(t, dedupe.Start())
+	require.Equal(t, 0, dedupe.Inspect()["fingerprints"])
+	require.NoError(t, dedupe.Stop())
+}
@@ -98,7 +98,10 @@ func (f *FilterOperator) Process(ctx context.Context, entry *entry.Entry) error
 
 	if i.Cmp(f.dropCutoff) >= 0 {
 		f.Write(ctx, entry)
+		return nil
 	}
 
+	f.RecordDropped()
+
 	return nil
 }
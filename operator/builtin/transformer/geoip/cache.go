@@ -0,0 +1,99 @@
+package geoip
+
+import (
+	"container/list"
+	"sync"
+)
+
+// resultCache is a least recently used cache of the values resolved for addresses.
+// An empty result means no database has a record of the address.
+//
+// The cache is cleared when a database is reloaded. Each clear starts a new generation,
+// and results resolved during an earlier generation are not added, since they may have
+// been resolved from the database that was replaced.
+type resultCache struct {
+	maxEntries int
+
+	mux        sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	generation uint64
+}
+
+type cacheItem struct {
+	address string
+	values  map[string]interface{}
+}
+
+// newResultCache creates a cache that holds up to maxEntries results
+func newResultCache(maxEntries int) *resultCache {
+	return &resultCache{
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get returns the values cached for the address
+func (c *resultCache) get(address string) (map[string]interface{}, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	element, ok := c.items[address]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheItem).values, true
+}
+
+// currentGeneration returns the generation that results resolved from now on belong to
+func (c *resultCache) currentGeneration() uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.generation
+}
+
+// add caches the values of an address resolved during a generation, removing the
+// least recently used result if the cache is full. Values from an earlier generation
+// are not cached.
+func (c *resultCache) add(address string, values map[string]interface{}, generation uint64) {
+	if c.maxEntries <= 0 {
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if generation != c.generation {
+		return
+	}
+
+	if element, ok := c.items[address]; ok {
+		element.Value.(*cacheItem).values = values
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[address] = c.order.PushFront(&cacheItem{address: address, values: values})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).address)
+	}
+}
+
+// clear removes every result from the cache, and starts a new generation
+func (c *resultCache) clear() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.generation++
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// len returns the number of results in the cache
+func (c *resultCache) len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,96 @@
+package geoip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "databases",
+			Expect: func() *GeoIPConfig {
+				cfg := defaultCfg()
+				cfg.Field = entry.NewRecordField("client_ip")
+				cfg.Database = "/var/lib/GeoIP/GeoLite2-City.mmdb"
+				cfg.ASNDatabase = "/var/lib/GeoIP/GeoLite2-ASN.mmdb"
+				return cfg
+			}(),
+		},
+		{
+			Name: "target",
+			Expect: func() *GeoIPConfig {
+				cfg := defaultCfg()
+				cfg.Field = entry.NewLabelField("ip")
+				cfg.Database = "city.mmdb"
+				cfg.Target = entry.NewRecordField("client", "geo")
+				cfg.Language = "de"
+				cfg.CacheSize = 100
+				cfg.PollInterval = helper.Duration{Duration: time.Minute}
+				return cfg
+			}(),
+		},
+		{
+			Name: "values",
+			Expect: func() *GeoIPConfig {
+				cfg := defaultCfg()
+				cfg.Field = entry.NewRecordField("client_ip")
+				cfg.Database = "city.mmdb"
+				cfg.Values = map[string]entry.Field{
+					CountryCodeValue: entry.NewLabelField("country"),
+					CityValue:        entry.NewResourceField("city"),
+				}
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "field_missing",
+			ExpectErr: "missing required field 'field'",
+		},
+		{
+			Name:      "database_missing",
+			ExpectErr: "missing required field 'database'",
+		},
+		{
+			Name:      "cache_size_negative",
+			ExpectErr: "cache_size must not be negative",
+		},
+		{
+			Name:      "target_label",
+			ExpectErr: "target must be a record field",
+		},
+		{
+			Name:      "value_invalid",
+			ExpectErr: "invalid value 'zip'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *GeoIPConfig {
+	return NewGeoIPConfig("geoip")
+}
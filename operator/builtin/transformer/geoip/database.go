@@ -0,0 +1,158 @@
+package geoip
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoRecord holds the values of the GeoIP2 and GeoLite2 City, Country and ASN
+// databases that are added to entries
+type geoRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// values returns the values of the record that are set, using names in the language
+func (r *geoRecord) values(language string) map[string]interface{} {
+	values := make(map[string]interface{})
+	setString := func(key, value string) {
+		if value != "" {
+			values[key] = value
+		}
+	}
+
+	setString(CountryCodeValue, r.Country.ISOCode)
+	setString(CountryValue, r.Country.Names[language])
+	if len(r.Subdivisions) > 0 {
+		setString(RegionCodeValue, r.Subdivisions[0].ISOCode)
+		setString(RegionValue, r.Subdivisions[0].Names[language])
+	}
+	setString(CityValue, r.City.Names[language])
+	if r.Location.Latitude != nil && r.Location.Longitude != nil {
+		values[LatitudeValue] = *r.Location.Latitude
+		values[LongitudeValue] = *r.Location.Longitude
+	}
+	if r.ASN != 0 {
+		values[ASNValue] = r.ASN
+	}
+	setString(ASOrgValue, r.ASOrg)
+	return values
+}
+
+// database is an MMDB file, which is reopened when the file changes
+type database struct {
+	path string
+
+	mux       sync.RWMutex
+	reader    *maxminddb.Reader
+	modTime   time.Time
+	size      int64
+	loadedAt  time.Time
+	loadError error
+}
+
+// changed returns true if the file has changed since it was last opened
+func (d *database) changed() (bool, error) {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return false, err
+	}
+
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	return !info.ModTime().Equal(d.modTime) || info.Size() != d.size, nil
+}
+
+// open opens the file, and replaces the current reader if it opens successfully
+func (d *database) open() error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+
+	reader, err := maxminddb.Open(d.path)
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	// The state is recorded even on failure, so that a broken file is only
+	// retried once it changes again
+	d.modTime, d.size, d.loadError = info.ModTime(), info.Size(), err
+	if err != nil {
+		return err
+	}
+
+	if d.reader != nil {
+		_ = d.reader.Close()
+	}
+	d.reader, d.loadedAt = reader, time.Now()
+	return nil
+}
+
+// lookup adds the values of the record of the address, if the database has one
+func (d *database) lookup(ip net.IP, language string, values map[string]interface{}) error {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	if d.reader == nil {
+		return nil
+	}
+
+	var record geoRecord
+	_, ok, err := d.reader.LookupNetwork(ip, &record)
+	if err != nil || !ok {
+		return err
+	}
+	for key, value := range record.values(language) {
+		values[key] = value
+	}
+	return nil
+}
+
+// close closes the current reader
+func (d *database) close() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.reader == nil {
+		return nil
+	}
+	err := d.reader.Close()
+	d.reader = nil
+	return err
+}
+
+// details returns the state of the database for inspection
+func (d *database) details() map[string]interface{} {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+
+	details := map[string]interface{}{
+		"path":      d.path,
+		"loaded_at": d.loadedAt,
+	}
+	if d.reader != nil {
+		details["type"] = d.reader.Metadata.DatabaseType
+		details["build_epoch"] = d.reader.Metadata.BuildEpoch
+	}
+	if d.loadError != nil {
+		details["load_error"] = d.loadError.Error()
+	}
+	return details
+}
@@ -0,0 +1,329 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("geoip", func() operator.Builder { return NewGeoIPConfig("") })
+}
+
+// The names of the values resolved for an address
+const (
+	CountryCodeValue = "country_code"
+	CountryValue     = "country"
+	RegionCodeValue  = "region_code"
+	RegionValue      = "region"
+	CityValue        = "city"
+	LatitudeValue    = "latitude"
+	LongitudeValue   = "longitude"
+	ASNValue         = "asn"
+	ASOrgValue       = "as_org"
+)
+
+var valueNames = []string{
+	CountryCodeValue, CountryValue, RegionCodeValue, RegionValue, CityValue,
+	LatitudeValue, LongitudeValue, ASNValue, ASOrgValue,
+}
+
+// NewGeoIPConfig creates a new geoip config with default values
+func NewGeoIPConfig(operatorID string) *GeoIPConfig {
+	return &GeoIPConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "geoip"),
+		Target:            entry.NewRecordField("geoip"),
+		Language:          "en",
+		CacheSize:         10000,
+		PollInterval:      helper.Duration{Duration: 10 * time.Second},
+	}
+}
+
+// GeoIPConfig is the configuration of a geoip operator
+type GeoIPConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	Field        entry.Field            `json:"field,omitempty"         yaml:"field,omitempty"`
+	Database     string                 `json:"database,omitempty"      yaml:"database,omitempty"`
+	ASNDatabase  string                 `json:"asn_database,omitempty"  yaml:"asn_database,omitempty"`
+	Target       entry.Field            `json:"target,omitempty"        yaml:"target,omitempty"`
+	Values       map[string]entry.Field `json:"values,omitempty"        yaml:"values,omitempty"`
+	Language     string                 `json:"language,omitempty"      yaml:"language,omitempty"`
+	CacheSize    int                    `json:"cache_size,omitempty"    yaml:"cache_size,omitempty"`
+	PollInterval helper.Duration        `json:"poll_interval,omitempty" yaml:"poll_interval,omitempty"`
+}
+
+// Build will build a geoip operator
+func (c GeoIPConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Field.FieldInterface == nil:
+		return nil, fmt.Errorf("missing required field 'field'")
+	case c.Database == "":
+		return nil, fmt.Errorf("missing required field 'database'")
+	case c.CacheSize < 0:
+		return nil, fmt.Errorf("cache_size must not be negative")
+	case c.PollInterval.Raw() < 0:
+		return nil, fmt.Errorf("poll_interval must not be negative")
+	}
+
+	if len(c.Values) == 0 {
+		if _, ok := c.Target.FieldInterface.(entry.RecordField); !ok {
+			return nil, fmt.Errorf("target must be a record field")
+		}
+	}
+	for name := range c.Values {
+		if !isValueName(name) {
+			return nil, fmt.Errorf("invalid value '%s', must be one of %s", name, strings.Join(valueNames, ", "))
+		}
+	}
+
+	databases := []*database{{path: c.Database}}
+	if c.ASNDatabase != "" {
+		databases = append(databases, &database{path: c.ASNDatabase})
+	}
+
+	geoIPOperator := &GeoIPOperator{
+		TransformerOperator: transformerOperator,
+		field:               c.Field,
+		databases:           databases,
+		target:              c.Target,
+		values:              c.Values,
+		language:            c.Language,
+		cache:               newResultCache(c.CacheSize),
+		pollInterval:        c.PollInterval.Raw(),
+	}
+
+	return []operator.Operator{geoIPOperator}, nil
+}
+
+func isValueName(name string) bool {
+	for _, valueName := range valueNames {
+		if name == valueName {
+			return true
+		}
+	}
+	return false
+}
+
+// GeoIPOperator is an operator that adds the location and network of an IP address
+// to entries
+type GeoIPOperator struct {
+	helper.TransformerOperator
+	field        entry.Field
+	databases    []*database
+	target       entry.Field
+	values       map[string]entry.Field
+	language     string
+	cache        *resultCache
+	pollInterval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start will open the databases, and start polling them for changes
+func (g *GeoIPOperator) Start() error {
+	for _, db := range g.databases {
+		if err := db.open(); err != nil {
+			g.closeDatabases()
+			return fmt.Errorf("open geoip database: %s", err)
+		}
+	}
+
+	if g.pollInterval == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	g.wg.Add(1)
+	go g.pollDatabases(ctx)
+	return nil
+}
+
+// Stop will stop polling the databases, and close them
+func (g *GeoIPOperator) Stop() error {
+	if g.cancel != nil {
+		g.cancel()
+		g.wg.Wait()
+	}
+	g.closeDatabases()
+	return nil
+}
+
+func (g *GeoIPOperator) closeDatabases() {
+	for _, db := range g.databases {
+		if err := db.close(); err != nil {
+			g.Warnw("Failed to close geoip database", zap.Any("error", err))
+		}
+	}
+}
+
+// Process will add the values resolved for the address in the entry's field
+func (g *GeoIPOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	skip, err := g.Skip(ctx, entry)
+	if err != nil {
+		return g.HandleEntryError(ctx, entry, err)
+	}
+	if skip {
+		g.Write(ctx, entry)
+		return nil
+	}
+
+	value, ok := entry.Get(g.field)
+	if !ok {
+		g.Write(ctx, entry)
+		return nil
+	}
+
+	ip := parseIP(value)
+	if ip == nil {
+		return g.HandleEntryError(ctx, entry, fmt.Errorf("'%v' is not an IP address", value))
+	}
+
+	values, err := g.resolve(ip)
+	if err != nil {
+		return g.HandleEntryError(ctx, entry, err)
+	}
+
+	if err := g.setValues(entry, values); err != nil {
+		return g.HandleEntryError(ctx, entry, err)
+	}
+	g.Write(ctx, entry)
+	return nil
+}
+
+// parseIP returns the IP address of a value, which may include a port
+func parseIP(value interface{}) net.IP {
+	switch v := value.(type) {
+	case net.IP:
+		return v
+	case string:
+		v = strings.TrimSpace(v)
+		if ip := net.ParseIP(v); ip != nil {
+			return ip
+		}
+		if host, _, err := net.SplitHostPort(v); err == nil {
+			return net.ParseIP(host)
+		}
+	}
+	return nil
+}
+
+// resolve returns the values of the records of the address in each database
+func (g *GeoIPOperator) resolve(ip net.IP) (map[string]interface{}, error) {
+	address := ip.String()
+	if values, ok := g.cache.get(address); ok {
+		return values, nil
+	}
+
+	// The generation is read before the databases, so that values looked up in a
+	// database that is replaced meanwhile are not cached
+	generation := g.cache.currentGeneration()
+	values := make(map[string]interface{})
+	for _, db := range g.databases {
+		if err := db.lookup(ip, g.language, values); err != nil {
+			return nil, fmt.Errorf("look up '%s': %s", address, err)
+		}
+	}
+	g.cache.add(address, values, generation)
+	return values, nil
+}
+
+// setValues sets the resolved values on the entry. The values are set as a map at
+// the target, unless individual values are mapped to fields.
+func (g *GeoIPOperator) setValues(e *entry.Entry, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if len(g.values) == 0 {
+		// Cached values are shared, so each entry gets its own copy
+		copied := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			copied[key] = value
+		}
+		return e.Set(g.target, copied)
+	}
+
+	for name, field := range g.values {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		switch field.FieldInterface.(type) {
+		case entry.LabelField, entry.ResourceField:
+			// Labels and resource values must be strings
+			value = fmt.Sprint(value)
+		}
+		if err := e.Set(field, value); err != nil {
+			return fmt.Errorf("set %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// pollDatabases reopens databases when their files are replaced, until the context
+// is done
+func (g *GeoIPOperator) pollDatabases(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, db := range g.databases {
+			changed, err := db.changed()
+			if err != nil {
+				g.Warnw("Failed to check geoip database", zap.Any("error", err))
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			// The previous database is kept if the new one cannot be opened. The
+			// cache is cleared after the new one replaces it, so that no result of
+			// the previous one is cached afterwards.
+			if err := db.open(); err != nil {
+				g.Warnw("Failed to reload geoip database", zap.Any("error", err))
+				continue
+			}
+			g.cache.clear()
+			g.Infow("Reloaded geoip database", "path", db.path)
+		}
+	}
+}
+
+// Inspect reports the state of each database, and the number of cached addresses
+func (g *GeoIPOperator) Inspect() map[string]interface{} {
+	databases := make([]map[string]interface{}, 0, len(g.databases))
+	for _, db := range g.databases {
+		databases = append(databases, db.details())
+	}
+
+	return map[string]interface{}{
+		"databases":        databases,
+		"cached_addresses": g.cache.len(),
+	}
+}
@@ -0,0 +1,211 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// cityRecord returns a record in the layout of the GeoLite2 City database
+func cityRecord(countryCode, country, city string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"country": mmdbtype.Map{
+			"iso_code": mmdbtype.String(countryCode),
+			"names":    mmdbtype.Map{"en": mmdbtype.String(country)},
+		},
+		"subdivisions": mmdbtype.Slice{mmdbtype.Map{
+			"iso_code": mmdbtype.String("ENG"),
+			"names":    mmdbtype.Map{"en": mmdbtype.String("England")},
+		}},
+		"city": mmdbtype.Map{
+			"names": mmdbtype.Map{"en": mmdbtype.String(city)},
+		},
+		"location": mmdbtype.Map{
+			"latitude":  mmdbtype.Float64(51.5142),
+			"longitude": mmdbtype.Float64(-0.0931),
+		},
+	}
+}
+
+// writeDatabase writes an MMDB file with a record for each network
+func writeDatabase(t *testing.T, path, databaseType string, records map[string]mmdbtype.Map) {
+	writer, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: databaseType, IncludeReservedNetworks: true})
+	require.NoError(t, err)
+	for cidr, record := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		require.NoError(t, writer.Insert(network, record))
+	}
+
+	// The file is replaced rather than rewritten, as database updates are
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	require.NoError(t, err)
+	_, err = writer.WriteTo(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, os.Rename(tmpPath, path))
+}
+
+// newGeoIPConfig returns a config using a city database in a temporary directory
+func newGeoIPConfig(t *testing.T) *GeoIPConfig {
+	path := filepath.Join(testutil.NewTempDir(t), "city.mmdb")
+	writeDatabase(t, path, "GeoLite2-City", map[string]mmdbtype.Map{
+		"81.2.69.0/24": cityRecord("GB", "United Kingdom", "London"),
+	})
+
+	cfg := NewGeoIPConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Field = entry.NewRecordField("client_ip")
+	cfg.Database = path
+	return cfg
+}
+
+func TestGeoIPTarget(t *testing.T) {
+	cfg := newGeoIPConfig(t)
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	geoIP := ops[0].(*GeoIPOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, geoIP.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, geoIP.Start())
+	defer geoIP.Stop()
+
+	require.NoError(t, geoIP.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"client_ip": "81.2.69.142"}}))
+	require.NoError(t, geoIP.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"client_ip": "81.2.69.142:443"}}))
+	require.NoError(t, geoIP.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"client_ip": "10.0.0.1"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 3)
+
+	expected := map[string]interface{}{
+		"country_code": "GB",
+		"country":      "United Kingdom",
+		"region_code":  "ENG",
+		"region":       "England",
+		"city":         "London",
+		"latitude":     51.5142,
+		"longitude":    -0.0931,
+	}
+	for _, e := range written[:2] {
+		require.Equal(t, expected, e.Record.(map[string]interface{})["geoip"])
+	}
+
+	// Addresses without a record are written unchanged
+	require.Equal(t, map[string]interface{}{"client_ip": "10.0.0.1"}, written[2].Record)
+	require.Equal(t, 2, geoIP.Inspect()["cached_addresses"])
+}
+
+func TestGeoIPValues(t *testing.T) {
+	cfg := newGeoIPConfig(t)
+	asnPath := filepath.Join(testutil.NewTempDir(t), "asn.mmdb")
+	writeDatabase(t, asnPath, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"81.2.69.0/24": {
+			"autonomous_system_number":       mmdbtype.Uint32(20712),
+			"autonomous_system_organization": mmdbtype.String("Andrews & Arnold Ltd"),
+		},
+	})
+	cfg.ASNDatabase = asnPath
+	cfg.Values = map[string]entry.Field{
+		CountryCodeValue: entry.NewLabelField("country"),
+		ASNValue:         entry.NewResourceField("asn"),
+		ASOrgValue:       entry.NewRecordField("network", "org"),
+	}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	geoIP := ops[0].(*GeoIPOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, geoIP.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, geoIP.Start())
+	defer geoIP.Stop()
+
+	require.NoError(t, geoIP.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"client_ip": net.ParseIP("81.2.69.142")}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	e := written[0]
+	require.Equal(t, map[string]string{"country": "GB"}, e.Labels)
+	require.Equal(t, map[string]string{"asn": "20712"}, e.Resource)
+	require.Equal(t, "Andrews & Arnold Ltd", e.Record.(map[string]interface{})["network"].(map[string]interface{})["org"])
+}
+
+func TestGeoIPInvalidAddress(t *testing.T) {
+	cfg := newGeoIPConfig(t)
+	cfg.OnError = helper.DropOnError
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	geoIP := ops[0].(*GeoIPOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, geoIP.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, geoIP.Start())
+	defer geoIP.Stop()
+
+	err = geoIP.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"client_ip": "not an address"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "'not an address' is not an IP address")
+	require.Len(t, fake.ReceivedEntries(), 0)
+}
+
+func TestGeoIPReload(t *testing.T) {
+	cfg := newGeoIPConfig(t)
+	cfg.PollInterval = helper.Duration{Duration: 10 * time.Millisecond}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	geoIP := ops[0].(*GeoIPOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, geoIP.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, geoIP.Start())
+	defer geoIP.Stop()
+
+	first := &entry.Entry{Record: map[string]interface{}{"client_ip": "81.2.69.142"}}
+	require.NoError(t, geoIP.Process(context.Background(), first))
+	fake.ExpectEntry(t, first)
+	require.Equal(t, 1, geoIP.Inspect()["cached_addresses"])
+
+	writeDatabase(t, cfg.Database, "GeoLite2-City", map[string]mmdbtype.Map{
+		"81.2.69.0/24": cityRecord("GB", "United Kingdom", "Manchester"),
+		"10.0.0.0/8":   cityRecord("GB", "United Kingdom", "Leeds"),
+	})
+	require.Eventually(t, func() bool { return geoIP.Inspect()["cached_addresses"] == 0 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, geoIP.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"client_ip": "81.2.69.142"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "Manchester", written[0].Record.(map[string]interface{})["geoip"].(map[string]interface{})["city"])
+}
+
+func TestGeoIPMissingDatabase(t *testing.T) {
+	cfg := newGeoIPConfig(t)
+	cfg.Database = filepath.Join(testutil.NewTempDir(t), "missing.mmdb")
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	err = ops[0].Start()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "open geoip database")
+}
+
+func TestResultCacheGeneration(t *testing.T) {
+	cache := newResultCache(10)
+	values := map[string]interface{}{CityValue: "London"}
+
+	// A result resolved before the cache was cleared is not cached
+	generation := cache.currentGeneration()
+	cache.clear()
+	cache.add("81.2.69.142", values, generation)
+	_, ok := cache.get("81.2.69.142")
+	require.False(t, ok)
+
+	cache.add("81.2.69.142", values, cache.currentGeneration())
+	cached, ok := cache.get("81.2.69.142")
+	require.True(t, ok)
+	require.Equal(t, values, cached)
+}
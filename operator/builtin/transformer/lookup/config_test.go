@@ -0,0 +1,114 @@
+package lookup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "csv",
+			Expect: func() *LookupConfig {
+				cfg := defaultCfg()
+				cfg.Path = "/etc/stanza/owners.csv"
+				cfg.Field = entry.NewRecordField("service")
+				cfg.KeyColumn = "name"
+				return cfg
+			}(),
+		},
+		{
+			Name: "bbolt",
+			Expect: func() *LookupConfig {
+				cfg := defaultCfg()
+				cfg.Path = "/etc/stanza/owners.db"
+				cfg.Format = BboltFormat
+				cfg.Bucket = "owners"
+				cfg.Field = entry.NewLabelField("service")
+				cfg.PollInterval = helper.Duration{Duration: time.Minute}
+				return cfg
+			}(),
+		},
+		{
+			Name: "columns",
+			Expect: func() *LookupConfig {
+				cfg := defaultCfg()
+				cfg.Path = "owners.json"
+				cfg.Field = entry.NewRecordField("service")
+				cfg.Columns = map[string]entry.Field{
+					"team": entry.NewLabelField("team"),
+					"tier": entry.NewRecordField("owner", "tier"),
+				}
+				return cfg
+			}(),
+		},
+		{
+			Name: "defaults",
+			Expect: func() *LookupConfig {
+				cfg := defaultCfg()
+				cfg.Path = "owners.json"
+				cfg.Field = entry.NewRecordField("service")
+				cfg.OnMiss = DefaultOnMiss
+				cfg.Defaults = map[string]interface{}{"team": "unknown", "tier": 3}
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "path_missing",
+			ExpectErr: "missing required field 'path'",
+		},
+		{
+			Name:      "field_missing",
+			ExpectErr: "missing required field 'field'",
+		},
+		{
+			Name:      "format_unknown",
+			ExpectErr: "could not infer the format of 'owners.txt'",
+		},
+		{
+			Name:      "format_invalid",
+			ExpectErr: "invalid format 'xml'",
+		},
+		{
+			Name:      "bucket_missing",
+			ExpectErr: "bucket is required with format 'bbolt'",
+		},
+		{
+			Name:      "on_miss_invalid",
+			ExpectErr: "invalid on_miss 'skip'",
+		},
+		{
+			Name:      "defaults_missing",
+			ExpectErr: "defaults are required with on_miss 'default'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *LookupConfig {
+	return NewLookupConfig("lookup")
+}
@@ -0,0 +1,334 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("lookup", func() operator.Builder { return NewLookupConfig("") })
+}
+
+const (
+	// IgnoreOnMiss writes entries that do not match a row unchanged
+	IgnoreOnMiss = "ignore"
+	// DefaultOnMiss copies the default values to entries that do not match a row
+	DefaultOnMiss = "default"
+	// DropOnMiss drops entries that do not match a row
+	DropOnMiss = "drop"
+	// ErrorOnMiss handles entries that do not match a row as errors, according to on_error
+	ErrorOnMiss = "error"
+)
+
+// NewLookupConfig creates a new lookup config with default values
+func NewLookupConfig(operatorID string) *LookupConfig {
+	return &LookupConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "lookup"),
+		OnMiss:            IgnoreOnMiss,
+		PollInterval:      helper.Duration{Duration: 10 * time.Second},
+	}
+}
+
+// LookupConfig is the configuration of a lookup operator
+type LookupConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	Path         string                 `json:"path,omitempty"          yaml:"path,omitempty"`
+	Format       string                 `json:"format,omitempty"        yaml:"format,omitempty"`
+	KeyColumn    string                 `json:"key_column,omitempty"    yaml:"key_column,omitempty"`
+	Bucket       string                 `json:"bucket,omitempty"        yaml:"bucket,omitempty"`
+	Field        entry.Field            `json:"field,omitempty"         yaml:"field,omitempty"`
+	Columns      map[string]entry.Field `json:"columns,omitempty"       yaml:"columns,omitempty"`
+	OnMiss       string                 `json:"on_miss,omitempty"       yaml:"on_miss,omitempty"`
+	Defaults     map[string]interface{} `json:"defaults,omitempty"      yaml:"defaults,omitempty"`
+	PollInterval helper.Duration        `json:"poll_interval,omitempty" yaml:"poll_interval,omitempty"`
+}
+
+// Build will build a lookup operator
+func (c LookupConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Path == "":
+		return nil, fmt.Errorf("missing required field 'path'")
+	case c.Field.FieldInterface == nil:
+		return nil, fmt.Errorf("missing required field 'field'")
+	case c.PollInterval.Raw() < 0:
+		return nil, fmt.Errorf("poll_interval must not be negative")
+	}
+
+	format := c.Format
+	if format == "" {
+		if format, err = inferFormat(c.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case CSVFormat, JSONFormat:
+	case BboltFormat:
+		if c.Bucket == "" {
+			return nil, fmt.Errorf("bucket is required with format '%s'", BboltFormat)
+		}
+	default:
+		return nil, fmt.Errorf("invalid format '%s', must be one of '%s', '%s' or '%s'", format, CSVFormat, JSONFormat, BboltFormat)
+	}
+
+	switch c.OnMiss {
+	case IgnoreOnMiss, DropOnMiss, ErrorOnMiss:
+	case DefaultOnMiss:
+		if len(c.Defaults) == 0 {
+			return nil, fmt.Errorf("defaults are required with on_miss '%s'", DefaultOnMiss)
+		}
+	default:
+		return nil, fmt.Errorf("invalid on_miss '%s', must be one of '%s', '%s', '%s' or '%s'", c.OnMiss, IgnoreOnMiss, DefaultOnMiss, DropOnMiss, ErrorOnMiss)
+	}
+
+	lookupOperator := &LookupOperator{
+		TransformerOperator: transformerOperator,
+		source: tableSource{
+			path:      c.Path,
+			format:    format,
+			keyColumn: c.KeyColumn,
+			bucket:    c.Bucket,
+		},
+		field:        c.Field,
+		columns:      c.Columns,
+		onMiss:       c.OnMiss,
+		defaults:     c.Defaults,
+		pollInterval: c.PollInterval.Raw(),
+	}
+
+	return []operator.Operator{lookupOperator}, nil
+}
+
+// LookupOperator is an operator that enriches entries with the columns of the row
+// of a table that matches a field
+type LookupOperator struct {
+	helper.TransformerOperator
+	source       tableSource
+	field        entry.Field
+	columns      map[string]entry.Field
+	onMiss       string
+	defaults     map[string]interface{}
+	pollInterval time.Duration
+
+	mux       sync.RWMutex
+	table     table
+	modTime   time.Time
+	size      int64
+	loadedAt  time.Time
+	loadError error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start will load the table, and start polling it for changes
+func (l *LookupOperator) Start() error {
+	if err := l.reload(); err != nil {
+		return fmt.Errorf("load lookup table: %s", err)
+	}
+
+	if l.pollInterval == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.wg.Add(1)
+	go l.pollTable(ctx)
+	return nil
+}
+
+// Stop will stop polling the table
+func (l *LookupOperator) Stop() error {
+	if l.cancel != nil {
+		l.cancel()
+		l.wg.Wait()
+	}
+	return nil
+}
+
+// Process will copy the columns of the row matching the entry to the entry
+func (l *LookupOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	skip, err := l.Skip(ctx, entry)
+	if err != nil {
+		return l.HandleEntryError(ctx, entry, err)
+	}
+	if skip {
+		l.Write(ctx, entry)
+		return nil
+	}
+
+	var row map[string]interface{}
+	value, ok := entry.Get(l.field)
+	if ok {
+		l.mux.RLock()
+		row, ok = l.table[formatValue(value)]
+		l.mux.RUnlock()
+	}
+
+	if !ok {
+		switch l.onMiss {
+		case DropOnMiss:
+			l.RecordDropped()
+			return nil
+		case ErrorOnMiss:
+			return l.HandleEntryError(ctx, entry, fmt.Errorf("no row matches '%v'", value))
+		case DefaultOnMiss:
+			row = l.defaults
+		default:
+			l.Write(ctx, entry)
+			return nil
+		}
+	}
+
+	if err := l.copyColumns(entry, row); err != nil {
+		return l.HandleEntryError(ctx, entry, err)
+	}
+	l.Write(ctx, entry)
+	return nil
+}
+
+// copyColumns copies the columns of a row to the fields they are mapped to. If no
+// columns are mapped, every column is copied to the label of the same name.
+func (l *LookupOperator) copyColumns(e *entry.Entry, row map[string]interface{}) error {
+	if len(l.columns) == 0 {
+		for column, value := range row {
+			e.AddLabel(column, formatValue(value))
+		}
+		return nil
+	}
+
+	for column, field := range l.columns {
+		value, ok := row[column]
+		if !ok {
+			continue
+		}
+
+		switch field.FieldInterface.(type) {
+		case entry.LabelField, entry.ResourceField:
+			// Labels and resource values must be strings
+			value = formatValue(value)
+		default:
+			// The row is shared by every entry that matches it
+			value = copyValue(value)
+		}
+		if err := e.Set(field, value); err != nil {
+			return fmt.Errorf("copy column '%s': %s", column, err)
+		}
+	}
+	return nil
+}
+
+// copyValue deep copies the maps and slices of a column value
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case map[interface{}]interface{}:
+		copied := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// pollTable reloads the table when its file changes, until the context is done
+func (l *LookupOperator) pollTable(ctx context.Context) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(l.source.path)
+		if err != nil {
+			l.Warnw("Failed to check lookup table", zap.Any("error", err))
+			continue
+		}
+
+		l.mux.RLock()
+		changed := !info.ModTime().Equal(l.modTime) || info.Size() != l.size
+		l.mux.RUnlock()
+		if !changed {
+			continue
+		}
+
+		// The previous table is kept if the new one cannot be loaded
+		if err := l.reload(); err != nil {
+			l.Warnw("Failed to reload lookup table", zap.Any("error", err))
+			continue
+		}
+		l.Infow("Reloaded lookup table", "path", l.source.path)
+	}
+}
+
+// reload loads the table from its file, and replaces the current table if it
+// loads successfully
+func (l *LookupOperator) reload() error {
+	info, err := os.Stat(l.source.path)
+	if err != nil {
+		return err
+	}
+
+	t, err := l.source.load()
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	// The state is recorded even on failure, so that a broken file is only
+	// retried once it changes again
+	l.modTime, l.size, l.loadError = info.ModTime(), info.Size(), err
+	if err != nil {
+		return err
+	}
+	l.table, l.loadedAt = t, time.Now()
+	return nil
+}
+
+// Inspect reports the number of rows in the table, when it was loaded, and the
+// error of the last attempt to load it
+func (l *LookupOperator) Inspect() map[string]interface{} {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	details := map[string]interface{}{
+		"rows":      len(l.table),
+		"loaded_at": l.loadedAt,
+	}
+	if l.loadError != nil {
+		details["load_error"] = l.loadError.Error()
+	}
+	return details
+}
@@ -0,0 +1,320 @@
+package lookup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+const ownersCSV = `service,team,tier
+checkout,payments,1
+search,discovery,2
+`
+
+// writeTable writes a table file in a temporary directory, returning its path
+func writeTable(t *testing.T, name, contents string) string {
+	path := filepath.Join(testutil.NewTempDir(t), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func newLookupConfig(path string) *LookupConfig {
+	cfg := NewLookupConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Path = path
+	cfg.Field = entry.NewRecordField("service")
+	return cfg
+}
+
+func TestLookupCSV(t *testing.T) {
+	cfg := newLookupConfig(writeTable(t, "owners.csv", ownersCSV))
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "checkout"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, map[string]string{"team": "payments", "tier": "1"}, written[0].Labels)
+	require.Equal(t, 2, lookup.Inspect()["rows"])
+}
+
+func TestLookupColumns(t *testing.T) {
+	cfg := newLookupConfig(writeTable(t, "owners.json", `[
+		{"service": "checkout", "team": "payments", "tier": 1},
+		{"service": "search", "team": "discovery", "tier": 2}
+	]`))
+	cfg.KeyColumn = "service"
+	cfg.Columns = map[string]entry.Field{
+		"team": entry.NewResourceField("team"),
+		"tier": entry.NewRecordField("tier"),
+	}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "search"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	e := written[0]
+	require.Equal(t, map[string]string{"team": "discovery"}, e.Resource)
+	require.Equal(t, map[string]interface{}{"service": "search", "tier": 2.0}, e.Record)
+}
+
+func TestLookupColumnsCopied(t *testing.T) {
+	cfg := newLookupConfig(writeTable(t, "owners.json", `{
+		"checkout": {"owner": {"team": "payments", "oncall": ["alice", "bob"]}}
+	}`))
+	cfg.Columns = map[string]entry.Field{"owner": entry.NewRecordField("owner")}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	// Modifying the value copied to one entry does not modify the table
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "checkout"}}))
+	first := fake.ReceivedEntries()[0].Record.(map[string]interface{})["owner"].(map[string]interface{})
+	first["team"] = "modified"
+	first["oncall"].([]interface{})[0] = "mallory"
+
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "checkout"}}))
+	second := fake.ReceivedEntries()[0].Record.(map[string]interface{})["owner"]
+	require.Equal(t, map[string]interface{}{"team": "payments", "oncall": []interface{}{"alice", "bob"}}, second)
+}
+
+func TestLookupDuplicateKeys(t *testing.T) {
+	cases := []struct {
+		name      string
+		file      string
+		contents  string
+		keyColumn string
+		expected  string
+	}{
+		{
+			"CSV",
+			"owners.csv",
+			"service,team\ncheckout,payments\ncheckout,billing\n",
+			"",
+			"duplicate key 'checkout'",
+		},
+		{
+			"JSONArray",
+			"owners.json",
+			`[{"service": "checkout", "team": "payments"}, {"service": "checkout", "team": "billing"}]`,
+			"service",
+			"row 1 has duplicate key 'checkout'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newLookupConfig(writeTable(t, tc.file, tc.contents))
+			cfg.KeyColumn = tc.keyColumn
+			ops, err := cfg.Build(testutil.NewBuildContext(t))
+			require.NoError(t, err)
+			err = ops[0].Start()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestLookupNumericKeys(t *testing.T) {
+	cfg := newLookupConfig(writeTable(t, "accounts.json", `[
+		{"account": 1000000, "owner": "payments", "limit": 2500000}
+	]`))
+	cfg.KeyColumn = "account"
+	cfg.Field = entry.NewRecordField("account")
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	// Numbers are matched and copied to labels without an exponent
+	for _, account := range []interface{}{"1000000", 1000000.0, 1000000} {
+		e := entry.New()
+		e.Record = map[string]interface{}{"account": account}
+		require.NoError(t, lookup.Process(context.Background(), e))
+	}
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 3)
+	for _, e := range written {
+		require.Equal(t, map[string]string{"owner": "payments", "limit": "2500000"}, e.Labels)
+	}
+}
+
+func TestLookupJSONObject(t *testing.T) {
+	cfg := newLookupConfig(writeTable(t, "owners.json", `{
+		"checkout": {"team": "payments"}
+	}`))
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "checkout"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "payments", written[0].Labels["team"])
+}
+
+func TestLookupBbolt(t *testing.T) {
+	path := filepath.Join(testutil.NewTempDir(t), "owners.db")
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("owners"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("checkout"), []byte(`{"team": "payments"}`))
+	}))
+	require.NoError(t, db.Close())
+
+	cfg := newLookupConfig(path)
+	cfg.Bucket = "owners"
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "checkout"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "payments", written[0].Labels["team"])
+}
+
+func TestLookupOnMiss(t *testing.T) {
+	path := writeTable(t, "owners.csv", ownersCSV)
+
+	t.Run("Ignore", func(t *testing.T) {
+		cfg := newLookupConfig(path)
+		ops, err := cfg.Build(testutil.NewBuildContext(t))
+		require.NoError(t, err)
+		lookup := ops[0].(*LookupOperator)
+		fake := testutil.NewFakeOutput(t)
+		require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+		require.NoError(t, lookup.Start())
+		defer lookup.Stop()
+
+		require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "unknown"}}))
+		written := fake.ReceivedEntries()
+		require.Len(t, written, 1)
+		require.Nil(t, written[0].Labels)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		cfg := newLookupConfig(path)
+		cfg.OnMiss = DefaultOnMiss
+		cfg.Defaults = map[string]interface{}{"team": "unowned"}
+		ops, err := cfg.Build(testutil.NewBuildContext(t))
+		require.NoError(t, err)
+		lookup := ops[0].(*LookupOperator)
+		fake := testutil.NewFakeOutput(t)
+		require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+		require.NoError(t, lookup.Start())
+		defer lookup.Stop()
+
+		require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "unknown"}}))
+		written := fake.ReceivedEntries()
+		require.Len(t, written, 1)
+		require.Equal(t, "unowned", written[0].Labels["team"])
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		cfg := newLookupConfig(path)
+		cfg.OnMiss = DropOnMiss
+		ops, err := cfg.Build(testutil.NewBuildContext(t))
+		require.NoError(t, err)
+		lookup := ops[0].(*LookupOperator)
+		fake := testutil.NewFakeOutput(t)
+		require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+		require.NoError(t, lookup.Start())
+		defer lookup.Stop()
+
+		require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "unknown"}}))
+		require.NoError(t, lookup.Process(context.Background(), entry.New()))
+		require.Len(t, fake.ReceivedEntries(), 0)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		cfg := newLookupConfig(path)
+		cfg.OnMiss = ErrorOnMiss
+		cfg.OnError = helper.DropOnError
+		ops, err := cfg.Build(testutil.NewBuildContext(t))
+		require.NoError(t, err)
+		lookup := ops[0].(*LookupOperator)
+		fake := testutil.NewFakeOutput(t)
+		require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+		require.NoError(t, lookup.Start())
+		defer lookup.Stop()
+
+		err = lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "unknown"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no row matches 'unknown'")
+		require.Len(t, fake.ReceivedEntries(), 0)
+	})
+}
+
+func TestLookupReload(t *testing.T) {
+	path := writeTable(t, "owners.csv", ownersCSV)
+	cfg := newLookupConfig(path)
+	cfg.PollInterval = helper.Duration{Duration: 10 * time.Millisecond}
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	lookup := ops[0].(*LookupOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, lookup.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, lookup.Start())
+	defer lookup.Stop()
+
+	require.NoError(t, os.WriteFile(path, []byte("service,team\ncheckout,billing\n"), 0600))
+	require.Eventually(t, func() bool { return lookup.Inspect()["rows"] == 1 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, lookup.Process(context.Background(), &entry.Entry{Record: map[string]interface{}{"service": "checkout"}}))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, map[string]string{"team": "billing"}, written[0].Labels)
+
+	// A table that fails to load does not replace the current one
+	require.NoError(t, os.WriteFile(path, []byte("service,team\n\"unclosed\n"), 0600))
+	require.Eventually(t, func() bool { return lookup.Inspect()["load_error"] != nil }, time.Second, 5*time.Millisecond)
+	require.Equal(t, 1, lookup.Inspect()["rows"])
+}
+
+func TestLookupMissingFile(t *testing.T) {
+	cfg := newLookupConfig(filepath.Join(testutil.NewTempDir(t), "owners.csv"))
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	err = ops[0].Start()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "load lookup table")
+}
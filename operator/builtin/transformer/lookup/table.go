@@ -0,0 +1,189 @@
+package lookup
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// CSVFormat is a CSV file with a header row naming the columns
+	CSVFormat = "csv"
+	// JSONFormat is a JSON array of objects, or an object of objects keyed by row
+	JSONFormat = "json"
+	// BboltFormat is a bucket in a bbolt database, with a JSON object for each key
+	BboltFormat = "bbolt"
+)
+
+// table maps each key to the columns of its row
+type table map[string]map[string]interface{}
+
+// tableSource describes where a table is loaded from
+type tableSource struct {
+	path      string
+	format    string
+	keyColumn string
+	bucket    string
+}
+
+// inferFormat returns the format of a table file from its extension
+func inferFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSVFormat, nil
+	case ".json":
+		return JSONFormat, nil
+	case ".db", ".bolt", ".bbolt":
+		return BboltFormat, nil
+	default:
+		return "", fmt.Errorf("could not infer the format of '%s', format must be one of '%s', '%s' or '%s'", path, CSVFormat, JSONFormat, BboltFormat)
+	}
+}
+
+// load reads the table from its source
+func (s tableSource) load() (table, error) {
+	if s.format == BboltFormat {
+		return loadBboltTable(s.path, s.bucket)
+	}
+
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if s.format == CSVFormat {
+		return loadCSVTable(bytes.NewReader(contents), s.keyColumn)
+	}
+	return loadJSONTable(contents, s.keyColumn)
+}
+
+// loadCSVTable reads a CSV table. The first row names the columns, and rows are
+// keyed by the key column, or by the first column if it is not set. Keys must be
+// unique, since only one row could be used for a duplicated key.
+func loadCSVTable(r io.Reader, keyColumn string) (table, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %s", err)
+	}
+
+	keyIndex := 0
+	if keyColumn != "" {
+		keyIndex = -1
+		for i, column := range header {
+			if column == keyColumn {
+				keyIndex = i
+			}
+		}
+		if keyIndex < 0 {
+			return nil, fmt.Errorf("key column '%s' is not in the header", keyColumn)
+		}
+	}
+
+	t := make(table)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		key := record[keyIndex]
+		if _, ok := t[key]; ok {
+			return nil, fmt.Errorf("duplicate key '%s'", key)
+		}
+
+		row := make(map[string]interface{}, len(header)-1)
+		for i, column := range header {
+			if i != keyIndex {
+				row[column] = record[i]
+			}
+		}
+		t[key] = row
+	}
+}
+
+// loadJSONTable reads a JSON table. An array of objects is keyed by the key column,
+// which must be unique, and an object of objects is keyed by its keys.
+func loadJSONTable(contents []byte, keyColumn string) (table, error) {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(contents, &rows); err == nil {
+		if keyColumn == "" {
+			return nil, fmt.Errorf("key_column is required for an array of rows")
+		}
+
+		t := make(table, len(rows))
+		for i, row := range rows {
+			key, ok := row[keyColumn]
+			if !ok {
+				return nil, fmt.Errorf("row %d is missing key column '%s'", i, keyColumn)
+			}
+			if _, ok := t[formatValue(key)]; ok {
+				return nil, fmt.Errorf("row %d has duplicate key '%s'", i, formatValue(key))
+			}
+			delete(row, keyColumn)
+			t[formatValue(key)] = row
+		}
+		return t, nil
+	}
+
+	t := make(table)
+	if err := json.Unmarshal(contents, &t); err != nil {
+		return nil, fmt.Errorf("must be an array of objects or an object of objects: %s", err)
+	}
+	return t, nil
+}
+
+// formatValue formats a key or column value as a string. Numbers are written without
+// an exponent, so that a JSON number such as 1000000 matches the string "1000000".
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// loadBboltTable reads a table from a bucket of a bbolt database, where each value
+// is a JSON object of the row's columns
+func loadBboltTable(path, bucket string) (table, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	t := make(table)
+	err = db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return fmt.Errorf("bucket '%s' does not exist", bucket)
+		}
+		return b.ForEach(func(key, value []byte) error {
+			row := make(map[string]interface{})
+			if err := json.Unmarshal(value, &row); err != nil {
+				return fmt.Errorf("row '%s': %s", key, err)
+			}
+			t[string(key)] = row
+			return nil
+		})
+	})
+	return t, err
+}
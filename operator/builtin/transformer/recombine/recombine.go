@@ -125,6 +125,19 @@ func (r *RecombineOperator) Stop() error {
 	return nil
 }
 
+// Drain flushes the current batch as a single combined entry, so that a partially
+// received log is not split up when the pipeline shuts down
+func (r *RecombineOperator) Drain(ctx context.Context) int {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.flushCombined(); err != nil {
+		r.Errorw("Failed to combine batch while draining", "error", err)
+		r.flushUncombined(ctx)
+	}
+	return 0
+}
+
 // Process processes a log entry to be combined
 func (r *RecombineOperator) Process(ctx context.Context, e *entry.Entry) error {
 	// Lock the recombine operator because process can't run concurrently
@@ -170,4 +170,36 @@ func TestRecombineOperator(t *testing.T) {
 			require.FailNow(t, "Entry was not flushed on shutdown")
 		}
 	})
+	t.Run("CombinesOnDrain", func(t *testing.T) {
+		cfg := NewRecombineOperatorConfig("")
+		cfg.CombineField = entry.NewRecordField()
+		cfg.IsFirstEntry = "false"
+		cfg.OutputIDs = []string{"fake"}
+		ops, err := cfg.Build(testutil.NewBuildContext(t))
+		require.NoError(t, err)
+		recombine := ops[0].(*RecombineOperator)
+
+		fake := testutil.NewFakeOutput(t)
+		err = recombine.SetOutputs([]operator.Operator{fake})
+		require.NoError(t, err)
+
+		recombine.Process(context.Background(), entryWithRecord(t1, "test1"))
+		recombine.Process(context.Background(), entryWithRecord(t2, "test2"))
+
+		require.Equal(t, 0, recombine.Drain(context.Background()))
+
+		// Ensure that the batch is flushed as a single entry
+		select {
+		case e := <-fake.Received:
+			require.Equal(t, "test1\ntest2", e.Record)
+		default:
+			require.FailNow(t, "Batch was not flushed on drain")
+		}
+
+		select {
+		case e := <-fake.Received:
+			require.FailNow(t, "Received unexpected entry: ", e)
+		default:
+		}
+	})
 }
@@ -0,0 +1,115 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "detectors",
+			Expect: func() *RedactConfig {
+				cfg := defaultCfg()
+				cfg.Fields = []entry.Field{entry.NewRecordField("message"), entry.NewLabelField("user")}
+				cfg.Detectors = []string{EmailDetector, IPv4Detector}
+				return cfg
+			}(),
+		},
+		{
+			Name: "patterns",
+			Expect: func() *RedactConfig {
+				cfg := defaultCfg()
+				cfg.Detectors = []string{}
+				cfg.Patterns = []PatternConfig{{Name: "employee_id", Regex: `E\d{6}`}}
+				return cfg
+			}(),
+		},
+		{
+			Name: "mask",
+			Expect: func() *RedactConfig {
+				cfg := defaultCfg()
+				cfg.Action = MaskAction
+				cfg.MaskChar = "#"
+				cfg.KeepLast = 2
+				return cfg
+			}(),
+		},
+		{
+			Name: "hash",
+			Expect: func() *RedactConfig {
+				cfg := defaultCfg()
+				cfg.Action = HashAction
+				cfg.HashKey = "secret"
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "detector_unknown",
+			ExpectErr: "unknown detector 'phone'",
+		},
+		{
+			Name:      "detector_duplicate",
+			ExpectErr: "detector 'email' is listed more than once",
+		},
+		{
+			Name:      "detectors_empty",
+			ExpectErr: "at least one detector or pattern is required",
+		},
+		{
+			Name:      "pattern_name_missing",
+			ExpectErr: "patterns must have a name",
+		},
+		{
+			Name:      "pattern_invalid",
+			ExpectErr: "compiling regex of pattern 'id'",
+		},
+		{
+			Name:      "pattern_name_duplicate",
+			ExpectErr: "pattern 'email' has the same name as another detector",
+		},
+		{
+			Name:      "mask_char_invalid",
+			ExpectErr: "mask_char must be a single character",
+		},
+		{
+			Name:      "keep_last_negative",
+			ExpectErr: "keep_last must not be negative",
+		},
+		{
+			Name:      "hash_key_missing",
+			ExpectErr: "hash_key is required with action 'hash'",
+		},
+		{
+			Name:      "action_invalid",
+			ExpectErr: "invalid action 'encrypt'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *RedactConfig {
+	return NewRedactConfig("redact")
+}
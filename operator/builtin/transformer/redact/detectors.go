@@ -0,0 +1,127 @@
+package redact
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The names of the built-in detectors
+const (
+	EmailDetector       = "email"
+	CreditCardDetector  = "credit_card"
+	IPv4Detector        = "ipv4"
+	IPv6Detector        = "ipv6"
+	BearerTokenDetector = "bearer_token"
+)
+
+// detector finds sensitive values in strings. If its regex has a capture group,
+// only the first group of each match is redacted.
+type detector struct {
+	name  string
+	regex *regexp.Regexp
+
+	// valid, if set, filters out matches that are not sensitive values
+	valid func(string) bool
+}
+
+var builtinDetectors = map[string]*detector{
+	EmailDetector: {
+		name:  EmailDetector,
+		regex: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`),
+	},
+	CreditCardDetector: {
+		name:  CreditCardDetector,
+		regex: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		valid: luhnValid,
+	},
+	IPv4Detector: {
+		name:  IPv4Detector,
+		regex: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+	},
+	IPv6Detector: {
+		name:  IPv6Detector,
+		regex: regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`),
+		valid: func(s string) bool {
+			// Times and MAC addresses also match the pattern, but are not addresses
+			ip := net.ParseIP(s)
+			return ip != nil && ip.To4() == nil
+		},
+	},
+	BearerTokenDetector: {
+		name:  BearerTokenDetector,
+		regex: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	},
+}
+
+// builtinDetectorNames returns the names of the built-in detectors in order
+func builtinDetectorNames() []string {
+	names := make([]string, 0, len(builtinDetectors))
+	for name := range builtinDetectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newDetectors returns the built-in detectors with the supplied names, followed by
+// a detector for each custom pattern
+func newDetectors(names []string, patterns []PatternConfig) ([]*detector, error) {
+	detectors := make([]*detector, 0, len(names)+len(patterns))
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		d, ok := builtinDetectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector '%s', must be one of %s", name, strings.Join(builtinDetectorNames(), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("detector '%s' is listed more than once", name)
+		}
+		seen[name] = true
+		detectors = append(detectors, d)
+	}
+
+	for _, pattern := range patterns {
+		switch {
+		case pattern.Name == "":
+			return nil, fmt.Errorf("patterns must have a name")
+		case seen[pattern.Name]:
+			return nil, fmt.Errorf("pattern '%s' has the same name as another detector", pattern.Name)
+		}
+		seen[pattern.Name] = true
+
+		regex, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("compiling regex of pattern '%s': %s", pattern.Name, err)
+		}
+		detectors = append(detectors, &detector{name: pattern.Name, regex: regex})
+	}
+
+	return detectors, nil
+}
+
+// luhnValid returns true if the digits of the string pass the Luhn checksum used
+// by payment card numbers
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
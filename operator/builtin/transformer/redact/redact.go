@@ -0,0 +1,252 @@
+package redact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+)
+
+func init() {
+	operator.Register("redact", func() operator.Builder { return NewRedactConfig("") })
+}
+
+const (
+	// ReplaceAction replaces sensitive values with a fixed string
+	ReplaceAction = "replace"
+	// MaskAction replaces the characters of sensitive values, except for the last few
+	MaskAction = "mask"
+	// HashAction replaces sensitive values with their keyed HMAC-SHA256 hash
+	HashAction = "hash"
+
+	// hashLength is the number of bytes of the hash that are kept
+	hashLength = 16
+)
+
+// NewRedactConfig creates a new redact config with default values
+func NewRedactConfig(operatorID string) *RedactConfig {
+	return &RedactConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "redact"),
+		Detectors:         builtinDetectorNames(),
+		Action:            ReplaceAction,
+		Replacement:       "[REDACTED]",
+		MaskChar:          "*",
+		KeepLast:          4,
+	}
+}
+
+// RedactConfig is the configuration of a redact operator
+type RedactConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	Fields      []entry.Field   `json:"fields,omitempty"      yaml:"fields,omitempty"`
+	Detectors   []string        `json:"detectors"             yaml:"detectors"`
+	Patterns    []PatternConfig `json:"patterns,omitempty"    yaml:"patterns,omitempty"`
+	Action      string          `json:"action,omitempty"      yaml:"action,omitempty"`
+	Replacement string          `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	MaskChar    string          `json:"mask_char,omitempty"   yaml:"mask_char,omitempty"`
+	KeepLast    int             `json:"keep_last,omitempty"   yaml:"keep_last,omitempty"`
+	HashKey     string          `json:"hash_key,omitempty"    yaml:"hash_key,omitempty"`
+}
+
+// PatternConfig is a custom regex that detects sensitive values
+type PatternConfig struct {
+	Name  string `json:"name"  yaml:"name"`
+	Regex string `json:"regex" yaml:"regex"`
+}
+
+// Build will build a redact operator
+func (c RedactConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	detectors, err := newDetectors(c.Detectors, c.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	if len(detectors) == 0 {
+		return nil, fmt.Errorf("at least one detector or pattern is required")
+	}
+
+	switch c.Action {
+	case ReplaceAction:
+	case MaskAction:
+		switch {
+		case len([]rune(c.MaskChar)) != 1:
+			return nil, fmt.Errorf("mask_char must be a single character")
+		case c.KeepLast < 0:
+			return nil, fmt.Errorf("keep_last must not be negative")
+		}
+	case HashAction:
+		if c.HashKey == "" {
+			return nil, fmt.Errorf("hash_key is required with action '%s'", HashAction)
+		}
+	default:
+		return nil, fmt.Errorf("invalid action '%s', must be one of '%s', '%s' or '%s'", c.Action, ReplaceAction, MaskAction, HashAction)
+	}
+
+	redactOperator := &RedactOperator{
+		TransformerOperator: transformerOperator,
+		fields:              c.Fields,
+		detectors:           detectors,
+		action:              c.Action,
+		replacement:         c.Replacement,
+		maskChar:            c.MaskChar,
+		keepLast:            c.KeepLast,
+		hashKey:             []byte(c.HashKey),
+		counts:              make(map[string]*int64, len(detectors)),
+	}
+	for _, d := range detectors {
+		redactOperator.counts[d.name] = new(int64)
+	}
+
+	return []operator.Operator{redactOperator}, nil
+}
+
+// RedactOperator is an operator that redacts sensitive values from entries
+type RedactOperator struct {
+	helper.TransformerOperator
+	fields      []entry.Field
+	detectors   []*detector
+	action      string
+	replacement string
+	maskChar    string
+	keepLast    int
+	hashKey     []byte
+
+	// counts is the number of values redacted by each detector
+	counts map[string]*int64
+}
+
+// Process will redact sensitive values from the entry
+func (r *RedactOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	return r.ProcessWith(ctx, entry, r.Transform)
+}
+
+// Transform will redact sensitive values from the configured fields of the entry,
+// or from every string in its record if no fields are configured
+func (r *RedactOperator) Transform(e *entry.Entry) error {
+	if len(r.fields) == 0 {
+		e.Record = r.redactValue(e.Record)
+		return nil
+	}
+
+	for _, field := range r.fields {
+		value, ok := e.Get(field)
+		if !ok {
+			continue
+		}
+		if err := e.Set(field, r.redactValue(value)); err != nil {
+			return fmt.Errorf("set redacted value of %s: %s", field, err)
+		}
+	}
+	return nil
+}
+
+// redactValue redacts the strings in a value, recursing through maps and slices
+func (r *RedactOperator) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case []byte:
+		return r.redactString(string(v))
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = r.redactValue(nested)
+		}
+		return v
+	case map[string]string:
+		for key, nested := range v {
+			v[key] = r.redactString(nested)
+		}
+		return v
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = r.redactValue(nested)
+		}
+		return v
+	case []string:
+		for i, nested := range v {
+			v[i] = r.redactString(nested)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// redactString applies each detector to a string in turn
+func (r *RedactOperator) redactString(s string) string {
+	for _, d := range r.detectors {
+		s = r.applyDetector(d, s)
+	}
+	return s
+}
+
+// applyDetector redacts the values found by a detector in a string
+func (r *RedactOperator) applyDetector(d *detector, s string) string {
+	matches := d.regex.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		// Only the first capture group is redacted, if the regex has one
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+
+		value := s[start:end]
+		if d.valid != nil && !d.valid(value) {
+			continue
+		}
+
+		b.WriteString(s[last:start])
+		b.WriteString(r.redact(value))
+		last = end
+		atomic.AddInt64(r.counts[d.name], 1)
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// redact returns the replacement of a sensitive value
+func (r *RedactOperator) redact(value string) string {
+	switch r.action {
+	case MaskAction:
+		runes := []rune(value)
+		keep := r.keepLast
+		if keep >= len(runes) {
+			// Short values are masked entirely, rather than left unmasked
+			keep = 0
+		}
+		return strings.Repeat(r.maskChar, len(runes)-keep) + string(runes[len(runes)-keep:])
+	case HashAction:
+		mac := hmac.New(sha256.New, r.hashKey)
+		_, _ = mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:hashLength])
+	default:
+		return r.replacement
+	}
+}
+
+// Inspect reports the number of values redacted by each detector
+func (r *RedactOperator) Inspect() map[string]interface{} {
+	redacted := make(map[string]interface{}, len(r.counts))
+	for name, count := range r.counts {
+		redacted[name] = atomic.LoadInt64(count)
+	}
+	return map[string]interface{}{"redacted": redacted}
+}
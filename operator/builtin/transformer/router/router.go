@@ -28,6 +28,9 @@ type RouterOperatorConfig struct {
 	helper.BasicConfig `yaml:",inline"`
 	Routes             []*RouterOperatorRouteConfig `json:"routes" yaml:"routes"`
 	Default            helper.OutputIDs             `json:"default" yaml:"default"`
+
+	// Fanout is rejected, since a router writes to the outputs of its routes directly
+	Fanout *helper.FanoutConfig `json:"fanout,omitempty" yaml:"fanout,omitempty"`
 }
 
 // RouterOperatorRouteConfig is the configuration of a route on a router operator
@@ -44,6 +47,10 @@ func (c RouterOperatorConfig) Build(bc operator.BuildContext) ([]operator.Operat
 		return nil, err
 	}
 
+	if c.Fanout != nil {
+		return nil, fmt.Errorf("fanout is not supported by the router operator")
+	}
+
 	if c.Default != nil {
 		defaultRoute := &RouterOperatorRouteConfig{
 			Expression: "true",
@@ -217,3 +217,13 @@ func TestRouterOperator(t *testing.T) {
 		})
 	}
 }
+
+func TestRouterOperatorRejectsFanout(t *testing.T) {
+	cfg := NewRouterOperatorConfig("test_operator_id")
+	cfg.Default = helper.OutputIDs{"output1"}
+	cfg.Fanout = &helper.FanoutConfig{Mode: helper.AsyncFanout}
+
+	_, err := cfg.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fanout is not supported")
+}
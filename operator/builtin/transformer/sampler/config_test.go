@@ -0,0 +1,107 @@
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "ratio",
+			Expect: func() *SamplerConfig {
+				cfg := defaultCfg()
+				cfg.Ratio = 0.25
+				return cfg
+			}(),
+		},
+		{
+			Name: "hash_field",
+			Expect: func() *SamplerConfig {
+				cfg := defaultCfg()
+				cfg.Ratio = 0.1
+				field := entry.NewRecordField("trace_id")
+				cfg.HashField = &field
+				return cfg
+			}(),
+		},
+		{
+			Name: "max_per_interval",
+			Expect: func() *SamplerConfig {
+				cfg := defaultCfg()
+				cfg.MaxPerInterval = 100
+				cfg.Interval = helper.Duration{Duration: 10 * time.Second}
+				field := entry.NewRecordField("service")
+				cfg.KeyField = &field
+				return cfg
+			}(),
+		},
+		{
+			Name: "keep_severity",
+			Expect: func() *SamplerConfig {
+				cfg := defaultCfg()
+				cfg.Ratio = 0.1
+				cfg.KeepSeverity = "error"
+				return cfg
+			}(),
+		},
+		{
+			Name: "if",
+			Expect: func() *SamplerConfig {
+				cfg := defaultCfg()
+				cfg.Ratio = 0.1
+				cfg.IfExpr = `$record.level == "debug"`
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "ratio_too_large",
+			ExpectErr: "ratio must be a number between 0 and 1",
+		},
+		{
+			Name:      "max_per_interval_negative",
+			ExpectErr: "max_per_interval must not be negative",
+		},
+		{
+			Name:      "key_field_without_max",
+			ExpectErr: "key_field requires max_per_interval to be set",
+		},
+		{
+			Name:      "interval_zero",
+			ExpectErr: "interval must be greater than zero",
+		},
+		{
+			Name:      "keep_severity_invalid",
+			ExpectErr: "keep_severity",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *SamplerConfig {
+	return NewSamplerConfig("sampler")
+}
@@ -0,0 +1,239 @@
+package sampler
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/metrics"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	operator.Register("sampler", func() operator.Builder { return NewSamplerConfig("") })
+	metrics.MustRegisterOperatorVecs(droppedCounter)
+}
+
+// droppedCounter counts the entries dropped by each sampler, by reason
+var droppedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "stanza",
+	Subsystem: "sampler",
+	Name:      "entries_dropped_total",
+	Help:      "Number of entries dropped by a sampler, by reason.",
+}, []string{"operator_id", "reason"})
+
+const (
+	// sampledReason is the reason recorded for entries dropped by the ratio
+	sampledReason = "sampled"
+	// rateLimitedReason is the reason recorded for entries dropped by the rate cap
+	rateLimitedReason = "rate_limited"
+)
+
+var randFloat = rand.Float64 // allow override for testing
+
+var timeNow = time.Now // allow override for testing
+
+// NewSamplerConfig creates a new sampler config with default values
+func NewSamplerConfig(operatorID string) *SamplerConfig {
+	return &SamplerConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "sampler"),
+		Ratio:             1,
+		Interval:          helper.Duration{Duration: time.Second},
+	}
+}
+
+// SamplerConfig is the configuration of a sampler operator
+type SamplerConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	Ratio          float64         `json:"ratio"                      yaml:"ratio"`
+	HashField      *entry.Field    `json:"hash_field,omitempty"       yaml:"hash_field,omitempty"`
+	KeyField       *entry.Field    `json:"key_field,omitempty"        yaml:"key_field,omitempty"`
+	MaxPerInterval int             `json:"max_per_interval,omitempty" yaml:"max_per_interval,omitempty"`
+	Interval       helper.Duration `json:"interval,omitempty"         yaml:"interval,omitempty"`
+	KeepSeverity   string          `json:"keep_severity,omitempty"    yaml:"keep_severity,omitempty"`
+}
+
+// Build will build a sampler operator
+func (c SamplerConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Ratio < 0 || c.Ratio > 1:
+		return nil, fmt.Errorf("ratio must be a number between 0 and 1")
+	case c.MaxPerInterval < 0:
+		return nil, fmt.Errorf("max_per_interval must not be negative")
+	case c.KeyField != nil && c.MaxPerInterval == 0:
+		return nil, fmt.Errorf("key_field requires max_per_interval to be set")
+	case c.MaxPerInterval > 0 && c.Interval.Raw() <= 0:
+		return nil, fmt.Errorf("interval must be greater than zero")
+	}
+
+	samplerOperator := &SamplerOperator{
+		TransformerOperator: transformerOperator,
+		ratio:               c.Ratio,
+		hashField:           c.HashField,
+		keyField:            c.KeyField,
+		maxPerInterval:      c.MaxPerInterval,
+		interval:            c.Interval.Raw(),
+	}
+
+	if c.KeepSeverity != "" {
+		severity, err := helper.ParseSeverity(c.KeepSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("keep_severity: %s", err)
+		}
+		samplerOperator.keepSeverity = &severity
+	}
+
+	return []operator.Operator{samplerOperator}, nil
+}
+
+// SamplerOperator is an operator that keeps a sample of the entries it receives
+type SamplerOperator struct {
+	kept        int64
+	alwaysKept  int64
+	sampled     int64
+	rateLimited int64
+
+	helper.TransformerOperator
+	ratio          float64
+	hashField      *entry.Field
+	keyField       *entry.Field
+	maxPerInterval int
+	interval       time.Duration
+	keepSeverity   *entry.Severity
+
+	mux         sync.Mutex
+	windowStart time.Time
+	windowCount map[string]int
+
+	sampledCounter     prometheus.Counter
+	rateLimitedCounter prometheus.Counter
+}
+
+// Start will start the sampler operator
+func (s *SamplerOperator) Start() error {
+	s.sampledCounter = droppedCounter.WithLabelValues(s.ID(), sampledReason)
+	s.rateLimitedCounter = droppedCounter.WithLabelValues(s.ID(), rateLimitedReason)
+	return nil
+}
+
+// Process will write the entry to the outputs if it is kept by the sampler.
+// Entries at or above the keep severity are always kept. Other entries are
+// sampled by the ratio, and then capped to the maximum per interval.
+func (s *SamplerOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	skip, err := s.Skip(ctx, entry)
+	if err != nil {
+		return s.HandleEntryError(ctx, entry, err)
+	}
+
+	switch {
+	case skip:
+	case s.keepSeverity != nil && entry.Severity >= *s.keepSeverity:
+		atomic.AddInt64(&s.alwaysKept, 1)
+	case !s.sample(entry):
+		atomic.AddInt64(&s.sampled, 1)
+		s.drop(s.sampledCounter)
+		return nil
+	case !s.allow(entry):
+		atomic.AddInt64(&s.rateLimited, 1)
+		s.drop(s.rateLimitedCounter)
+		return nil
+	}
+
+	atomic.AddInt64(&s.kept, 1)
+	s.Write(ctx, entry)
+	return nil
+}
+
+// sample returns true if the entry is kept by the ratio. If a hash field is set,
+// the decision is made by the hash of its value, so that all entries with the same
+// value are kept or dropped together.
+func (s *SamplerOperator) sample(entry *entry.Entry) bool {
+	switch {
+	case s.ratio >= 1:
+		return true
+	case s.ratio <= 0:
+		return false
+	}
+
+	if s.hashField != nil {
+		if value, ok := entry.Get(s.hashField); ok {
+			return hashFraction(value) < s.ratio
+		}
+	}
+	return randFloat() < s.ratio
+}
+
+// hashFraction maps a value to a number in [0, 1)
+func hashFraction(value interface{}) float64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprint(h, value)
+
+	// The high bits of FNV are poorly distributed for similar short values, such
+	// as sequential ids, so they are mixed with the murmur3 finalizer
+	sum := h.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	sum *= 0xc4ceb9fe1a85ec53
+	sum ^= sum >> 33
+	return float64(sum>>11) / (1 << 53)
+}
+
+// allow returns true if the key of the entry is below its cap for the current interval
+func (s *SamplerOperator) allow(entry *entry.Entry) bool {
+	if s.maxPerInterval == 0 {
+		return true
+	}
+
+	key := ""
+	if s.keyField != nil {
+		if value, ok := entry.Get(s.keyField); ok {
+			key = fmt.Sprint(value)
+		}
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := timeNow()
+	if s.windowCount == nil || now.Sub(s.windowStart) >= s.interval {
+		s.windowStart = now
+		s.windowCount = make(map[string]int)
+	}
+	if s.windowCount[key] >= s.maxPerInterval {
+		return false
+	}
+	s.windowCount[key]++
+	return true
+}
+
+// drop records a dropped entry
+func (s *SamplerOperator) drop(counter prometheus.Counter) {
+	s.RecordDropped()
+	if counter != nil {
+		counter.Inc()
+	}
+}
+
+// Inspect reports the number of entries kept and dropped by the sampler
+func (s *SamplerOperator) Inspect() map[string]interface{} {
+	return map[string]interface{}{
+		"kept":         atomic.LoadInt64(&s.kept),
+		"always_kept":  atomic.LoadInt64(&s.alwaysKept),
+		"sampled":      atomic.LoadInt64(&s.sampled),
+		"rate_limited": atomic.LoadInt64(&s.rateLimited),
+	}
+}
@@ -0,0 +1,162 @@
+package sampler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplerHashField(t *testing.T) {
+	cfg := NewSamplerConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Ratio = 0.5
+	field := entry.NewRecordField("trace_id")
+	cfg.HashField = &field
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	sampler := ops[0].(*SamplerOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, sampler.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, sampler.Start())
+
+	// Entries with the same trace id are kept or dropped together
+	counts := make(map[interface{}]int)
+	kept := 0
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 3; j++ {
+			e := entry.New()
+			e.Record = map[string]interface{}{"trace_id": fmt.Sprintf("trace-%d", i)}
+			require.NoError(t, sampler.Process(context.Background(), e))
+		}
+		for _, e := range fake.ReceivedEntries() {
+			counts[e.Record.(map[string]interface{})["trace_id"]]++
+			kept++
+		}
+	}
+
+	for traceID, count := range counts {
+		require.Equal(t, 3, count, traceID)
+	}
+	require.InDelta(t, 50, len(counts), 20)
+
+	details := sampler.Inspect()
+	require.Equal(t, int64(kept), details["kept"])
+	require.Equal(t, int64(300-kept), details["sampled"])
+}
+
+func TestSamplerRandom(t *testing.T) {
+	values := []float64{0.1, 0.9, 0.3, 0.7}
+	original := randFloat
+	randFloat = func() float64 {
+		value := values[0]
+		values = values[1:]
+		return value
+	}
+	defer func() { randFloat = original }()
+
+	cfg := NewSamplerConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Ratio = 0.5
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	sampler := ops[0].(*SamplerOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, sampler.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, sampler.Start())
+
+	for i := 0; i < 4; i++ {
+		e := entry.New()
+		e.Record = i
+		require.NoError(t, sampler.Process(context.Background(), e))
+	}
+	fake.ExpectRecord(t, 0)
+	fake.ExpectRecord(t, 2)
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+}
+
+func TestSamplerRateCap(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+
+	cfg := NewSamplerConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.MaxPerInterval = 2
+	field := entry.NewRecordField("service")
+	cfg.KeyField = &field
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	sampler := ops[0].(*SamplerOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, sampler.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, sampler.Start())
+
+	process := func(service string) {
+		e := entry.New()
+		e.Record = map[string]interface{}{"service": service}
+		require.NoError(t, sampler.Process(context.Background(), e))
+	}
+
+	for i := 0; i < 3; i++ {
+		process("a")
+		process("b")
+	}
+	require.Len(t, fake.ReceivedEntries(), 4)
+	require.Equal(t, int64(2), sampler.Inspect()["rate_limited"])
+
+	clock.Advance(time.Second)
+	process("a")
+	require.Len(t, fake.ReceivedEntries(), 1)
+}
+
+func TestSamplerKeepSeverity(t *testing.T) {
+	cfg := NewSamplerConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Ratio = 0
+	cfg.KeepSeverity = "error"
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	sampler := ops[0].(*SamplerOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, sampler.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, sampler.Start())
+
+	for _, severity := range []entry.Severity{entry.Info, entry.Error, entry.Critical, entry.Warning} {
+		e := entry.New()
+		e.Severity = severity
+		e.Record = severity.String()
+		require.NoError(t, sampler.Process(context.Background(), e))
+	}
+
+	fake.ExpectRecord(t, "error")
+	fake.ExpectRecord(t, "critical")
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+	details := sampler.Inspect()
+	require.Equal(t, int64(2), details["always_kept"])
+	require.Equal(t, int64(2), details["sampled"])
+}
+
+func TestSamplerIfExpr(t *testing.T) {
+	cfg := NewSamplerConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	cfg.Ratio = 0
+	cfg.IfExpr = `$record == "sampled"`
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	sampler := ops[0].(*SamplerOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, sampler.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, sampler.Start())
+
+	for _, record := range []string{"sampled", "passed"} {
+		e := entry.New()
+		e.Record = record
+		require.NoError(t, sampler.Process(context.Background(), e))
+	}
+	fake.ExpectRecord(t, "passed")
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+}
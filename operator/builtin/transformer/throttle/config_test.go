@@ -0,0 +1,94 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/operator/helper/operatortest"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []operatortest.ConfigUnmarshalTest{
+		{
+			Name:   "default",
+			Expect: defaultCfg(),
+		},
+		{
+			Name: "rate",
+			Expect: func() *ThrottleConfig {
+				cfg := defaultCfg()
+				field := entry.NewResourceField("k8s.pod.name")
+				cfg.KeyField = &field
+				cfg.Rate = 100
+				cfg.Burst = 200
+				return cfg
+			}(),
+		},
+		{
+			Name: "interval",
+			Expect: func() *ThrottleConfig {
+				cfg := defaultCfg()
+				cfg.Interval = helper.Duration{Duration: 100 * time.Millisecond}
+				cfg.Mode = DropMode
+				cfg.MaxKeys = 50
+				return cfg
+			}(),
+		},
+		{
+			Name: "summary_interval",
+			Expect: func() *ThrottleConfig {
+				cfg := defaultCfg()
+				cfg.Rate = 10
+				cfg.SummaryInterval = helper.Duration{Duration: 5 * time.Minute}
+				return cfg
+			}(),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	cases := []operatortest.ConfigBuildTest{
+		{
+			Name:      "rate_missing",
+			ExpectErr: "one of 'rate' or 'interval' must be defined",
+		},
+		{
+			Name:      "rate_and_interval",
+			ExpectErr: "only one of 'rate' or 'interval' can be defined",
+		},
+		{
+			Name:      "rate_negative",
+			ExpectErr: "rate and interval must be greater than zero",
+		},
+		{
+			Name:      "mode_invalid",
+			ExpectErr: "invalid mode 'block'",
+		},
+		{
+			Name:      "summary_interval_zero",
+			ExpectErr: "summary_interval must be greater than zero",
+		},
+		{
+			Name:      "max_keys_negative",
+			ExpectErr: "max_keys must be greater than zero",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tc.Run(t, defaultCfg())
+		})
+	}
+}
+
+func defaultCfg() *ThrottleConfig {
+	return NewThrottleConfig("throttle")
+}
@@ -0,0 +1,324 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+func init() {
+	operator.Register("throttle", func() operator.Builder { return NewThrottleConfig("") })
+}
+
+const (
+	// DropMode drops the entries of a key beyond its limit
+	DropMode = "drop"
+	// SummarizeMode drops the entries of a key beyond its limit, and periodically
+	// writes an entry with the number of entries suppressed for the key
+	SummarizeMode = "summarize"
+)
+
+var timeNow = time.Now // allow override for testing
+
+// NewThrottleConfig creates a new throttle config with default values
+func NewThrottleConfig(operatorID string) *ThrottleConfig {
+	return &ThrottleConfig{
+		TransformerConfig: helper.NewTransformerConfig(operatorID, "throttle"),
+		Mode:              SummarizeMode,
+		SummaryInterval:   helper.Duration{Duration: time.Minute},
+		MaxKeys:           10000,
+	}
+}
+
+// ThrottleConfig is the configuration of a throttle operator
+type ThrottleConfig struct {
+	helper.TransformerConfig `yaml:",inline"`
+
+	KeyField        *entry.Field    `json:"key_field,omitempty"        yaml:"key_field,omitempty"`
+	Rate            float64         `json:"rate,omitempty"             yaml:"rate,omitempty"`
+	Interval        helper.Duration `json:"interval,omitempty"         yaml:"interval,omitempty"`
+	Burst           uint            `json:"burst,omitempty"            yaml:"burst,omitempty"`
+	Mode            string          `json:"mode,omitempty"             yaml:"mode,omitempty"`
+	SummaryInterval helper.Duration `json:"summary_interval,omitempty" yaml:"summary_interval,omitempty"`
+	MaxKeys         int             `json:"max_keys,omitempty"         yaml:"max_keys,omitempty"`
+}
+
+// Build will build a throttle operator
+func (c ThrottleConfig) Build(context operator.BuildContext) ([]operator.Operator, error) {
+	transformerOperator, err := c.TransformerConfig.Build(context)
+	if err != nil {
+		return nil, err
+	}
+
+	var interval time.Duration
+	switch {
+	case c.Rate != 0 && c.Interval.Raw() != 0:
+		return nil, fmt.Errorf("only one of 'rate' or 'interval' can be defined")
+	case c.Rate < 0 || c.Interval.Raw() < 0:
+		return nil, fmt.Errorf("rate and interval must be greater than zero")
+	case c.Rate > 0:
+		interval = time.Duration(float64(time.Second) / c.Rate)
+	case c.Interval.Raw() > 0:
+		interval = c.Interval.Raw()
+	default:
+		return nil, fmt.Errorf("one of 'rate' or 'interval' must be defined")
+	}
+
+	switch {
+	case c.Mode != DropMode && c.Mode != SummarizeMode:
+		return nil, fmt.Errorf("invalid mode '%s', must be one of '%s' or '%s'", c.Mode, DropMode, SummarizeMode)
+	case c.SummaryInterval.Raw() <= 0:
+		return nil, fmt.Errorf("summary_interval must be greater than zero")
+	case c.MaxKeys <= 0:
+		return nil, fmt.Errorf("max_keys must be greater than zero")
+	}
+
+	throttleOperator := &ThrottleOperator{
+		TransformerOperator: transformerOperator,
+		keyField:            c.KeyField,
+		interval:            interval,
+		capacity:            float64(c.Burst) + 1,
+		summarize:           c.Mode == SummarizeMode,
+		summaryInterval:     c.SummaryInterval.Raw(),
+		maxKeys:             c.MaxKeys,
+	}
+
+	return []operator.Operator{throttleOperator}, nil
+}
+
+// ThrottleOperator is an operator that limits the rate of entries of each key,
+// dropping the entries beyond the limit
+type ThrottleOperator struct {
+	passed     int64
+	suppressed int64
+
+	helper.TransformerOperator
+	keyField        *entry.Field
+	interval        time.Duration
+	capacity        float64
+	summarize       bool
+	summaryInterval time.Duration
+	maxKeys         int
+
+	mux      sync.Mutex
+	buckets  map[string]*bucket
+	overflow *bucket
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// bucket is the token bucket of a key
+type bucket struct {
+	tokens     float64
+	updated    time.Time
+	suppressed int
+}
+
+// take refills the bucket for the time since it was last updated, and takes a
+// token from it if one is available
+func (b *bucket) take(now time.Time, interval time.Duration, capacity float64) bool {
+	b.refill(now, interval, capacity)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds a token for each interval since the bucket was last updated
+func (b *bucket) refill(now time.Time, interval time.Duration, capacity float64) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(interval)
+		if b.tokens > capacity {
+			b.tokens = capacity
+		}
+		b.updated = now
+	}
+}
+
+// Start will start the throttle operator, which periodically writes the number of
+// suppressed entries of each key and forgets keys that are no longer limited
+func (t *ThrottleOperator) Start() error {
+	t.mux.Lock()
+	t.buckets = make(map[string]*bucket)
+	t.overflow = t.newBucket(timeNow())
+	t.mux.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.wg.Add(1)
+	go t.summarizeSuppressed(ctx)
+	return nil
+}
+
+// Stop will stop the throttle operator, and write the number of suppressed entries
+// of each key
+func (t *ThrottleOperator) Stop() error {
+	if t.cancel != nil {
+		t.cancel()
+		t.wg.Wait()
+	}
+	t.Drain(context.Background())
+	return nil
+}
+
+// Drain writes the number of suppressed entries of each key, so that they are not
+// lost when the pipeline shuts down
+func (t *ThrottleOperator) Drain(ctx context.Context) int {
+	t.flush(ctx)
+	return 0
+}
+
+// Process will write the entry to the outputs if its key is within its limit
+func (t *ThrottleOperator) Process(ctx context.Context, entry *entry.Entry) error {
+	skip, err := t.Skip(ctx, entry)
+	if err != nil {
+		return t.HandleEntryError(ctx, entry, err)
+	}
+	if skip {
+		t.Write(ctx, entry)
+		return nil
+	}
+
+	key := ""
+	if t.keyField != nil {
+		if value, ok := entry.Get(t.keyField); ok {
+			key = fmt.Sprint(value)
+		}
+	}
+
+	now := timeNow()
+	t.mux.Lock()
+	b, ok := t.buckets[key]
+	switch {
+	case ok:
+	case len(t.buckets) < t.maxKeys:
+		b = t.newBucket(now)
+		t.buckets[key] = b
+	default:
+		// Keys beyond the maximum share a single limit, so that a flood of
+		// new keys cannot bypass it
+		b = t.overflow
+	}
+
+	allowed := b.take(now, t.interval, t.capacity)
+	if !allowed {
+		b.suppressed++
+	}
+	t.mux.Unlock()
+
+	if !allowed {
+		atomic.AddInt64(&t.suppressed, 1)
+		t.RecordDropped()
+		return nil
+	}
+
+	atomic.AddInt64(&t.passed, 1)
+	t.Write(ctx, entry)
+	return nil
+}
+
+// newBucket creates a full bucket
+func (t *ThrottleOperator) newBucket(now time.Time) *bucket {
+	return &bucket{tokens: t.capacity, updated: now}
+}
+
+// summarizeSuppressed writes the number of suppressed entries of each key every
+// summary interval, until the context is done
+func (t *ThrottleOperator) summarizeSuppressed(ctx context.Context) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.summaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.flush(ctx)
+	}
+}
+
+// flush writes the number of suppressed entries of each key, if summarizing, and
+// forgets the keys whose buckets have refilled
+func (t *ThrottleOperator) flush(ctx context.Context) {
+	now := timeNow()
+	suppressed := make(map[string]int)
+
+	t.mux.Lock()
+	if t.buckets == nil {
+		t.mux.Unlock()
+		return
+	}
+	for key, b := range t.buckets {
+		if b.suppressed > 0 {
+			suppressed[key] = b.suppressed
+			b.suppressed = 0
+		}
+		b.refill(now, t.interval, t.capacity)
+		if b.tokens >= t.capacity {
+			delete(t.buckets, key)
+		}
+	}
+	overflowed := t.overflow.suppressed
+	t.overflow.suppressed = 0
+	t.mux.Unlock()
+
+	if !t.summarize {
+		return
+	}
+
+	keys := make([]string, 0, len(suppressed))
+	for key := range suppressed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		summary := t.newSummary(now, suppressed[key], fmt.Sprintf("%d entries suppressed from %s", suppressed[key], key))
+		if t.keyField != nil && key != "" {
+			if err := summary.Set(t.keyField, key); err != nil {
+				t.Warnw("Failed to set key on summary", zap.Any("error", err))
+			}
+		}
+		t.Write(ctx, summary)
+	}
+	if overflowed > 0 {
+		t.Write(ctx, t.newSummary(now, overflowed, fmt.Sprintf("%d entries suppressed from keys beyond max_keys", overflowed)))
+	}
+}
+
+// newSummary creates an entry reporting suppressed entries
+func (t *ThrottleOperator) newSummary(now time.Time, suppressed int, message string) *entry.Entry {
+	summary := entry.New()
+	summary.Timestamp = now
+	summary.Severity = entry.Warning
+	summary.Record = map[string]interface{}{
+		"message":    message,
+		"suppressed": suppressed,
+	}
+	return summary
+}
+
+// Inspect reports the number of keys being limited, and the number of entries
+// passed and suppressed
+func (t *ThrottleOperator) Inspect() map[string]interface{} {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return map[string]interface{}{
+		"keys":       len(t.buckets),
+		"passed":     atomic.LoadInt64(&t.passed),
+		"suppressed": atomic.LoadInt64(&t.suppressed),
+	}
+}
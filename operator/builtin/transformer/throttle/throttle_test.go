@@ -0,0 +1,125 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newPodConfig() *ThrottleConfig {
+	cfg := NewThrottleConfig("test")
+	cfg.OutputIDs = []string{"fake"}
+	field := entry.NewResourceField("k8s.pod.name")
+	cfg.KeyField = &field
+	cfg.Rate = 1
+	cfg.Burst = 1
+	return cfg
+}
+
+func TestThrottlePerKey(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+	ops, err := newPodConfig().Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	throttle := ops[0].(*ThrottleOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, throttle.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, throttle.Start())
+	defer throttle.Stop()
+
+	// A noisy pod is limited without affecting a quiet one
+	for i := 0; i < 10; i++ {
+		require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "noisy"}}))
+	}
+	require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "quiet"}}))
+	require.Len(t, fake.ReceivedEntries(), 3)
+
+	clock.Advance(time.Second)
+	require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "noisy"}}))
+	require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "noisy"}}))
+	require.Len(t, fake.ReceivedEntries(), 1)
+
+	details := throttle.Inspect()
+	require.Equal(t, 2, details["keys"])
+	require.Equal(t, int64(4), details["passed"])
+	require.Equal(t, int64(9), details["suppressed"])
+}
+
+func TestThrottleSummarize(t *testing.T) {
+	clock := testutil.NewFakeClock(t, &timeNow)
+	ops, err := newPodConfig().Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	throttle := ops[0].(*ThrottleOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, throttle.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, throttle.Start())
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "noisy"}}))
+	}
+	require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "quiet"}}))
+	require.Len(t, fake.ReceivedEntries(), 3)
+
+	clock.Advance(time.Minute)
+	require.Equal(t, 0, throttle.Drain(context.Background()))
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+
+	summary := written[0]
+	require.Equal(t, entry.Warning, summary.Severity)
+	require.Equal(t, "noisy", summary.Resource["k8s.pod.name"])
+	require.Equal(t, map[string]interface{}{
+		"message":    "3 entries suppressed from noisy",
+		"suppressed": 3,
+	}, summary.Record)
+
+	// Keys whose limit has refilled are forgotten, and nothing more is reported
+	require.Equal(t, 0, throttle.Inspect()["keys"])
+	require.NoError(t, throttle.Stop())
+	fake.ExpectNoEntry(t, 10*time.Millisecond)
+}
+
+func TestThrottleDrop(t *testing.T) {
+	testutil.NewFakeClock(t, &timeNow)
+	cfg := newPodConfig()
+	cfg.Mode = DropMode
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	throttle := ops[0].(*ThrottleOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, throttle.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, throttle.Start())
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": "noisy"}}))
+	}
+	require.NoError(t, throttle.Stop())
+	require.Len(t, fake.ReceivedEntries(), 2)
+}
+
+func TestThrottleMaxKeys(t *testing.T) {
+	testutil.NewFakeClock(t, &timeNow)
+	cfg := newPodConfig()
+	cfg.MaxKeys = 1
+	cfg.Burst = 0
+	ops, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	throttle := ops[0].(*ThrottleOperator)
+	fake := testutil.NewFakeOutput(t)
+	require.NoError(t, throttle.SetOutputs([]operator.Operator{fake}))
+	require.NoError(t, throttle.Start())
+
+	for _, pod := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, throttle.Process(context.Background(), &entry.Entry{Resource: map[string]string{"k8s.pod.name": pod}}))
+	}
+	require.Len(t, fake.ReceivedEntries(), 2)
+
+	require.NoError(t, throttle.Stop())
+	written := fake.ReceivedEntries()
+	require.Len(t, written, 1)
+	require.Equal(t, "2 entries suppressed from keys beyond max_keys", written[0].Record.(map[string]interface{})["message"])
+}
@@ -0,0 +1,12 @@
+package operator
+
+import "context"
+
+// Drainer is an operator that holds entries, such as in a buffer or batch, that it
+// should flush before it is stopped.
+type Drainer interface {
+	// Drain flushes the entries held by the operator, returning once they have been
+	// flushed or the context is done. It returns the number of entries that were not
+	// flushed.
+	Drain(context.Context) int
+}
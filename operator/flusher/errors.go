@@ -0,0 +1,51 @@
+package flusher
+
+import (
+	"errors"
+	"net/http"
+)
+
+// PermanentError is an error that will not be resolved by retrying the flush
+type PermanentError struct {
+	Err error
+}
+
+// Error returns the message of the wrapped error
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks an error as permanent so that the flusher stops retrying the chunk immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent returns true if the error, or any error it wraps, is permanent
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// IsRetryableStatus returns true if a request that failed with the supplied HTTP
+// status code may succeed when retried. Client errors are permanent, except for
+// request timeouts and rate limiting.
+func IsRetryableStatus(code int) bool {
+	if code < 400 || code >= 500 {
+		return true
+	}
+
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,37 @@
+package flusher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPermanent(t *testing.T) {
+	err := errors.New("test")
+	require.False(t, IsPermanent(err))
+	require.True(t, IsPermanent(Permanent(err)))
+	require.True(t, IsPermanent(fmt.Errorf("wrapped: %w", Permanent(err))))
+	require.Nil(t, Permanent(nil))
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	cases := map[int]bool{
+		200: true,
+		400: false,
+		401: false,
+		404: false,
+		408: true,
+		413: false,
+		429: true,
+		500: true,
+		503: true,
+	}
+
+	for code, expected := range cases {
+		t.Run(fmt.Sprint(code), func(t *testing.T) {
+			require.Equal(t, expected, IsRetryableStatus(code))
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package flusher
+
+import (
+	"context"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+// NewDropFunc returns a DropFunc that applies the flusher's on_exhausted policy to a chunk
+// of entries read from a buffer.
+//
+// Chunks that failed with a permanent error, or that exhausted their retries under the drop
+// policy, are sent to the dead letter output of the output operator if it has one, and are
+// then marked as flushed. If sending to the dead letter output fails, the chunk is left
+// unflushed. Under the spill policy, the entries of the chunk are added to the spill buffer
+// and the chunk is then marked as flushed. Chunks under the hold policy are retried until
+// they are flushed, so they are never dropped unless they fail with a permanent error.
+func (f *Flusher) NewDropFunc(ctx context.Context, output *helper.OutputOperator, entries []*entry.Entry, clearer buffer.Clearer) DropFunc {
+	return func(err error) {
+		switch {
+		case IsPermanent(err):
+		case f.onExhausted == HoldOnExhausted:
+			return
+		case f.onExhausted == SpillOnExhausted:
+			f.spillChunk(ctx, entries, clearer)
+			return
+		}
+
+		if output.DeadLetter.Enabled() && !output.SendToDeadLetter(ctx, entries, err) {
+			return
+		}
+
+		if err := clearer.MarkAllAsFlushed(); err != nil {
+			f.Errorw("Failed to mark dropped entries as flushed", zap.Error(err))
+		}
+	}
+}
+
+// spillChunk adds the entries of a chunk to the spill buffer, and marks the chunk as
+// flushed from the buffer it was read from. If an entry can not be spilled, which only
+// happens when the output is stopped while the spill buffer is full, the chunk is left
+// unflushed.
+func (f *Flusher) spillChunk(ctx context.Context, entries []*entry.Entry, clearer buffer.Clearer) {
+	for _, e := range entries {
+		if err := f.spill.Add(ctx, e); err != nil {
+			f.Errorw("Failed to spill entries. Leaving chunk unflushed", zap.Error(err))
+			return
+		}
+	}
+
+	if err := clearer.MarkAllAsFlushed(); err != nil {
+		f.Errorw("Failed to mark spilled entries as flushed", zap.Error(err))
+	}
+}
+
+// replaySpilled moves the entries in the spill buffer back to buf every max_interval,
+// until buf signals backpressure or the context is done
+func (f *Flusher) replaySpilled(ctx context.Context, buf buffer.Buffer) {
+	ticker := time.NewTicker(f.newExponentialBackoff().MaxInterval)
+	defer ticker.Stop()
+
+	entries := make([]*entry.Entry, f.spill.MaxChunkSize())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for !pressured(buf) {
+			clearer, n, err := f.spill.Read(entries)
+			if err != nil {
+				f.Errorw("Failed to read spilled entries", zap.Error(err))
+				break
+			}
+			if n == 0 {
+				break
+			}
+
+			for _, e := range entries[:n] {
+				if err := buf.Add(ctx, e); err != nil {
+					// The context is done, and the entries are read again after a restart
+					return
+				}
+			}
+			if err := clearer.MarkAllAsFlushed(); err != nil {
+				f.Errorw("Failed to mark replayed entries as flushed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// pressured returns true if the buffer is signaling backpressure
+func pressured(buf buffer.Buffer) bool {
+	signal := buf.Backpressure()
+	return signal != nil && signal.Pressured()
+}
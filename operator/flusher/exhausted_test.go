@@ -0,0 +1,101 @@
+package flusher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testClearer struct {
+	flushed bool
+}
+
+func (c *testClearer) MarkAllAsFlushed() error {
+	c.flushed = true
+	return nil
+}
+
+func (c *testClearer) MarkRangeAsFlushed(uint, uint) error {
+	c.flushed = true
+	return nil
+}
+
+func TestDropFunc(t *testing.T) {
+	cases := []struct {
+		name            string
+		onExhausted     string
+		err             error
+		expectedFlushed bool
+	}{
+		{"Drop", DropOnExhausted, errors.New("test"), true},
+		{"Hold", HoldOnExhausted, errors.New("test"), false},
+		{"PermanentHold", HoldOnExhausted, Permanent(errors.New("test")), true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := testutil.NewBuildContext(t)
+			output, err := helper.NewOutputConfig("test", "test").Build(bc)
+			require.NoError(t, err)
+
+			flusherCfg := NewConfig()
+			flusherCfg.OnExhausted = tc.onExhausted
+			flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+			require.NoError(t, err)
+
+			entries := []*entry.Entry{entry.New(), entry.New()}
+			clearer := &testClearer{}
+			flusher.NewDropFunc(context.Background(), &output, entries, clearer)(tc.err)
+			require.Equal(t, tc.expectedFlushed, clearer.flushed)
+		})
+	}
+}
+
+func TestSpill(t *testing.T) {
+	bc := testutil.NewBuildContext(t)
+	output, err := helper.NewOutputConfig("test", "test").Build(bc)
+	require.NoError(t, err)
+
+	flusherCfg := NewConfig()
+	flusherCfg.OnExhausted = SpillOnExhausted
+	flusherCfg.Retry.MaxInterval = helper.NewDuration(10 * time.Millisecond)
+	flusherCfg.SpillBuffer = buffer.NewDiskBufferConfig()
+	flusherCfg.SpillBuffer.Path = testutil.NewTempDir(t)
+	flusher, err := flusherCfg.Build(bc, "$.test")
+	require.NoError(t, err)
+	defer flusher.Stop()
+
+	entries := []*entry.Entry{entry.New(), entry.New()}
+
+	t.Run("Permanent", func(t *testing.T) {
+		clearer := &testClearer{}
+		flusher.NewDropFunc(context.Background(), &output, entries, clearer)(Permanent(errors.New("test")))
+		require.True(t, clearer.flushed)
+		require.Equal(t, 0, flusher.spill.Unflushed())
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		clearer := &testClearer{}
+		flusher.NewDropFunc(context.Background(), &output, entries, clearer)(errors.New("test"))
+		require.True(t, clearer.flushed)
+		require.Equal(t, 2, flusher.spill.Unflushed())
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		buf, err := buffer.NewConfig().Build(bc, "$.test")
+		require.NoError(t, err)
+		defer buf.Close()
+
+		flusher.Start(buf)
+		require.Eventually(t, func() bool {
+			return buf.Unflushed() == 2 && flusher.spill.Unflushed() == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
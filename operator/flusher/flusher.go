@@ -2,18 +2,31 @@ package flusher
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	backoff "github.com/cenkalti/backoff/v4"
+	"github.com/observiq/stanza/metrics"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/operator/helper"
 	"go.uber.org/zap"
 	"golang.org/x/sync/semaphore"
 )
 
-// These are vars so they can be overridden in tests
-var maxRetryInterval = time.Minute
-var maxElapsedTime = time.Hour
+const (
+	// DropOnExhausted drops a chunk after exhausting its retries, sending it to the
+	// dead letter output if one is configured
+	DropOnExhausted = "drop"
+	// HoldOnExhausted keeps retrying a chunk every max_interval after exhausting its
+	// retries. A held chunk keeps its space in the buffer until it is flushed.
+	HoldOnExhausted = "hold"
+	// SpillOnExhausted moves a chunk to the spill buffer after exhausting its retries.
+	// Spilled entries are moved back to the buffer while it has room for them.
+	SpillOnExhausted = "spill"
+)
 
 // Config holds the configuration to build a new flusher
 type Config struct {
@@ -21,31 +34,100 @@ type Config struct {
 	// Defaults to 16.
 	MaxConcurrent int `json:"max_concurrent" yaml:"max_concurrent"`
 
-	// TODO configurable retry
+	// Retry configures the backoff between attempts to flush a chunk
+	Retry RetryConfig `json:"retry" yaml:"retry"`
+
+	// OnExhausted is the policy applied to a chunk once its retries are exhausted.
+	// One of drop, hold or spill. Defaults to drop.
+	OnExhausted string `json:"on_exhausted" yaml:"on_exhausted"`
+
+	// SpillBuffer is the disk buffer that chunks are moved to under the spill policy.
+	// It must be separate from the buffer of the output.
+	SpillBuffer *buffer.DiskBufferConfig `json:"spill_buffer,omitempty" yaml:"spill_buffer,omitempty"`
+}
+
+// RetryConfig holds the configuration of the exponential backoff between flush retries
+type RetryConfig struct {
+	InitialInterval helper.Duration `json:"initial_interval" yaml:"initial_interval"`
+	Multiplier      float64         `json:"multiplier"       yaml:"multiplier"`
+	MaxInterval     helper.Duration `json:"max_interval"     yaml:"max_interval"`
+	// MaxElapsedTime is the time after which a chunk's retries are exhausted.
+	// A value of 0 retries forever.
+	MaxElapsedTime helper.Duration `json:"max_elapsed_time" yaml:"max_elapsed_time"`
+	// Jitter is the randomization factor applied to each interval, between 0 and 1
+	Jitter float64 `json:"jitter" yaml:"jitter"`
 }
 
 // NewConfig creates a new default flusher config
 func NewConfig() Config {
 	return Config{
 		MaxConcurrent: 16,
+		Retry:         NewRetryConfig(),
+		OnExhausted:   DropOnExhausted,
+	}
+}
+
+// NewRetryConfig creates a new default retry config
+func NewRetryConfig() RetryConfig {
+	return RetryConfig{
+		InitialInterval: helper.NewDuration(50 * time.Millisecond),
+		Multiplier:      backoff.DefaultMultiplier,
+		MaxInterval:     helper.NewDuration(time.Minute),
+		MaxElapsedTime:  helper.NewDuration(time.Hour),
+		Jitter:          backoff.DefaultRandomizationFactor,
 	}
 }
 
-// Build uses a Config to build a new Flusher
-func (c *Config) Build(logger *zap.SugaredLogger) *Flusher {
+// Build uses a Config to build a new Flusher for the operator with the supplied id
+func (c *Config) Build(bc operator.BuildContext, operatorID string) (*Flusher, error) {
 	maxConcurrent := c.MaxConcurrent
 	if maxConcurrent == 0 {
 		maxConcurrent = 16
 	}
 
+	onExhausted := c.OnExhausted
+	switch onExhausted {
+	case "":
+		onExhausted = DropOnExhausted
+	case DropOnExhausted, HoldOnExhausted, SpillOnExhausted:
+	default:
+		return nil, fmt.Errorf("flusher on_exhausted policy '%s' is not one of drop, hold or spill", c.OnExhausted)
+	}
+
+	var spill buffer.Buffer
+	switch {
+	case onExhausted == SpillOnExhausted && c.SpillBuffer == nil:
+		return nil, fmt.Errorf("flusher on_exhausted policy 'spill' requires a spill_buffer")
+	case onExhausted == SpillOnExhausted:
+		var err error
+		spill, err = c.SpillBuffer.Build(bc, operatorID+"_spill")
+		if err != nil {
+			return nil, fmt.Errorf("build spill buffer: %s", err)
+		}
+	case c.SpillBuffer != nil:
+		return nil, fmt.Errorf("flusher spill_buffer is only used with the on_exhausted policy 'spill'")
+	}
+
+	if c.Retry.Multiplier != 0 && c.Retry.Multiplier < 1 {
+		return nil, fmt.Errorf("flusher retry multiplier must be at least 1, got %v", c.Retry.Multiplier)
+	}
+
+	if c.Retry.Jitter < 0 || c.Retry.Jitter > 1 {
+		return nil, fmt.Errorf("flusher retry jitter must be between 0 and 1, got %v", c.Retry.Jitter)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Flusher{
 		ctx:           ctx,
 		cancel:        cancel,
 		sem:           semaphore.NewWeighted(int64(maxConcurrent)),
-		SugaredLogger: logger,
-	}
+		retry:         c.Retry,
+		onExhausted:   onExhausted,
+		spill:         spill,
+		metrics:       metrics.NewFlusher(operatorID),
+		SugaredLogger: bc.Logger.SugaredLogger,
+	}, nil
 }
 
 // Flusher is used to flush entries from a buffer concurrently. It handles max concurrency,
@@ -56,14 +138,26 @@ type Flusher struct {
 	cancel         context.CancelFunc
 	sem            *semaphore.Weighted
 	wg             sync.WaitGroup
+	retry          RetryConfig
+	onExhausted    string
+	spill          buffer.Buffer
+	metrics        *metrics.Flusher
+
+	// status holds the error of the most recent failed flush, until a flush succeeds
+	status helper.HealthStatus
 	*zap.SugaredLogger
 }
 
 // FlushFunc is any function that flushes
 type FlushFunc func(context.Context) error
 
-// Do executes the flusher function in a goroutine
-func (f *Flusher) Do(flush FlushFunc) {
+// DropFunc is called with the last flush error when a chunk fails with a permanent
+// error or exhausts its retries
+type DropFunc func(error)
+
+// Do executes the flusher function in a goroutine. If the chunk fails with a permanent
+// error or exhausts its retries, drop is called if it is not nil.
+func (f *Flusher) Do(flush FlushFunc, drop DropFunc) {
 	// Wait until we have free flusher goroutines
 	if err := f.sem.Acquire(f.ctx, 1); err != nil {
 		// Context cancelled
@@ -75,7 +169,21 @@ func (f *Flusher) Do(flush FlushFunc) {
 	go func() {
 		defer f.wg.Done()
 		defer f.sem.Release(1)
-		f.flushWithRetry(f.ctx, flush)
+		f.flushWithRetry(f.ctx, flush, drop)
+	}()
+}
+
+// Start moves the entries of spilled chunks back to buf while it has room for them,
+// if the flusher has a spill buffer. It is called when the output is started.
+func (f *Flusher) Start(buf buffer.Buffer) {
+	if f.spill == nil {
+		return
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.replaySpilled(f.ctx, buf)
 	}()
 }
 
@@ -83,24 +191,59 @@ func (f *Flusher) Do(flush FlushFunc) {
 func (f *Flusher) Stop() {
 	f.cancel()
 	f.wg.Wait()
+	if f.spill != nil {
+		if err := f.spill.Close(); err != nil {
+			f.Errorw("Failed to close spill buffer", zap.Error(err))
+		}
+	}
 }
 
 // flushWithRetry will continue trying to call flushFunc with the entries passed
-// in until either flushFunc returns no error or the context is cancelled. It will only
-// return an error in the case that the context was cancelled. If no error was returned,
-// it is safe to mark the entries in the buffer as flushed.
-func (f *Flusher) flushWithRetry(ctx context.Context, flush FlushFunc) {
+// in until either flushFunc returns no error, it returns a permanent error, the
+// retries are exhausted, or the context is cancelled. If no error was returned,
+// it is safe to mark the entries in the buffer as flushed. Under the hold policy,
+// a chunk whose retries are exhausted is retried every max_interval until it is
+// flushed.
+func (f *Flusher) flushWithRetry(ctx context.Context, flush FlushFunc, drop DropFunc) {
 	chunkID := f.nextChunkID()
-	b := newExponentialBackoff()
-	for {
+	b := f.newExponentialBackoff()
+	held := false
+	for retries := 0; ; retries++ {
 		err := flush(ctx)
+		f.metrics.Attempted(err)
 		if err == nil {
+			f.status.Set(nil)
+			f.metrics.Finished(retries, false)
+			return
+		}
+
+		if IsPermanent(err) {
+			// The destination was reached, but rejected the chunk
+			f.status.Set(nil)
+			f.Errorw("Failed flushing chunk with a permanent error. Dropping logs in chunk", "chunk_id", chunkID, "error", err)
+			f.metrics.Finished(retries, true)
+			if drop != nil {
+				drop(err)
+			}
 			return
 		}
 
-		waitTime := b.NextBackOff()
+		f.status.Set(err)
+		waitTime := b.MaxInterval
+		if !held {
+			waitTime = b.NextBackOff()
+		}
+		if waitTime == b.Stop && f.onExhausted == HoldOnExhausted {
+			f.Errorw("Reached max backoff time during chunk flush retry. Holding chunk and retrying every max_interval", "chunk_id", chunkID)
+			held = true
+			waitTime = b.MaxInterval
+		}
 		if waitTime == b.Stop {
-			f.Errorw("Reached max backoff time during chunk flush retry. Dropping logs in chunk", "chunk_id", chunkID)
+			f.Errorw("Reached max backoff time during chunk flush retry", "chunk_id", chunkID, "on_exhausted", f.onExhausted)
+			f.metrics.Finished(retries, true)
+			if drop != nil {
+				drop(err)
+			}
 			return
 		}
 
@@ -121,18 +264,45 @@ func (f *Flusher) flushWithRetry(ctx context.Context, flush FlushFunc) {
 	}
 }
 
+// Ready returns an error if the most recent flush failed with an error that
+// may be retried, such as the destination being unreachable
+func (f *Flusher) Ready() error {
+	if err := f.status.Err(); err != nil {
+		return fmt.Errorf("failed to flush entries: %w", err)
+	}
+	return nil
+}
+
 func (f *Flusher) nextChunkID() uint64 {
 	return atomic.AddUint64(&f.chunkIDCounter, 1)
 }
 
-// newExponentialBackoff returns a default ExponentialBackOff
-func newExponentialBackoff() *backoff.ExponentialBackOff {
+// newExponentialBackoff returns an ExponentialBackOff configured by the flusher's retry
+// config, using defaults for any unset intervals
+func (f *Flusher) newExponentialBackoff() *backoff.ExponentialBackOff {
+	defaults := NewRetryConfig()
+
+	initialInterval := f.retry.InitialInterval.Raw()
+	if initialInterval == 0 {
+		initialInterval = defaults.InitialInterval.Raw()
+	}
+
+	multiplier := f.retry.Multiplier
+	if multiplier == 0 {
+		multiplier = defaults.Multiplier
+	}
+
+	maxInterval := f.retry.MaxInterval.Raw()
+	if maxInterval == 0 {
+		maxInterval = defaults.MaxInterval.Raw()
+	}
+
 	b := &backoff.ExponentialBackOff{
-		InitialInterval:     50 * time.Millisecond,
-		RandomizationFactor: backoff.DefaultRandomizationFactor,
-		Multiplier:          backoff.DefaultMultiplier,
-		MaxInterval:         maxRetryInterval,
-		MaxElapsedTime:      maxElapsedTime,
+		InitialInterval:     initialInterval,
+		RandomizationFactor: f.retry.Jitter,
+		Multiplier:          multiplier,
+		MaxInterval:         maxInterval,
+		MaxElapsedTime:      f.retry.MaxElapsedTime.Raw(),
 		Stop:                backoff.Stop,
 		Clock:               backoff.SystemClock,
 	}
@@ -7,18 +7,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/require"
-	"go.uber.org/zap/zaptest"
 )
 
 func TestFlusher(t *testing.T) {
-
-	// Override setting for test
-	maxElapsedTime = 5 * time.Second
-
 	outChan := make(chan struct{}, 100)
 	flusherCfg := NewConfig()
-	flusher := flusherCfg.Build(zaptest.NewLogger(t).Sugar())
+	flusherCfg.Retry.MaxElapsedTime = helper.NewDuration(5 * time.Second)
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
 
 	failed := errors.New("test failure")
 	for i := 0; i < 100; i++ {
@@ -29,7 +29,7 @@ func TestFlusher(t *testing.T) {
 			}
 			outChan <- struct{}{}
 			return nil
-		})
+		}, nil)
 	}
 
 	for i := 0; i < 100; i++ {
@@ -42,16 +42,137 @@ func TestFlusher(t *testing.T) {
 }
 
 func TestMaxElapsedTime(t *testing.T) {
-
-	// Override setting for test
-	maxElapsedTime = 1 * time.Second
+	maxElapsedTime := 1 * time.Second
 
 	flusherCfg := NewConfig()
-	flusher := flusherCfg.Build(zaptest.NewLogger(t).Sugar())
+	flusherCfg.Retry.MaxElapsedTime = helper.NewDuration(maxElapsedTime)
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
 
 	start := time.Now()
+	var dropErr error
 	flusher.flushWithRetry(context.Background(), func(_ context.Context) error {
 		return errors.New("never flushes")
+	}, func(err error) {
+		dropErr = err
 	})
 	require.WithinDuration(t, start.Add(maxElapsedTime), time.Now(), maxElapsedTime)
+	require.EqualError(t, dropErr, "never flushes")
+}
+
+func TestPermanentError(t *testing.T) {
+	flusherCfg := NewConfig()
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
+
+	attempts := 0
+	var dropErr error
+	flusher.flushWithRetry(context.Background(), func(_ context.Context) error {
+		attempts++
+		return Permanent(errors.New("bad request"))
+	}, func(err error) {
+		dropErr = err
+	})
+	require.Equal(t, 1, attempts)
+	require.True(t, IsPermanent(dropErr))
+	require.EqualError(t, dropErr, "bad request")
+}
+
+func TestBuildInvalidConfig(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(*Config)
+	}{
+		{
+			"OnExhausted",
+			func(c *Config) { c.OnExhausted = "retry" },
+		},
+		{
+			"Multiplier",
+			func(c *Config) { c.Retry.Multiplier = 0.5 },
+		},
+		{
+			"Jitter",
+			func(c *Config) { c.Retry.Jitter = 1.5 },
+		},
+		{
+			"SpillWithoutSpillBuffer",
+			func(c *Config) { c.OnExhausted = SpillOnExhausted },
+		},
+		{
+			"SpillBufferWithoutSpill",
+			func(c *Config) { c.SpillBuffer = buffer.NewDiskBufferConfig() },
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewConfig()
+			tc.modify(&cfg)
+			_, err := cfg.Build(testutil.NewBuildContext(t), "$.test")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestInfiniteMaxElapsedTime(t *testing.T) {
+	flusherCfg := NewConfig()
+	flusherCfg.Retry.MaxElapsedTime = helper.NewDuration(0)
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
+
+	b := flusher.newExponentialBackoff()
+	b.Clock = fakeClock{time.Now().Add(24 * 365 * time.Hour)}
+	require.NotEqual(t, b.Stop, b.NextBackOff())
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestReady(t *testing.T) {
+	flusherCfg := NewConfig()
+	flusherCfg.Retry.MaxElapsedTime = helper.NewDuration(time.Millisecond)
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
+	require.NoError(t, flusher.Ready())
+
+	flusher.flushWithRetry(context.Background(), func(_ context.Context) error {
+		return errors.New("connection refused")
+	}, nil)
+	require.EqualError(t, flusher.Ready(), "failed to flush entries: connection refused")
+
+	flusher.flushWithRetry(context.Background(), func(_ context.Context) error {
+		return Permanent(errors.New("bad request"))
+	}, nil)
+	require.NoError(t, flusher.Ready())
+}
+
+func TestHoldRetriesAtMaxInterval(t *testing.T) {
+	flusherCfg := NewConfig()
+	flusherCfg.OnExhausted = HoldOnExhausted
+	flusherCfg.Retry.InitialInterval = helper.NewDuration(time.Millisecond)
+	flusherCfg.Retry.MaxInterval = helper.NewDuration(10 * time.Millisecond)
+	flusherCfg.Retry.MaxElapsedTime = helper.NewDuration(time.Millisecond)
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
+
+	attempts := 0
+	dropped := false
+	flusher.flushWithRetry(context.Background(), func(_ context.Context) error {
+		attempts++
+		if attempts < 5 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, func(err error) {
+		dropped = true
+	})
+	require.Equal(t, 5, attempts)
+	require.False(t, dropped)
+	require.NoError(t, flusher.Ready())
 }
@@ -0,0 +1,41 @@
+package flusher
+
+import (
+	"context"
+
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/buffer"
+)
+
+// BufferedOutput holds the buffer and flusher of an output that buffers entries
+// before flushing them. Outputs embed it to report the state of their buffer and
+// flusher to the agent.
+type BufferedOutput struct {
+	Buffer  buffer.Buffer
+	Flusher *Flusher
+}
+
+// Backpressure returns the signal raised when the output's buffer reaches its high watermark
+func (b *BufferedOutput) Backpressure() *operator.Backpressure {
+	return b.Buffer.Backpressure()
+}
+
+// Drain waits until the output's buffer has been flushed, or the context is done
+func (b *BufferedOutput) Drain(ctx context.Context) int {
+	return buffer.Drain(ctx, b.Buffer)
+}
+
+// Inspect reports the number of entries in the output's buffer that have not been flushed
+func (b *BufferedOutput) Inspect() map[string]interface{} {
+	return map[string]interface{}{"buffered_entries": b.Buffer.Unflushed()}
+}
+
+// Healthy returns an error if the output's buffer is full and has stopped flushing
+func (b *BufferedOutput) Healthy() error {
+	return b.Buffer.Healthy()
+}
+
+// Ready returns an error if the output failed to reach its destination on its most recent flush
+func (b *BufferedOutput) Ready() error {
+	return b.Flusher.Ready()
+}
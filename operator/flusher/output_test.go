@@ -0,0 +1,35 @@
+package flusher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedOutput(t *testing.T) {
+	bufferCfg := buffer.NewConfig()
+	buf, err := bufferCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
+	defer buf.Close()
+
+	flusherCfg := NewConfig()
+	flusher, err := flusherCfg.Build(testutil.NewBuildContext(t), "$.test")
+	require.NoError(t, err)
+	defer flusher.Stop()
+
+	output := &BufferedOutput{Buffer: buf, Flusher: flusher}
+	require.NoError(t, output.Buffer.Add(context.Background(), entry.New()))
+	require.Equal(t, map[string]interface{}{"buffered_entries": 1}, output.Inspect())
+	require.NotNil(t, output.Backpressure())
+	require.NoError(t, output.Healthy())
+	require.NoError(t, output.Ready())
+
+	// Nothing reads from the buffer, so the entry is not flushed before the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, 1, output.Drain(ctx))
+}
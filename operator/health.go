@@ -0,0 +1,16 @@
+package operator
+
+// HealthChecker is an operator that can tell whether it is stuck in a way that
+// restarting the agent may fix, such as a listener that is no longer accepting
+// connections or a buffer that has stopped flushing
+type HealthChecker interface {
+	// Healthy returns an error describing why the operator is unhealthy, or nil if it is healthy
+	Healthy() error
+}
+
+// ReadinessChecker is an operator that can tell whether it is currently able to do
+// its work, such as an output that can reach its endpoint
+type ReadinessChecker interface {
+	// Ready returns an error describing why the operator is not ready, or nil if it is ready
+	Ready() error
+}
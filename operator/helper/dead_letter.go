@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+)
+
+const (
+	// DeadLetterOperatorLabel is the label set to the id of the operator that failed to process an entry
+	DeadLetterOperatorLabel = "stanza_dead_letter_operator"
+	// DeadLetterErrorLabel is the label set to the error that caused an entry to be sent to a dead letter output
+	DeadLetterErrorLabel = "stanza_dead_letter_error"
+)
+
+// DeadLetter sends entries that could not be processed to a dead letter output.
+type DeadLetter struct {
+	OutputID string
+	Output   operator.Operator
+}
+
+// NewDeadLetter creates a dead letter with a namespaced output id. An empty id disables the dead letter.
+func NewDeadLetter(outputID string, bc operator.BuildContext) DeadLetter {
+	if outputID == "" {
+		return DeadLetter{}
+	}
+	return DeadLetter{OutputID: bc.PrependNamespace(outputID)}
+}
+
+// Enabled returns true if a dead letter output is configured.
+func (d *DeadLetter) Enabled() bool {
+	return d.OutputID != ""
+}
+
+// SetOutput finds the dead letter output among the supplied operators.
+func (d *DeadLetter) SetOutput(operators []operator.Operator) error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	for _, op := range operators {
+		if op.ID() != d.OutputID {
+			continue
+		}
+		if !op.CanProcess() {
+			return fmt.Errorf("dead letter operator '%s' can not process entries", d.OutputID)
+		}
+		d.Output = op
+		return nil
+	}
+	return fmt.Errorf("dead letter operator '%s' does not exist", d.OutputID)
+}
+
+// Send labels an entry with the id of the operator that failed and the error,
+// and sends it to the dead letter output.
+func (d *DeadLetter) Send(ctx context.Context, operatorID string, e *entry.Entry, err error) error {
+	if d.Output == nil {
+		return fmt.Errorf("dead letter output '%s' is not connected", d.OutputID)
+	}
+
+	if e.Labels == nil {
+		e.Labels = make(map[string]string)
+	}
+	e.Labels[DeadLetterOperatorLabel] = operatorID
+	e.Labels[DeadLetterErrorLabel] = err.Error()
+	return d.Output.Process(ctx, e)
+}
+
+// appendOutput returns the outputs with the dead letter output appended, unless it is already present.
+func (d *DeadLetter) appendOutput(outputs []operator.Operator) []operator.Operator {
+	if d.Output == nil {
+		return outputs
+	}
+	for _, output := range outputs {
+		if output == d.Output {
+			return outputs
+		}
+	}
+	return append(append(make([]operator.Operator, 0, len(outputs)+1), outputs...), d.Output)
+}
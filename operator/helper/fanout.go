@@ -0,0 +1,283 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/metrics"
+	"github.com/observiq/stanza/operator"
+)
+
+const (
+	// SyncFanout writes an entry to each output in turn, in the goroutine that wrote it
+	SyncFanout = "sync"
+	// AsyncFanout queues an entry for each output, and writes it from a goroutine per output
+	AsyncFanout = "async"
+
+	// BlockWhenFull blocks a write until there is room in the queue of each output
+	BlockWhenFull = "block"
+	// DropWhenFull drops an entry for each output whose queue is full
+	DropWhenFull = "drop"
+
+	defaultFanoutQueueSize = 1000
+
+	// fanoutDropWarningInterval is the minimum time between warnings that a writer
+	// has dropped entries for its outputs
+	fanoutDropWarningInterval = 10 * time.Second
+)
+
+// FanoutConfig configures how a writer delivers entries to its outputs.
+type FanoutConfig struct {
+	Mode      string `json:"mode,omitempty"       yaml:"mode,omitempty"`
+	QueueSize int    `json:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+	WhenFull  string `json:"when_full,omitempty"  yaml:"when_full,omitempty"`
+}
+
+// validate returns an error if the fanout config is invalid
+func (c FanoutConfig) validate() error {
+	switch c.Mode {
+	case "", SyncFanout, AsyncFanout:
+	default:
+		return fmt.Errorf("fanout mode '%s' is not one of sync or async", c.Mode)
+	}
+
+	switch c.WhenFull {
+	case "", BlockWhenFull, DropWhenFull:
+	default:
+		return fmt.Errorf("fanout when_full '%s' is not one of block or drop", c.WhenFull)
+	}
+
+	if c.QueueSize < 0 {
+		return fmt.Errorf("fanout queue_size must not be negative")
+	}
+	return nil
+}
+
+// fanout writes entries to outputs asynchronously. Each output has its own queue and
+// goroutine, so a slow or failing output does not hold back the others until its queue
+// is full. A write to a full queue blocks, or drops the entry for that output if drop
+// is set.
+type fanout struct {
+	branches []*fanoutBranch
+	drop     bool
+	mux      sync.RWMutex
+	stopped  bool
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// stopping is closed when the fan-out starts to stop, to release blocked writes
+	stopping  chan struct{}
+	closeOnce sync.Once
+
+	// dropped is the number of entries dropped since the last warning at lastWarning
+	dropMux     sync.Mutex
+	dropped     int
+	lastWarning time.Time
+}
+
+// fanoutBranch is the queue between a writer and one of its outputs
+type fanoutBranch struct {
+	queue   chan *entry.Entry
+	metrics *metrics.FanoutBranch
+}
+
+// newFanout starts a goroutine per output that writes the entries queued for that output
+// using writeTo.
+func newFanout(operatorID string, outputs []operator.Operator, config FanoutConfig, writeTo func(context.Context, int, operator.Operator, *entry.Entry)) *fanout {
+	queueSize := config.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultFanoutQueueSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &fanout{
+		branches: make([]*fanoutBranch, 0, len(outputs)),
+		drop:     config.WhenFull == DropWhenFull,
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+	}
+	for i, output := range outputs {
+		branch := &fanoutBranch{
+			queue:   make(chan *entry.Entry, queueSize),
+			metrics: metrics.NewFanoutBranch(operatorID, output.ID()),
+		}
+		f.branches = append(f.branches, branch)
+
+		f.wg.Add(1)
+		go func(i int, output operator.Operator) {
+			defer f.wg.Done()
+			for {
+				// Check for cancellation first, so that entries still queued
+				// when the fan-out is cancelled are left in the queue
+				if f.ctx.Err() != nil {
+					return
+				}
+
+				select {
+				case <-f.ctx.Done():
+					return
+				case e, ok := <-branch.queue:
+					if !ok {
+						return
+					}
+					branch.metrics.Queued(-1)
+					writeTo(f.ctx, i, output, e)
+				}
+			}
+		}(i, output)
+	}
+	return f
+}
+
+// write queues an entry for each output. If the queue of an output is full, it blocks
+// until there is room, the context is done, or the fan-out stops, unless drop is set.
+// It returns the number of outputs the entry was dropped for.
+func (f *fanout) write(ctx context.Context, e *entry.Entry) int {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	dropped := 0
+	for i, branch := range f.branches {
+		if f.stopped {
+			branch.metrics.Dropped(1)
+			dropped++
+			continue
+		}
+
+		branchEntry := e
+		if i != len(f.branches)-1 {
+			branchEntry = e.Copy()
+		}
+
+		if !f.enqueue(ctx, branch, branchEntry) {
+			branch.metrics.Dropped(1)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// enqueue adds an entry to the queue of a branch. It returns false if the entry
+// was dropped.
+func (f *fanout) enqueue(ctx context.Context, branch *fanoutBranch, e *entry.Entry) bool {
+	select {
+	case branch.queue <- e:
+		branch.metrics.Queued(1)
+		return true
+	default:
+		if f.drop {
+			return false
+		}
+	}
+
+	select {
+	case branch.queue <- e:
+		branch.metrics.Queued(1)
+		return true
+	case <-ctx.Done():
+		return false
+	case <-f.stopping:
+		return false
+	}
+}
+
+// stop stops accepting entries and waits until every queued entry has been written,
+// or the context is done. It returns the number of queued entries that were dropped.
+func (f *fanout) stop(ctx context.Context) int {
+	// Release blocked writes, which hold the read lock
+	f.closeOnce.Do(func() { close(f.stopping) })
+
+	f.mux.Lock()
+	if f.stopped {
+		f.mux.Unlock()
+		return 0
+	}
+	f.stopped = true
+	for _, branch := range f.branches {
+		close(branch.queue)
+	}
+	f.mux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		f.cancel()
+		return 0
+	case <-ctx.Done():
+	}
+
+	f.cancel()
+	<-done
+
+	dropped := 0
+	for _, branch := range f.branches {
+		remaining := len(branch.queue)
+		branch.metrics.Queued(-remaining)
+		branch.metrics.Dropped(remaining)
+		dropped += remaining
+	}
+	return dropped
+}
+
+// recordDropped adds to the number of dropped entries. If no warning has been logged
+// within fanoutDropWarningInterval, it returns the number dropped since the last one
+// and true, and the count is reset.
+func (f *fanout) recordDropped(n int, now time.Time) (int, bool) {
+	f.dropMux.Lock()
+	defer f.dropMux.Unlock()
+
+	f.dropped += n
+	if now.Sub(f.lastWarning) < fanoutDropWarningInterval {
+		return 0, false
+	}
+
+	dropped := f.dropped
+	f.dropped = 0
+	f.lastWarning = now
+	return dropped, true
+}
+
+// writeAsync queues an entry for each output of the writer. Entries dropped because
+// a queue is full, the context is done, or the fan-out has stopped, are counted, and
+// reported in a warning at most once per fanoutDropWarningInterval.
+func (w *WriterOperator) writeAsync(ctx context.Context, e *entry.Entry) {
+	dropped := w.fanout.write(ctx, e)
+	if dropped == 0 {
+		return
+	}
+
+	if n, ok := w.fanout.recordDropped(dropped, time.Now()); ok {
+		w.Warnw("Dropped entries for outputs with a full or stopped fan-out queue", "dropped", n)
+	}
+}
+
+// StartFanout starts a goroutine per output of the writer if it is configured for
+// asynchronous fan-out, replacing any fan-out it already had. It is called when the
+// writer is started, after its outputs have been set.
+func (w *WriterOperator) StartFanout() {
+	if w.FanoutConfig.Mode != AsyncFanout {
+		return
+	}
+	w.StopFanout(context.Background())
+	w.fanout = newFanout(w.ID(), w.OutputOperators, w.FanoutConfig, w.writeTo)
+}
+
+// StopFanout stops the asynchronous fan-out of the writer, if it has one, waiting until
+// all queued entries have been written to the outputs or the context is done. It returns
+// the number of queued entries that were dropped.
+func (w *WriterOperator) StopFanout(ctx context.Context) int {
+	if w.fanout == nil {
+		return 0
+	}
+	return w.fanout.stop(ctx)
+}
@@ -0,0 +1,231 @@
+package helper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAsyncWriter(t *testing.T, fanoutConfig FanoutConfig, outputs ...operator.Operator) WriterOperator {
+	config := NewWriterConfig("test-id", "test-type")
+	config.Fanout = fanoutConfig
+	config.Fanout.Mode = AsyncFanout
+	for _, output := range outputs {
+		config.OutputIDs = append(config.OutputIDs, output.ID())
+	}
+
+	writer, err := config.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.NoError(t, writer.SetOutputs(outputs))
+	writer.StartFanout()
+	t.Cleanup(func() { writer.StopFanout(context.Background()) })
+	return writer
+}
+
+func TestFanoutConfigInvalidMode(t *testing.T) {
+	config := NewWriterConfig("test-id", "test-type")
+	config.Fanout.Mode = "invalid"
+	_, err := config.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fanout mode")
+}
+
+func TestFanoutConfigInvalidWhenFull(t *testing.T) {
+	config := NewWriterConfig("test-id", "test-type")
+	config.Fanout.WhenFull = "invalid"
+	_, err := config.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fanout when_full")
+}
+
+func TestFanoutAsyncWrite(t *testing.T) {
+	fake := testutil.NewFakeOutput(t)
+	other := testutil.NewMockOperator("$.other")
+	other.On("Process", mock.Anything, mock.Anything).Return(nil)
+	writer := newAsyncWriter(t, FanoutConfig{}, other, fake)
+
+	e := entry.New()
+	writer.Write(context.Background(), e)
+	fake.ExpectEntry(t, e)
+
+	require.Equal(t, 0, writer.StopFanout(context.Background()))
+	other.AssertNumberOfCalls(t, "Process", 1)
+}
+
+func TestFanoutSlowOutputIsolated(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	slow := testutil.NewMockOperator("$.slow")
+	slow.On("Process", mock.Anything, mock.Anything).Run(func(mock.Arguments) { <-block }).Return(nil)
+	fake := testutil.NewFakeOutput(t)
+	writer := newAsyncWriter(t, FanoutConfig{QueueSize: 1, WhenFull: DropWhenFull}, slow, fake)
+
+	// The slow output holds one entry and queues one more, so the rest are dropped for it
+	for i := 0; i < 5; i++ {
+		e := entry.New()
+		writer.Write(context.Background(), e)
+		fake.ExpectEntry(t, e)
+	}
+}
+
+func TestFanoutBlocksWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	slow := testutil.NewMockOperator("$.slow")
+	slow.On("Process", mock.Anything, mock.Anything).Run(func(mock.Arguments) { <-block }).Return(nil)
+	writer := newAsyncWriter(t, FanoutConfig{QueueSize: 1}, slow)
+
+	// The slow output holds one entry and queues one more, so the third write blocks
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 3; i++ {
+			writer.Write(context.Background(), entry.New())
+		}
+	}()
+
+	select {
+	case <-written:
+		require.FailNow(t, "write to a full queue did not block")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(block)
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for write")
+	}
+	require.Equal(t, 0, writer.StopFanout(context.Background()))
+	slow.AssertNumberOfCalls(t, "Process", 3)
+}
+
+func TestFanoutStopReleasesBlockedWrite(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	slow := testutil.NewMockOperator("$.slow")
+	slow.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case <-block:
+		case <-args.Get(0).(context.Context).Done():
+		}
+	}).Return(nil)
+	writer := newAsyncWriter(t, FanoutConfig{QueueSize: 1}, slow)
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 3; i++ {
+			writer.Write(context.Background(), entry.New())
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The blocked write is dropped, and the entry still queued is dropped at the deadline
+	require.Equal(t, 1, writer.StopFanout(ctx))
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for blocked write")
+	}
+}
+
+func TestFanoutStopDrainsQueue(t *testing.T) {
+	block := make(chan struct{})
+	slow := testutil.NewMockOperator("$.slow")
+	slow.On("Process", mock.Anything, mock.Anything).Run(func(mock.Arguments) { <-block }).Return(nil)
+	writer := newAsyncWriter(t, FanoutConfig{QueueSize: 10}, slow)
+
+	for i := 0; i < 3; i++ {
+		writer.Write(context.Background(), entry.New())
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		writer.StopFanout(context.Background())
+	}()
+
+	close(block)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		require.FailNow(t, "timed out waiting for fanout to stop")
+	}
+	slow.AssertNumberOfCalls(t, "Process", 3)
+
+	// Entries written after stopping are dropped
+	writer.Write(context.Background(), entry.New())
+	slow.AssertNumberOfCalls(t, "Process", 3)
+}
+
+func TestFanoutStopDeadline(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	slow := testutil.NewMockOperator("$.slow")
+	slow.On("Process", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		select {
+		case <-block:
+		case <-args.Get(0).(context.Context).Done():
+		}
+	}).Return(nil)
+	writer := newAsyncWriter(t, FanoutConfig{QueueSize: 10}, slow)
+
+	for i := 0; i < 3; i++ {
+		writer.Write(context.Background(), entry.New())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// One entry is being processed when the deadline is reached, and two are still queued
+	require.Equal(t, 2, writer.StopFanout(ctx))
+}
+
+func TestFanoutStartedByStartFanout(t *testing.T) {
+	config := NewWriterConfig("test-id", "test-type")
+	config.Fanout = FanoutConfig{Mode: AsyncFanout}
+	config.OutputIDs = []string{"$.fake"}
+
+	writer, err := config.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.NoError(t, writer.SetOutputs([]operator.Operator{testutil.NewFakeOutput(t)}))
+
+	// Setting the outputs does not start any goroutines, which would leak if a later
+	// operator in the pipeline failed to build
+	require.Nil(t, writer.fanout)
+
+	writer.StartFanout()
+	defer writer.StopFanout(context.Background())
+	require.NotNil(t, writer.fanout)
+}
+
+func TestFanoutRecordDropped(t *testing.T) {
+	f := &fanout{}
+	now := time.Now()
+
+	n, ok := f.recordDropped(2, now)
+	require.True(t, ok)
+	require.Equal(t, 2, n)
+
+	// Drops within the warning interval are counted towards the next warning
+	_, ok = f.recordDropped(3, now.Add(time.Second))
+	require.False(t, ok)
+	_, ok = f.recordDropped(1, now.Add(2*time.Second))
+	require.False(t, ok)
+
+	n, ok = f.recordDropped(1, now.Add(fanoutDropWarningInterval))
+	require.True(t, ok)
+	require.Equal(t, 5, n)
+}
@@ -0,0 +1,25 @@
+package helper
+
+import "sync"
+
+// HealthStatus records the most recent error of a long-running task of an operator,
+// such as serving a listener, so that it can be reported by a health check. The zero
+// value has no error.
+type HealthStatus struct {
+	mux sync.Mutex
+	err error
+}
+
+// Set records the most recent error of the task. A nil error clears the status.
+func (h *HealthStatus) Set(err error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.err = err
+}
+
+// Err returns the most recent error of the task, or nil if it has none
+func (h *HealthStatus) Err() error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.err
+}
@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthStatus(t *testing.T) {
+	var status HealthStatus
+	require.NoError(t, status.Err())
+
+	status.Set(fmt.Errorf("failed"))
+	require.EqualError(t, status.Err(), "failed")
+
+	status.Set(nil)
+	require.NoError(t, status.Err())
+}
@@ -2,6 +2,8 @@ package helper
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 
 	"github.com/observiq/stanza/entry"
 	"github.com/observiq/stanza/errors"
@@ -9,6 +11,18 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// BackpressureNone ignores backpressure, blocking writes only when a downstream buffer is full
+	BackpressureNone = "none"
+	// BackpressurePause stops an input from reading while backpressure is signalled downstream
+	BackpressurePause = "pause"
+	// BackpressureDrop drops and counts entries while backpressure is signalled downstream
+	BackpressureDrop = "drop"
+
+	// DefaultBackpressure is the policy of an input that does not set one
+	DefaultBackpressure = BackpressurePause
+)
+
 // NewInputConfig creates a new input config with default values.
 func NewInputConfig(operatorID, operatorType string) InputConfig {
 	return InputConfig{
@@ -16,6 +30,7 @@ func NewInputConfig(operatorID, operatorType string) InputConfig {
 		IdentifierConfig: NewIdentifierConfig(),
 		WriterConfig:     NewWriterConfig(operatorID, operatorType),
 		WriteTo:          entry.NewRecordField(),
+		Backpressure:     DefaultBackpressure,
 	}
 }
 
@@ -25,6 +40,7 @@ type InputConfig struct {
 	IdentifierConfig `yaml:",inline"`
 	WriterConfig     `yaml:",inline"`
 	WriteTo          entry.Field `json:"write_to" yaml:"write_to"`
+	Backpressure     string      `json:"backpressure,omitempty" yaml:"backpressure,omitempty"`
 }
 
 // Build will build a base producer.
@@ -44,11 +60,25 @@ func (c InputConfig) Build(context operator.BuildContext) (InputOperator, error)
 		return InputOperator{}, errors.WithDetails(err, "operator_id", c.ID())
 	}
 
+	backpressure := c.Backpressure
+	switch backpressure {
+	case "":
+		backpressure = DefaultBackpressure
+	case BackpressureNone, BackpressurePause, BackpressureDrop:
+	default:
+		return InputOperator{}, errors.NewError(
+			fmt.Sprintf("invalid backpressure policy '%s'", c.Backpressure),
+			"backpressure must be one of none, pause, or drop",
+			"operator_id", c.ID(),
+		)
+	}
+
 	inputOperator := InputOperator{
-		Labeler:        labeler,
-		Identifier:     identifier,
-		WriterOperator: writerOperator,
-		WriteTo:        c.WriteTo,
+		Labeler:            labeler,
+		Identifier:         identifier,
+		WriterOperator:     writerOperator,
+		WriteTo:            c.WriteTo,
+		BackpressurePolicy: backpressure,
 	}
 
 	return inputOperator, nil
@@ -59,7 +89,14 @@ type InputOperator struct {
 	Labeler
 	Identifier
 	WriterOperator
-	WriteTo entry.Field
+	WriteTo            entry.Field
+	BackpressurePolicy string
+
+	// backpressure holds the []*operator.Backpressure signals downstream of the input
+	backpressure atomic.Value
+
+	// pause holds the *operator.Backpressure signal raised while the input is paused
+	pause atomic.Value
 }
 
 // NewEntry will create a new entry using the `write_to`, `labels`, and `resource` configuration.
@@ -93,3 +130,120 @@ func (i *InputOperator) Process(_ context.Context, entry *entry.Entry) error {
 		"Ensure that operator is not configured to receive logs from other operators",
 	)
 }
+
+// SetBackpressure sets the backpressure signals of the operators downstream of the input.
+func (i *InputOperator) SetBackpressure(signals []*operator.Backpressure) {
+	i.backpressure.Store(signals)
+}
+
+// backpressureSignals returns the backpressure signals downstream of the input
+func (i *InputOperator) backpressureSignals() []*operator.Backpressure {
+	signals, _ := i.backpressure.Load().([]*operator.Backpressure)
+	return signals
+}
+
+// Backpressured returns true if any operator downstream of the input is signalling backpressure.
+func (i *InputOperator) Backpressured() bool {
+	for _, signal := range i.backpressureSignals() {
+		if signal.Pressured() {
+			return true
+		}
+	}
+	return false
+}
+
+// pauseSignal returns the signal raised while the input is paused, creating it if needed
+func (i *InputOperator) pauseSignal() *operator.Backpressure {
+	if signal, ok := i.pause.Load().(*operator.Backpressure); ok {
+		return signal
+	}
+	i.pause.CompareAndSwap(nil, operator.NewBackpressure())
+	return i.pause.Load().(*operator.Backpressure)
+}
+
+// Pause stops the input from writing entries until it is resumed
+func (i *InputOperator) Pause() {
+	i.pauseSignal().Set(true)
+}
+
+// Resume lets a paused input write entries again
+func (i *InputOperator) Resume() {
+	i.pauseSignal().Set(false)
+}
+
+// Paused returns true if the input is paused
+func (i *InputOperator) Paused() bool {
+	signal, ok := i.pause.Load().(*operator.Backpressure)
+	return ok && signal.Pressured()
+}
+
+// waitForResume blocks while the input is paused. It returns an error if the
+// context is done first.
+func (i *InputOperator) waitForResume(ctx context.Context) error {
+	signal, ok := i.pause.Load().(*operator.Backpressure)
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-signal.Released():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitForCapacity blocks while the input is paused, or while backpressure is signalled
+// downstream of the input if the input uses the pause policy. It returns an error if
+// the context is done first.
+func (i *InputOperator) WaitForCapacity(ctx context.Context) error {
+	if err := i.waitForResume(ctx); err != nil {
+		return err
+	}
+
+	if i.BackpressurePolicy != BackpressurePause {
+		return nil
+	}
+
+	signals := i.backpressureSignals()
+
+	// Wait until every signal has been released at the same time
+	for waited := true; waited; {
+		waited = false
+		for _, signal := range signals {
+			if !signal.Pressured() {
+				continue
+			}
+			waited = true
+			select {
+			case <-signal.Released():
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// Write will write an entry to the outputs of the input, applying its backpressure
+// policy. Under the pause policy, it blocks until backpressure is released. Under the
+// drop policy, the entry is dropped and counted while backpressure is signalled.
+// While the input is paused, Write blocks until it is resumed.
+func (i *InputOperator) Write(ctx context.Context, e *entry.Entry) {
+	if err := i.waitForResume(ctx); err != nil {
+		return
+	}
+
+	switch i.BackpressurePolicy {
+	case BackpressurePause:
+		if err := i.WaitForCapacity(ctx); err != nil {
+			return
+		}
+	case BackpressureDrop:
+		if i.Backpressured() {
+			i.RecordDropped()
+			return
+		}
+	}
+	i.WriterOperator.Write(ctx, e)
+}
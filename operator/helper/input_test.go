@@ -3,8 +3,10 @@ package helper
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
 	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -131,3 +133,103 @@ func TestInputOperatorNewEntry(t *testing.T) {
 	require.True(t, exists)
 	require.Equal(t, "resource", resourceValue)
 }
+
+func TestInputConfigInvalidBackpressure(t *testing.T) {
+	config := NewInputConfig("test-id", "test-type")
+	config.Backpressure = "invalid"
+	_, err := config.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid backpressure policy")
+}
+
+func TestInputConfigDefaultBackpressure(t *testing.T) {
+	input, err := NewInputConfig("test-id", "test-type").Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.Equal(t, BackpressurePause, input.BackpressurePolicy)
+
+	// An input config that is not created by NewInputConfig has the same default
+	config := InputConfig{WriterConfig: NewWriterConfig("test-id", "test-type")}
+	input, err = config.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.Equal(t, BackpressurePause, input.BackpressurePolicy)
+}
+
+func newBackpressureTestInput(t *testing.T, policy string) (*InputOperator, *testutil.FakeOutput, *operator.Backpressure) {
+	config := NewInputConfig("test-id", "test-type")
+	config.Backpressure = policy
+	input, err := config.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+
+	fake := testutil.NewFakeOutput(t)
+	input.OutputOperators = []operator.Operator{fake}
+
+	signal := operator.NewBackpressure()
+	input.SetBackpressure([]*operator.Backpressure{signal})
+	return &input, fake, signal
+}
+
+func TestInputOperatorBackpressure(t *testing.T) {
+	t.Run("None", func(t *testing.T) {
+		input, fake, signal := newBackpressureTestInput(t, BackpressureNone)
+		signal.Set(true)
+		require.True(t, input.Backpressured())
+
+		e := entry.New()
+		input.Write(context.Background(), e)
+		fake.ExpectEntry(t, e)
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		input, fake, signal := newBackpressureTestInput(t, BackpressureDrop)
+		signal.Set(true)
+		input.Write(context.Background(), entry.New())
+		fake.ExpectNoEntry(t, 100*time.Millisecond)
+
+		signal.Set(false)
+		e := entry.New()
+		input.Write(context.Background(), e)
+		fake.ExpectEntry(t, e)
+	})
+
+	t.Run("Pause", func(t *testing.T) {
+		input, fake, signal := newBackpressureTestInput(t, BackpressurePause)
+		signal.Set(true)
+
+		e := entry.New()
+		go input.Write(context.Background(), e)
+		fake.ExpectNoEntry(t, 100*time.Millisecond)
+
+		signal.Set(false)
+		fake.ExpectEntry(t, e)
+	})
+
+	t.Run("PauseCancelled", func(t *testing.T) {
+		input, _, signal := newBackpressureTestInput(t, BackpressurePause)
+		signal.Set(true)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Error(t, input.WaitForCapacity(ctx))
+	})
+}
+
+func TestInputOperatorPause(t *testing.T) {
+	input, fake, _ := newBackpressureTestInput(t, BackpressureDrop)
+	require.False(t, input.Paused())
+
+	input.Pause()
+	require.True(t, input.Paused())
+
+	e := entry.New()
+	go input.Write(context.Background(), e)
+	fake.ExpectNoEntry(t, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, input.WaitForCapacity(ctx))
+
+	input.Resume()
+	require.False(t, input.Paused())
+	fake.ExpectEntry(t, e)
+	require.NoError(t, input.WaitForCapacity(context.Background()))
+}
@@ -20,6 +20,8 @@ import (
 	"path"
 	"testing"
 
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/testutil"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
 )
@@ -55,3 +57,18 @@ func (c ConfigUnmarshalTest) Run(t *testing.T, config interface{}) {
 		require.Equal(t, c.Expect, yamlConfig)
 	}
 }
+
+// ConfigBuildTest is used for testing golden configs that fail to build
+type ConfigBuildTest struct {
+	Name      string
+	ExpectErr string
+}
+
+// Run Unmarshalls a yaml file, builds it, and checks that the build fails with the expected error.
+func (c ConfigBuildTest) Run(t *testing.T, config operator.Builder) {
+	require.NoError(t, configFromFileViaYaml(path.Join(".", "testdata", fmt.Sprintf("%s.yaml", c.Name)), config))
+
+	_, err := config.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), c.ExpectErr)
+}
@@ -1,8 +1,13 @@
 package helper
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/observiq/stanza/entry"
 	"github.com/observiq/stanza/errors"
 	"github.com/observiq/stanza/operator"
+	"go.uber.org/zap"
 )
 
 // NewOutputConfig creates a new output config
@@ -14,11 +19,25 @@ func NewOutputConfig(operatorID, operatorType string) OutputConfig {
 
 // OutputConfig provides a basic implementation of an output operator config.
 type OutputConfig struct {
-	BasicConfig `mapstructure:",squash" yaml:",inline"`
+	BasicConfig      `mapstructure:",squash" yaml:",inline"`
+	DeadLetterOutput string `mapstructure:"dead_letter_output" json:"dead_letter_output,omitempty" yaml:"dead_letter_output,omitempty"`
 }
 
-// Build will build an output operator.
+// Build will build an output operator that does not support a dead letter output.
 func (c OutputConfig) Build(context operator.BuildContext) (OutputOperator, error) {
+	if c.DeadLetterOutput != "" {
+		return OutputOperator{}, errors.NewError(
+			fmt.Sprintf("the %s operator does not support 'dead_letter_output'", c.Type()),
+			"remove 'dead_letter_output' from the operator, or send entries to an output that retries them with a flusher",
+			"operator_id", c.ID(),
+		)
+	}
+	return c.BuildWithDeadLetter(context)
+}
+
+// BuildWithDeadLetter will build an output operator that sends the entries it fails
+// to deliver to its dead letter output, if one is configured.
+func (c OutputConfig) BuildWithDeadLetter(context operator.BuildContext) (OutputOperator, error) {
 	basicOperator, err := c.BasicConfig.Build(context)
 	if err != nil {
 		return OutputOperator{}, err
@@ -26,6 +45,7 @@ func (c OutputConfig) Build(context operator.BuildContext) (OutputOperator, erro
 
 	outputOperator := OutputOperator{
 		BasicOperator: basicOperator,
+		DeadLetter:    NewDeadLetter(c.DeadLetterOutput, context),
 	}
 
 	return outputOperator, nil
@@ -34,27 +54,67 @@ func (c OutputConfig) Build(context operator.BuildContext) (OutputOperator, erro
 // OutputOperator provides a basic implementation of an output operator.
 type OutputOperator struct {
 	BasicOperator
+	DeadLetter DeadLetter
+}
+
+// Output is implemented by the operators that send entries out of the pipeline,
+// which all embed an OutputOperator. Unlike CanOutput, it does not depend on
+// whether the operator has a dead letter output.
+type Output interface {
+	operator.Operator
+	isOutput()
+}
+
+func (o *OutputOperator) isOutput() {}
+
+// OutputBuilder is implemented by the configs of outputs, which all embed an
+// OutputConfig, so that an output can be recognized without building it.
+type OutputBuilder interface {
+	operator.Builder
+	isOutputBuilder()
 }
 
+func (c OutputConfig) isOutputBuilder() {}
+
 // CanProcess will always return true for an output operator.
 func (o *OutputOperator) CanProcess() bool {
 	return true
 }
 
-// CanOutput will always return false for an output operator.
+// CanOutput will return true only if the output operator has a dead letter output.
 func (o *OutputOperator) CanOutput() bool {
-	return false
+	return o.DeadLetter.Enabled()
 }
 
-// Outputs will always return an empty array for an output operator.
+// Outputs will return the dead letter output if one is configured, and an empty array otherwise.
 func (o *OutputOperator) Outputs() []operator.Operator {
-	return []operator.Operator{}
+	return o.DeadLetter.appendOutput([]operator.Operator{})
+}
+
+// SetOutputs will set the dead letter output, or return an error if none is configured.
+func (o *OutputOperator) SetOutputs(operators []operator.Operator) error {
+	if !o.DeadLetter.Enabled() {
+		return errors.NewError(
+			"Operator can not output, but is attempting to set an output.",
+			"This is an unexpected internal error. Please submit a bug/issue.",
+		)
+	}
+	return o.DeadLetter.SetOutput(operators)
 }
 
-// SetOutputs will return an error if called.
-func (o *OutputOperator) SetOutputs(_ []operator.Operator) error {
-	return errors.NewError(
-		"Operator can not output, but is attempting to set an output.",
-		"This is an unexpected internal error. Please submit a bug/issue.",
-	)
+// SendToDeadLetter will send entries that could not be delivered to the dead letter output.
+// It returns false if the entries could not be sent, in which case they are dropped.
+func (o *OutputOperator) SendToDeadLetter(ctx context.Context, entries []*entry.Entry, err error) bool {
+	if !o.DeadLetter.Enabled() {
+		return false
+	}
+
+	sent := true
+	for _, e := range entries {
+		if dlErr := o.DeadLetter.Send(ctx, o.ID(), e, err); dlErr != nil {
+			o.Errorw("Failed to send entry to dead letter output", zap.Any("error", dlErr))
+			sent = false
+		}
+	}
+	return sent
 }
@@ -1,10 +1,14 @@
 package helper
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
+	"github.com/observiq/stanza/entry"
 	"github.com/observiq/stanza/operator"
 	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -78,3 +82,28 @@ func TestOutputOperatorSetOutputs(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Operator can not output")
 }
+
+func TestOutputOperatorDeadLetter(t *testing.T) {
+	cfg := NewOutputConfig("test-id", "test-type")
+	cfg.DeadLetterOutput = "test-dead-letter"
+	_, err := cfg.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not support 'dead_letter_output'")
+
+	output, err := cfg.BuildWithDeadLetter(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	require.True(t, output.CanOutput())
+
+	deadLetter := testutil.NewMockOperator("$.test-dead-letter")
+	deadLetter.On("Process", mock.Anything, mock.Anything).Return(nil)
+	err = output.SetOutputs([]operator.Operator{deadLetter})
+	require.NoError(t, err)
+	require.Equal(t, []operator.Operator{deadLetter}, output.Outputs())
+
+	entries := []*entry.Entry{entry.New(), entry.New()}
+	sent := output.SendToDeadLetter(context.Background(), entries, fmt.Errorf("unreachable"))
+	require.True(t, sent)
+	deadLetter.AssertNumberOfCalls(t, "Process", 2)
+	require.Equal(t, "$.test-id", entries[0].Labels[DeadLetterOperatorLabel])
+	require.Equal(t, "unreachable", entries[0].Labels[DeadLetterErrorLabel])
+}
@@ -141,6 +141,11 @@ func (c *SeverityParserConfig) Build(_ operator.BuildContext) (SeverityParser, e
 	return p, nil
 }
 
+// ParseSeverity parses a severity from its name, one of its aliases, or its number
+func ParseSeverity(severity interface{}) (entry.Severity, error) {
+	return validateSeverity(severity)
+}
+
 func validateSeverity(severity interface{}) (entry.Severity, error) {
 	if sev, _, err := getBuiltinMapping("aliases").find(severity); err != nil {
 		return entry.Default, err
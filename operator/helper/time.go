@@ -91,11 +91,24 @@ func (t *TimeParser) Validate(_ operator.BuildContext) error {
 		if err := t.setLocation(); err != nil {
 			return errors.Wrap(err, "invalid 'location'")
 		}
+		if err := checkLayout(t.Layout); err != nil {
+			return errors.Wrap(err, "invalid 'layout'")
+		}
 	}
 
 	return nil
 }
 
+// checkLayout returns an error if a time formatted with a go time layout
+// can not be parsed with the same layout
+func checkLayout(layout string) error {
+	formatted := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC).Format(layout)
+	if _, err := time.Parse(layout, formatted); err != nil {
+		return fmt.Errorf("layout can not parse its own output: %s", err)
+	}
+	return nil
+}
+
 func (t *TimeParser) setLocation() error {
 	if t.Location != "" {
 		// If "location" is specified, it must be in the local timezone database
@@ -522,6 +522,12 @@ func TestTimeErrors(t *testing.T) {
 			location:   "fake",
 			buildErr:   true,
 		},
+		{
+			name:       "bad-gotime-layout",
+			layoutType: "gotime",
+			layout:     "12006",
+			buildErr:   true,
+		},
 		{
 			name:       "bad-epoch-layout",
 			layoutType: "epoch",
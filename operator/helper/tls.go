@@ -0,0 +1,312 @@
+package helper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// NoClientAuth does not request certificates from clients
+	NoClientAuth = "none"
+	// OptionalClientAuth verifies the certificates of clients that send one
+	OptionalClientAuth = "optional"
+	// RequiredClientAuth requires clients to send a verified certificate
+	RequiredClientAuth = "required"
+
+	// TLSClientSubjectLabel is the label set to the subject of a client's verified certificate
+	TLSClientSubjectLabel = "tls.client.subject"
+)
+
+// tlsPollInterval is the minimum duration between checks of the TLS files for changes
+var tlsPollInterval = 10 * time.Second
+
+// TLSServerConfig is the configuration of a TLS listener
+type TLSServerConfig struct {
+	// Enable forces the user of TLS
+	Enable bool `json:"enable,omitempty" yaml:"enable,omitempty"`
+
+	// Certificate is the file path for the certificate
+	Certificate string `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+
+	// PrivateKey is the file path for the private key
+	PrivateKey string `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+
+	// MinVersion is the minimum tls version
+	MinVersion float32 `json:"min_version,omitempty" yaml:"min_version,omitempty"`
+
+	// ClientCA is the file path for the certificate authorities that client certificates are verified with
+	ClientCA string `json:"client_ca,omitempty" yaml:"client_ca,omitempty"`
+
+	// ClientAuth is whether clients must send a certificate
+	ClientAuth string `json:"client_auth,omitempty" yaml:"client_auth,omitempty"`
+
+	// CipherSuites are the names of the cipher suites accepted for TLS 1.2 and earlier
+	CipherSuites []string `json:"cipher_suites,omitempty" yaml:"cipher_suites,omitempty"`
+}
+
+// Build will build the tls.Config of a listener, or nil if TLS is not enabled. The
+// certificate and client CA are reloaded when their files change.
+func (c TLSServerConfig) Build(logger *zap.SugaredLogger, defaultMinVersion uint16) (*tls.Config, error) {
+	minVersion, err := c.minVersion(defaultMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Enable {
+		return nil, nil
+	}
+
+	if c.Certificate == "" {
+		return nil, fmt.Errorf("missing required parameter 'certificate', required when TLS is enabled")
+	}
+
+	if c.PrivateKey == "" {
+		return nil, fmt.Errorf("missing required parameter 'private_key', required when TLS is enabled")
+	}
+
+	clientAuth := c.ClientAuth
+	if clientAuth == "" {
+		clientAuth = NoClientAuth
+		if c.ClientCA != "" {
+			clientAuth = RequiredClientAuth
+		}
+	}
+
+	var clientAuthType tls.ClientAuthType
+	switch clientAuth {
+	case NoClientAuth:
+		if c.ClientCA != "" {
+			return nil, fmt.Errorf("client_ca cannot be set with client_auth '%s'", NoClientAuth)
+		}
+		clientAuthType = tls.NoClientCert
+	case OptionalClientAuth, RequiredClientAuth:
+		if c.ClientCA == "" {
+			return nil, fmt.Errorf("missing required parameter 'client_ca', required when client_auth is '%s'", clientAuth)
+		}
+		// Certificates are verified by the files, so that a replaced client CA is used
+		clientAuthType = tls.RequestClientCert
+		if clientAuth == RequiredClientAuth {
+			clientAuthType = tls.RequireAnyClientCert
+		}
+	default:
+		return nil, fmt.Errorf("invalid client_auth '%s', must be one of '%s', '%s' or '%s'", clientAuth, NoClientAuth, OptionalClientAuth, RequiredClientAuth)
+	}
+
+	cipherSuites, err := c.cipherSuites()
+	if err != nil {
+		return nil, err
+	}
+
+	files := &tlsFiles{
+		certificate: c.Certificate,
+		privateKey:  c.PrivateKey,
+		clientCA:    c.ClientCA,
+		logger:      logger,
+	}
+	if err := files.load(); err != nil {
+		return nil, err
+	}
+
+	// #nosec - User to specify tls minimum version
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuthType,
+		GetCertificate: files.getCertificate,
+	}
+	if clientAuthType != tls.NoClientCert {
+		config.VerifyPeerCertificate = files.verifyPeerCertificate
+	}
+	return config, nil
+}
+
+func (c TLSServerConfig) minVersion(defaultMinVersion uint16) (uint16, error) {
+	switch c.MinVersion {
+	case 0:
+		return defaultMinVersion, nil
+	case 1.0:
+		return tls.VersionTLS10, nil
+	case 1.1:
+		return tls.VersionTLS11, nil
+	case 1.2:
+		return tls.VersionTLS12, nil
+	case 1.3:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version: %f", c.MinVersion)
+	}
+}
+
+func (c TLSServerConfig) cipherSuites() ([]uint16, error) {
+	if len(c.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	supported := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite
+	}
+
+	ids := make([]uint16, 0, len(c.CipherSuites))
+	for _, name := range c.CipherSuites {
+		suite, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite '%s'", name)
+		}
+
+		configurable := false
+		for _, version := range suite.SupportedVersions {
+			if version < tls.VersionTLS13 {
+				configurable = true
+			}
+		}
+		if !configurable {
+			return nil, fmt.Errorf("cipher suite '%s' is only used by TLS 1.3, whose cipher suites cannot be configured", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
+}
+
+// TLSClientSubject returns the subject of the certificate of a client, if it sent
+// one. Listeners built from a TLSServerConfig only accept verified certificates.
+func TLSClientSubject(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+	return state.PeerCertificates[0].Subject.String(), true
+}
+
+// tlsFiles holds the certificate and client CA of a listener, and reloads them
+// when their files change
+type tlsFiles struct {
+	certificate string
+	privateKey  string
+	clientCA    string
+	logger      *zap.SugaredLogger
+
+	mux       sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	states    map[string]tlsFileState
+	checkedAt time.Time
+}
+
+type tlsFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// load reads the files. The current state of the files is recorded even if they
+// fail to load, so that they are only reloaded once they change again.
+func (f *tlsFiles) load() error {
+	f.states = f.currentStates()
+	f.checkedAt = time.Now()
+
+	cert, err := tls.LoadX509KeyPair(f.certificate, f.privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if f.clientCA != "" {
+		pem, err := os.ReadFile(f.clientCA)
+		if err != nil {
+			return fmt.Errorf("failed to load client_ca: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("failed to load client_ca: no certificates found in %s", f.clientCA)
+		}
+	}
+
+	f.cert = &cert
+	f.clientCAs = clientCAs
+	return nil
+}
+
+func (f *tlsFiles) currentStates() map[string]tlsFileState {
+	states := make(map[string]tlsFileState)
+	for _, path := range []string{f.certificate, f.privateKey, f.clientCA} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			states[path] = tlsFileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
+
+// reloadIfChanged reloads the files if they have changed since they were last
+// checked. If they fail to load, the previous certificate and client CA are kept.
+func (f *tlsFiles) reloadIfChanged() {
+	if time.Since(f.checkedAt) < tlsPollInterval {
+		return
+	}
+	f.checkedAt = time.Now()
+
+	states := f.currentStates()
+	changed := len(states) != len(f.states)
+	for path, state := range states {
+		if f.states[path] != state {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := f.load(); err != nil {
+		f.logger.Errorw("Failed to reload TLS files, continuing with the previous ones", zap.Error(err))
+		return
+	}
+	f.logger.Infow("Reloaded TLS files", "certificate", f.certificate, "client_ca", f.clientCA)
+}
+
+func (f *tlsFiles) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.reloadIfChanged()
+	return f.cert, nil
+}
+
+// verifyPeerCertificate verifies a client's certificate with the current client CA
+func (f *tlsFiles) verifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		// Whether a certificate is required is enforced by the handshake
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	f.mux.Lock()
+	roots := f.clientCAs
+	f.mux.Unlock()
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return fmt.Errorf("failed to verify client certificate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package helper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// writeTLSFiles writes a server certificate and key issued by a new CA, and a
+// client CA, returning the config of a TLS listener using them
+func writeTLSFiles(t *testing.T, clientCA *testutil.CertificateAuthority) (TLSServerConfig, *testutil.CertificateAuthority) {
+	dir := testutil.NewTempDir(t)
+	serverCA := testutil.NewCertificateAuthority(t, "server-ca")
+	certPEM, keyPEM := serverCA.Issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+
+	config := TLSServerConfig{
+		Enable:      true,
+		Certificate: filepath.Join(dir, "server.crt"),
+		PrivateKey:  filepath.Join(dir, "server.key"),
+		ClientCA:    filepath.Join(dir, "client-ca.crt"),
+	}
+	require.NoError(t, os.WriteFile(config.Certificate, certPEM, 0600))
+	require.NoError(t, os.WriteFile(config.PrivateKey, keyPEM, 0600))
+	require.NoError(t, os.WriteFile(config.ClientCA, clientCA.CertPEM(), 0600))
+	return config, serverCA
+}
+
+// startTLSListener accepts connections with the config, and sends the subject of
+// the certificate of each client that completes a handshake
+func startTLSListener(t *testing.T, config *tls.Config) (string, <-chan string) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	subjects := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			tlsConn := conn.(*tls.Conn)
+			if err := tlsConn.Handshake(); err == nil {
+				state := tlsConn.ConnectionState()
+				subject, _ := TLSClientSubject(&state)
+				subjects <- subject
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), subjects
+}
+
+// dialTLS connects to a listener and reads until it is closed, since TLS 1.3 servers
+// reject client certificates after the client's handshake completes
+func dialTLS(address string, serverCA *testutil.CertificateAuthority, certs ...tls.Certificate) (*x509.Certificate, error) {
+	conn, err := tls.Dial("tcp", address, &tls.Config{
+		RootCAs:      serverCA.CertPool(),
+		ServerName:   "localhost",
+		Certificates: certs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := io.ReadAll(conn); err != nil {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestTLSServerConfigBuild(t *testing.T) {
+	clientCA := testutil.NewCertificateAuthority(t, "client-ca")
+
+	cases := []struct {
+		name     string
+		modify   func(*TLSServerConfig)
+		expected string
+	}{
+		{
+			"InvalidMinVersionWhenDisabled",
+			func(cfg *TLSServerConfig) { cfg.Enable = false; cfg.MinVersion = 1.4 },
+			"unsupported tls version",
+		},
+		{
+			"MissingCertificate",
+			func(cfg *TLSServerConfig) { cfg.Certificate = "" },
+			"missing required parameter 'certificate'",
+		},
+		{
+			"MissingCertificateFile",
+			func(cfg *TLSServerConfig) { cfg.Certificate = "/missing/server.crt" },
+			"failed to load tls certificate",
+		},
+		{
+			"InvalidClientAuth",
+			func(cfg *TLSServerConfig) { cfg.ClientAuth = "always" },
+			"invalid client_auth 'always'",
+		},
+		{
+			"ClientAuthWithoutClientCA",
+			func(cfg *TLSServerConfig) { cfg.ClientCA = ""; cfg.ClientAuth = OptionalClientAuth },
+			"missing required parameter 'client_ca', required when client_auth is 'optional'",
+		},
+		{
+			"ClientCAWithoutClientAuth",
+			func(cfg *TLSServerConfig) { cfg.ClientAuth = NoClientAuth },
+			"client_ca cannot be set with client_auth 'none'",
+		},
+		{
+			"InvalidClientCA",
+			func(cfg *TLSServerConfig) { cfg.ClientCA = cfg.PrivateKey },
+			"failed to load client_ca: no certificates found",
+		},
+		{
+			"UnsupportedCipherSuite",
+			func(cfg *TLSServerConfig) { cfg.CipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"} },
+			"unsupported cipher suite 'TLS_RSA_WITH_RC4_128_SHA'",
+		},
+		{
+			"TLS13CipherSuite",
+			func(cfg *TLSServerConfig) { cfg.CipherSuites = []string{"TLS_AES_128_GCM_SHA256"} },
+			"cipher suite 'TLS_AES_128_GCM_SHA256' is only used by TLS 1.3",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, _ := writeTLSFiles(t, clientCA)
+			tc.modify(&cfg)
+			_, err := cfg.Build(zaptest.NewLogger(t).Sugar(), tls.VersionTLS12)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expected)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		config, err := TLSServerConfig{Certificate: "/missing/server.crt"}.Build(zaptest.NewLogger(t).Sugar(), tls.VersionTLS12)
+		require.NoError(t, err)
+		require.Nil(t, config)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		cfg, _ := writeTLSFiles(t, clientCA)
+		cfg.CipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}
+		config, err := cfg.Build(zaptest.NewLogger(t).Sugar(), tls.VersionTLS12)
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+		require.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, config.CipherSuites)
+		require.Equal(t, tls.RequireAnyClientCert, config.ClientAuth)
+	})
+}
+
+func TestTLSServerClientAuth(t *testing.T) {
+	clientCA := testutil.NewCertificateAuthority(t, "client-ca")
+	otherCA := testutil.NewCertificateAuthority(t, "other-ca")
+	clientCert := clientCA.IssueKeyPair(t, "web-01", x509.ExtKeyUsageClientAuth)
+
+	t.Run("Required", func(t *testing.T) {
+		cfg, serverCA := writeTLSFiles(t, clientCA)
+		config, err := cfg.Build(zaptest.NewLogger(t).Sugar(), tls.VersionTLS12)
+		require.NoError(t, err)
+		address, subjects := startTLSListener(t, config)
+
+		_, err = dialTLS(address, serverCA, clientCert)
+		require.NoError(t, err)
+		require.Equal(t, "CN=web-01,O=stanza", <-subjects)
+
+		_, err = dialTLS(address, serverCA)
+		require.Error(t, err)
+
+		_, err = dialTLS(address, serverCA, otherCA.IssueKeyPair(t, "web-02", x509.ExtKeyUsageClientAuth))
+		require.Error(t, err)
+
+		_, err = dialTLS(address, serverCA, clientCA.IssueKeyPair(t, "web-03", x509.ExtKeyUsageServerAuth))
+		require.Error(t, err)
+	})
+
+	t.Run("Optional", func(t *testing.T) {
+		cfg, serverCA := writeTLSFiles(t, clientCA)
+		cfg.ClientAuth = OptionalClientAuth
+		config, err := cfg.Build(zaptest.NewLogger(t).Sugar(), tls.VersionTLS12)
+		require.NoError(t, err)
+		address, subjects := startTLSListener(t, config)
+
+		_, err = dialTLS(address, serverCA)
+		require.NoError(t, err)
+		require.Equal(t, "", <-subjects)
+
+		_, err = dialTLS(address, serverCA, clientCert)
+		require.NoError(t, err)
+		require.Equal(t, "CN=web-01,O=stanza", <-subjects)
+
+		_, err = dialTLS(address, serverCA, otherCA.IssueKeyPair(t, "web-02", x509.ExtKeyUsageClientAuth))
+		require.Error(t, err)
+	})
+}
+
+func TestTLSServerReload(t *testing.T) {
+	defer func(interval time.Duration) { tlsPollInterval = interval }(tlsPollInterval)
+	tlsPollInterval = 0
+
+	clientCA := testutil.NewCertificateAuthority(t, "client-ca")
+	cfg, serverCA := writeTLSFiles(t, clientCA)
+	config, err := cfg.Build(zaptest.NewLogger(t).Sugar(), tls.VersionTLS12)
+	require.NoError(t, err)
+	address, subjects := startTLSListener(t, config)
+
+	first, err := dialTLS(address, serverCA, clientCA.IssueKeyPair(t, "web-01", x509.ExtKeyUsageClientAuth))
+	require.NoError(t, err)
+	<-subjects
+
+	// A replaced certificate and client CA are used for new connections
+	newClientCA := testutil.NewCertificateAuthority(t, "new-client-ca")
+	certPEM, keyPEM := serverCA.Issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, os.WriteFile(cfg.Certificate, certPEM, 0600))
+	require.NoError(t, os.WriteFile(cfg.PrivateKey, keyPEM, 0600))
+	require.NoError(t, os.WriteFile(cfg.ClientCA, append(newClientCA.CertPEM(), '\n'), 0600))
+
+	second, err := dialTLS(address, serverCA, newClientCA.IssueKeyPair(t, "web-01", x509.ExtKeyUsageClientAuth))
+	require.NoError(t, err)
+	<-subjects
+	require.NotEqual(t, first.SerialNumber, second.SerialNumber)
+
+	_, err = dialTLS(address, serverCA, clientCA.IssueKeyPair(t, "web-01", x509.ExtKeyUsageClientAuth))
+	require.Error(t, err)
+
+	// An invalid certificate is not used, and the previous one is kept
+	require.NoError(t, os.WriteFile(cfg.Certificate, []byte("invalid"), 0600))
+	third, err := dialTLS(address, serverCA, newClientCA.IssueKeyPair(t, "web-01", x509.ExtKeyUsageClientAuth))
+	require.NoError(t, err)
+	<-subjects
+	require.Equal(t, second.SerialNumber, third.SerialNumber)
+}
+
+func TestTLSClientSubject(t *testing.T) {
+	_, ok := TLSClientSubject(nil)
+	require.False(t, ok)
+
+	_, ok = TLSClientSubject(&tls.ConnectionState{})
+	require.False(t, ok)
+
+	cert := &x509.Certificate{}
+	cert.Subject.CommonName = "web-01"
+	subject, ok := TLSClientSubject(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+	require.True(t, ok)
+	require.Equal(t, "CN=web-01", subject)
+}
@@ -22,9 +22,10 @@ func NewTransformerConfig(operatorID, operatorType string) TransformerConfig {
 
 // TransformerConfig provides a basic implementation of a transformer config.
 type TransformerConfig struct {
-	WriterConfig `yaml:",inline"`
-	OnError      string `json:"on_error" yaml:"on_error"`
-	IfExpr       string `json:"if"                  yaml:"if"`
+	WriterConfig     `yaml:",inline"`
+	OnError          string `json:"on_error" yaml:"on_error"`
+	DeadLetterOutput string `json:"dead_letter_output,omitempty" yaml:"dead_letter_output,omitempty"`
+	IfExpr           string `json:"if"                  yaml:"if"`
 }
 
 // Build will build a transformer operator.
@@ -36,10 +37,18 @@ func (c TransformerConfig) Build(context operator.BuildContext) (TransformerOper
 
 	switch c.OnError {
 	case SendOnError, DropOnError:
+	case DeadLetterOnError:
+		if c.DeadLetterOutput == "" {
+			return TransformerOperator{}, errors.NewError(
+				"operator config has `on_error` set to `dead_letter`, but no `dead_letter_output`.",
+				"ensure that the `dead_letter_output` field is set to the id of an operator.",
+				"operator_id", c.ID(),
+			)
+		}
 	default:
 		return TransformerOperator{}, errors.NewError(
 			"operator config has an invalid `on_error` field.",
-			"ensure that the `on_error` field is set to either `send` or `drop`.",
+			"ensure that the `on_error` field is set to either `send`, `drop`, or `dead_letter`.",
 			"on_error", c.OnError,
 		)
 	}
@@ -49,6 +58,10 @@ func (c TransformerConfig) Build(context operator.BuildContext) (TransformerOper
 		OnError:        c.OnError,
 	}
 
+	if c.OnError == DeadLetterOnError {
+		transformerOperator.DeadLetter = NewDeadLetter(c.DeadLetterOutput, context)
+	}
+
 	if c.IfExpr != "" {
 		compiled, err := expr.Compile(c.IfExpr, expr.AsBool(), expr.AllowUndefinedVariables())
 		if err != nil {
@@ -63,8 +76,9 @@ func (c TransformerConfig) Build(context operator.BuildContext) (TransformerOper
 // TransformerOperator provides a basic implementation of a transformer operator.
 type TransformerOperator struct {
 	WriterOperator
-	OnError string
-	IfExpr  *vm.Program
+	OnError    string
+	IfExpr     *vm.Program
+	DeadLetter DeadLetter
 }
 
 // CanProcess will always return true for a transformer operator.
@@ -72,6 +86,19 @@ func (t *TransformerOperator) CanProcess() bool {
 	return true
 }
 
+// Outputs returns the outputs of the transformer, including its dead letter output.
+func (t *TransformerOperator) Outputs() []operator.Operator {
+	return t.DeadLetter.appendOutput(t.WriterOperator.Outputs())
+}
+
+// SetOutputs will set the outputs and dead letter output of the transformer.
+func (t *TransformerOperator) SetOutputs(operators []operator.Operator) error {
+	if err := t.WriterOperator.SetOutputs(operators); err != nil {
+		return err
+	}
+	return t.DeadLetter.SetOutput(operators)
+}
+
 // ProcessWith will process an entry with a transform function.
 func (t *TransformerOperator) ProcessWith(ctx context.Context, entry *entry.Entry, transform TransformFunction) error {
 	// Short circuit if the "if" condition does not match
@@ -94,8 +121,16 @@ func (t *TransformerOperator) ProcessWith(ctx context.Context, entry *entry.Entr
 // HandleEntryError will handle an entry error using the on_error strategy.
 func (t *TransformerOperator) HandleEntryError(ctx context.Context, entry *entry.Entry, err error) error {
 	t.Errorw("Failed to process entry", zap.Any("error", err), zap.Any("action", t.OnError), zap.Any("entry", entry))
-	if t.OnError == SendOnError {
+	switch t.OnError {
+	case SendOnError:
 		t.Write(ctx, entry)
+	case DeadLetterOnError:
+		if dlErr := t.DeadLetter.Send(ctx, t.ID(), entry, err); dlErr != nil {
+			t.Errorw("Failed to send entry to dead letter output", zap.Any("error", dlErr))
+			t.RecordDropped()
+		}
+	default:
+		t.RecordDropped()
 	}
 	return err
 }
@@ -125,3 +160,6 @@ const SendOnError = "send"
 
 // DropOnError specifies an on_error mode for dropping entries after an error.
 const DropOnError = "drop"
+
+// DeadLetterOnError specifies an on_error mode for sending entries to a dead letter output after an error.
+const DeadLetterOnError = "dead_letter"
@@ -48,6 +48,29 @@ func TestTransformerOnErrorInvalid(t *testing.T) {
 	require.Contains(t, err.Error(), "operator config has an invalid `on_error` field.")
 }
 
+func TestTransformerOnErrorDeadLetterMissingOutput(t *testing.T) {
+	cfg := NewTransformerConfig("test", "test")
+	cfg.OnError = DeadLetterOnError
+	_, err := cfg.Build(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no `dead_letter_output`")
+}
+
+func TestTransformerOnErrorDeadLetterOutputs(t *testing.T) {
+	cfg := NewTransformerConfig("test", "test")
+	cfg.OutputIDs = []string{"test-output"}
+	cfg.OnError = DeadLetterOnError
+	cfg.DeadLetterOutput = "test-dead-letter"
+	transformer, err := cfg.Build(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+
+	output := testutil.NewMockOperator("$.test-output")
+	deadLetter := testutil.NewMockOperator("$.test-dead-letter")
+	err = transformer.SetOutputs([]operator.Operator{output, deadLetter})
+	require.NoError(t, err)
+	require.Equal(t, []operator.Operator{output, deadLetter}, transformer.Outputs())
+}
+
 func TestTransformerOperatorCanProcess(t *testing.T) {
 	cfg := NewTransformerConfig("test", "test")
 	transformer, err := cfg.Build(testutil.NewBuildContext(t))
@@ -111,6 +134,44 @@ func TestTransformerSendOnError(t *testing.T) {
 	output.AssertCalled(t, "Process", mock.Anything, mock.Anything)
 }
 
+func TestTransformerDeadLetterOnError(t *testing.T) {
+	output := &testutil.Operator{}
+	output.On("ID").Return("test-output")
+	output.On("Process", mock.Anything, mock.Anything).Return(nil)
+	deadLetter := &testutil.Operator{}
+	deadLetter.On("ID").Return("test-dead-letter")
+	deadLetter.On("Process", mock.Anything, mock.Anything).Return(nil)
+	buildContext := testutil.NewBuildContext(t)
+	transformer := TransformerOperator{
+		OnError: DeadLetterOnError,
+		WriterOperator: WriterOperator{
+			BasicOperator: BasicOperator{
+				OperatorID:    "test-id",
+				OperatorType:  "test-type",
+				SugaredLogger: buildContext.Logger.SugaredLogger,
+			},
+			OutputOperators: []operator.Operator{output},
+			OutputIDs:       []string{"test-output"},
+		},
+		DeadLetter: DeadLetter{
+			OutputID: "test-dead-letter",
+			Output:   deadLetter,
+		},
+	}
+	ctx := context.Background()
+	testEntry := entry.New()
+	transform := func(e *entry.Entry) error {
+		return fmt.Errorf("Failure")
+	}
+
+	err := transformer.ProcessWith(ctx, testEntry, transform)
+	require.Error(t, err)
+	output.AssertNotCalled(t, "Process", mock.Anything, mock.Anything)
+	deadLetter.AssertCalled(t, "Process", mock.Anything, testEntry)
+	require.Equal(t, "test-id", testEntry.Labels[DeadLetterOperatorLabel])
+	require.Equal(t, "Failure", testEntry.Labels[DeadLetterErrorLabel])
+}
+
 func TestTransformerProcessWithValid(t *testing.T) {
 	output := &testutil.Operator{}
 	output.On("ID").Return("test-output")
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/metrics"
 	"github.com/observiq/stanza/operator"
 )
 
@@ -19,7 +22,8 @@ func NewWriterConfig(operatorID, operatorType string) WriterConfig {
 // WriterConfig is the configuration of a writer operator.
 type WriterConfig struct {
 	BasicConfig `yaml:",inline"`
-	OutputIDs   OutputIDs `json:"output" yaml:"output"`
+	OutputIDs   OutputIDs    `json:"output" yaml:"output"`
+	Fanout      FanoutConfig `json:"fanout,omitempty" yaml:"fanout,omitempty"`
 }
 
 // Build will build a writer operator from the config.
@@ -29,6 +33,10 @@ func (c WriterConfig) Build(bc operator.BuildContext) (WriterOperator, error) {
 		return WriterOperator{}, err
 	}
 
+	if err := c.Fanout.validate(); err != nil {
+		return WriterOperator{}, errors.WithDetails(err, "operator_id", basicOperator.ID())
+	}
+
 	// Namespace all the output IDs
 	namespacedIDs := c.OutputIDs.WithNamespace(bc)
 	if len(namespacedIDs) == 0 {
@@ -38,6 +46,7 @@ func (c WriterConfig) Build(bc operator.BuildContext) (WriterOperator, error) {
 	writer := WriterOperator{
 		OutputIDs:     namespacedIDs,
 		BasicOperator: basicOperator,
+		FanoutConfig:  c.Fanout,
 	}
 	return writer, nil
 }
@@ -47,20 +56,40 @@ type WriterOperator struct {
 	BasicOperator
 	OutputIDs       OutputIDs
 	OutputOperators []operator.Operator
+	FanoutConfig    FanoutConfig
+
+	metrics       *metrics.Operator
+	outputMetrics []*metrics.Operator
+	fanout        *fanout
 }
 
-// Write will write an entry to the outputs of the operator.
+// Write will write an entry to the outputs of the operator. With asynchronous fan-out,
+// the entry is queued for each output instead.
 func (w *WriterOperator) Write(ctx context.Context, e *entry.Entry) {
+	w.metrics.Emitted()
+	if w.fanout != nil {
+		w.writeAsync(ctx, e)
+		return
+	}
+
 	for i, operator := range w.OutputOperators {
 		if i == len(w.OutputOperators)-1 {
-			if err := operator.Process(ctx, e); err != nil {
-				w.Errorf("error while writing entry: %s", err)
-			}
+			w.writeTo(ctx, i, operator, e)
 			return
 		}
-		if err := operator.Process(ctx, e.Copy()); err != nil {
-			w.Errorf("error while writing entry: %s", err)
-		}
+		w.writeTo(ctx, i, operator, e.Copy())
+	}
+}
+
+// writeTo will send an entry to a single output and record its metrics.
+func (w *WriterOperator) writeTo(ctx context.Context, i int, operator operator.Operator, e *entry.Entry) {
+	start := time.Now()
+	err := operator.Process(ctx, e)
+	if i < len(w.outputMetrics) {
+		w.outputMetrics[i].Processed(start, err)
+	}
+	if err != nil {
+		w.Errorf("error while writing entry: %s", err)
 	}
 }
 
@@ -77,6 +106,7 @@ func (w *WriterOperator) Outputs() []operator.Operator {
 // SetOutputs will set the outputs of the operator.
 func (w *WriterOperator) SetOutputs(operators []operator.Operator) error {
 	outputOperators := make([]operator.Operator, 0)
+	outputMetrics := make([]*metrics.Operator, 0)
 
 	for _, operatorID := range w.OutputIDs {
 		operator, ok := w.findOperator(operators, operatorID)
@@ -89,12 +119,20 @@ func (w *WriterOperator) SetOutputs(operators []operator.Operator) error {
 		}
 
 		outputOperators = append(outputOperators, operator)
+		outputMetrics = append(outputMetrics, metrics.NewOperator(operator.ID()))
 	}
 
 	w.OutputOperators = outputOperators
+	w.metrics = metrics.NewOperator(w.ID())
+	w.outputMetrics = outputMetrics
 	return nil
 }
 
+// RecordDropped records that the operator has dropped an entry.
+func (w *WriterOperator) RecordDropped() {
+	w.metrics.Dropped()
+}
+
 // FindOperator will find an operator matching the supplied id.
 func (w *WriterOperator) findOperator(operators []operator.Operator, operatorID string) (operator.Operator, bool) {
 	for _, operator := range operators {
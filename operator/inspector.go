@@ -0,0 +1,18 @@
+package operator
+
+// Inspector is an operator that can describe its runtime state, such as the number
+// of entries it has buffered
+type Inspector interface {
+	// Inspect returns a JSON serializable description of the runtime state of the operator
+	Inspect() map[string]interface{}
+}
+
+// Pauser is an operator, such as an input, that can be paused and resumed while running
+type Pauser interface {
+	// Pause stops the operator from emitting entries until it is resumed
+	Pause()
+	// Resume lets a paused operator emit entries again
+	Resume()
+	// Paused returns true if the operator is paused
+	Paused() bool
+}
@@ -1,6 +1,9 @@
 package pipeline
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/observiq/stanza/operator"
 )
 
@@ -27,16 +30,34 @@ func (c Config) BuildPipeline(bc operator.BuildContext, defaultOperator operator
 		bc.DefaultOutputIDs = []string{defaultOperator.ID()}
 	}
 
-	operators, err := c.BuildOperators(bc)
-	if err != nil {
-		return nil, err
+	builds := make(map[string]configBuild, len(c))
+	operators := make([]operator.Operator, 0, len(c))
+	for i, builder := range c {
+		nbc := getBuildContextWithDefaultOutput(c, i, bc)
+		ops, err := builder.Build(nbc)
+		if err != nil {
+			return nil, err
+		}
+		builds[nbc.PrependNamespace(builder.ID())] = configBuild{
+			fingerprint: fingerprintConfig(builder, nbc),
+			operators:   ops,
+		}
+		operators = append(operators, ops...)
 	}
 
 	if defaultOperator != nil {
 		operators = append(operators, defaultOperator)
 	}
 
-	return NewDirectedPipeline(operators)
+	pipeline, err := NewDirectedPipeline(operators)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.builds = builds
+	pipeline.config = c
+	pipeline.buildContext = bc
+	pipeline.defaultOperator = defaultOperator
+	return pipeline, nil
 }
 
 func getBuildContextWithDefaultOutput(configs []operator.Config, i int, bc operator.BuildContext) operator.BuildContext {
@@ -48,3 +69,60 @@ func getBuildContextWithDefaultOutput(configs []operator.Config, i int, bc opera
 	id = bc.PrependNamespace(id)
 	return bc.WithDefaultOutputIDs([]string{id})
 }
+
+// configBuild records the operators built from a single config, along with
+// a fingerprint of the config used to detect changes on reload.
+type configBuild struct {
+	fingerprint string
+	operators   []operator.Operator
+}
+
+// Fingerprinter is implemented by builders whose operators depend on more than
+// their serialized config, such as plugins, which are rendered from a template.
+type Fingerprinter interface {
+	// Fingerprint returns a string that changes whenever the operators built
+	// with the context would change
+	Fingerprint(bc operator.BuildContext) (string, error)
+}
+
+// Fingerprint creates a string that identifies the configs and the context they
+// are built in. An empty fingerprint is returned if any config can not be serialized.
+func (c Config) Fingerprint(bc operator.BuildContext) string {
+	var fingerprint strings.Builder
+	for i, builder := range c {
+		configFingerprint := fingerprintConfig(builder, getBuildContextWithDefaultOutput(c, i, bc))
+		if configFingerprint == "" {
+			return ""
+		}
+		fingerprint.WriteString(configFingerprint)
+		fingerprint.WriteByte('\n')
+	}
+	return fingerprint.String()
+}
+
+// fingerprintConfig creates a string that uniquely identifies an operator config
+// and the context it was built in. An empty fingerprint is returned if the config
+// can not be serialized, in which case the config is always considered changed.
+func fingerprintConfig(config operator.Config, bc operator.BuildContext) string {
+	marshalled, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+
+	var fingerprint strings.Builder
+	fingerprint.Write(marshalled)
+	fingerprint.WriteByte('|')
+	fingerprint.WriteString(bc.Namespace)
+	fingerprint.WriteByte('|')
+	fingerprint.WriteString(strings.Join(bc.DefaultOutputIDs, ","))
+
+	if fingerprinter, ok := config.Builder.(Fingerprinter); ok {
+		extra, err := fingerprinter.Fingerprint(bc)
+		if err != nil {
+			return ""
+		}
+		fingerprint.WriteByte('|')
+		fingerprint.WriteString(extra)
+	}
+	return fingerprint.String()
+}
@@ -1,21 +1,35 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/metrics"
 	"github.com/observiq/stanza/operator"
+	"go.uber.org/zap"
 	"gonum.org/v1/gonum/graph/encoding/dot"
 	"gonum.org/v1/gonum/graph/simple"
 	"gonum.org/v1/gonum/graph/topo"
 )
 
 var _ Pipeline = (*DirectedPipeline)(nil)
+var _ Reloader = (*DirectedPipeline)(nil)
+var _ Drainer = (*DirectedPipeline)(nil)
 
 // DirectedPipeline is a pipeline backed by a directed graph
 type DirectedPipeline struct {
 	Graph *simple.DirectedGraph
+
+	// builds maps the namespaced id of each config in the pipeline to the operators it built
+	builds map[string]configBuild
+
+	// config, buildContext and defaultOperator are what the running pipeline was
+	// built from, so that it can be restored if a reload fails to start
+	config          Config
+	buildContext    operator.BuildContext
+	defaultOperator operator.Operator
 }
 
 // Start will start the operators in a pipeline in reverse topological order
@@ -27,7 +41,9 @@ func (p *DirectedPipeline) Start() error {
 	for i := len(sortedNodes) - 1; i >= 0; i-- {
 		operator := sortedNodes[i].(OperatorNode).Operator()
 		operator.Logger().Debug("Starting operator")
+		startFanout(operator)
 		if err := operator.Start(); err != nil {
+			stopFanout(context.Background(), operator)
 			return err
 		}
 		operator.Logger().Debug("Started operator")
@@ -38,18 +54,50 @@ func (p *DirectedPipeline) Start() error {
 
 // Stop will stop the operators in a pipeline in topological order
 func (p *DirectedPipeline) Stop() error {
+	_, err := p.stop(context.Background(), false)
+	return err
+}
+
+// Drain will stop the operators in a pipeline in topological order, first giving
+// each operator until the context is done to flush the entries it holds. Since inputs
+// are stopped first, no new entries enter the pipeline while it drains. It returns
+// the number of entries that were not flushed.
+func (p *DirectedPipeline) Drain(ctx context.Context) (int, error) {
+	return p.stop(ctx, true)
+}
+
+// stop stops the operators in a pipeline in topological order, draining each one
+// first if drain is true
+func (p *DirectedPipeline) stop(ctx context.Context, drain bool) (int, error) {
 	sortedNodes, err := topo.Sort(p.Graph)
 	if err != nil {
-		return err
+		return 0, err
 	}
+
+	unflushed := 0
 	for _, node := range sortedNodes {
-		operator := node.(OperatorNode).Operator()
-		operator.Logger().Debug("Stopping operator")
-		_ = operator.Stop()
-		operator.Logger().Debug("Stopped operator")
+		op := node.(OperatorNode).Operator()
+		if drainer, ok := op.(operator.Drainer); ok && drain {
+			op.Logger().Debug("Draining operator")
+			if n := drainer.Drain(ctx); n > 0 {
+				op.Logger().Warnw("Operator did not flush all entries before the shutdown timeout", "unflushed", n)
+				unflushed += n
+			}
+		}
+
+		op.Logger().Debug("Stopping operator")
+		if err := op.Stop(); err != nil {
+			op.Logger().Errorw("Failed to stop operator", zap.Error(err))
+		}
+		if n := stopFanout(ctx, op); n > 0 {
+			op.Logger().Warnw("Dropped entries queued for outputs at the shutdown timeout", "dropped", n)
+			unflushed += n
+		}
+		metrics.DeleteOperator(op.ID())
+		op.Logger().Debug("Stopped operator")
 	}
 
-	return nil
+	return unflushed, nil
 }
 
 // Render will render the pipeline as a dot graph
@@ -116,6 +164,14 @@ func connectNode(graph *simple.DirectedGraph, inputNode OperatorNode) error {
 			)
 		}
 
+		if outputNodeID == inputNode.ID() {
+			return errors.NewError(
+				"operators cannot be connected, because an operator can not output to itself",
+				"ensure that the operator outputs to a different operator",
+				"input_operator", inputNode.Operator().ID(),
+			)
+		}
+
 		outputNode := graph.Node(outputNodeID).(OperatorNode)
 		if !outputNode.Operator().CanProcess() {
 			return errors.NewError(
@@ -162,6 +218,17 @@ func NewDirectedPipeline(operators []operator.Operator) (*DirectedPipeline, erro
 		return nil, err
 	}
 
+	graph, err := newGraph(operators)
+	if err != nil {
+		return nil, err
+	}
+	connectBackpressure(graph)
+
+	return &DirectedPipeline{Graph: graph}, nil
+}
+
+// newGraph creates a graph from a list of operators that already have their outputs set.
+func newGraph(operators []operator.Operator) (*simple.DirectedGraph, error) {
 	graph := simple.NewDirectedGraph()
 	if err := addNodes(graph, operators); err != nil {
 		return nil, err
@@ -171,7 +238,68 @@ func NewDirectedPipeline(operators []operator.Operator) (*DirectedPipeline, erro
 		return nil, err
 	}
 
-	return &DirectedPipeline{Graph: graph}, nil
+	return graph, nil
+}
+
+// fanoutRunner is an operator that writes to its outputs asynchronously
+type fanoutRunner interface {
+	StartFanout()
+	StopFanout(context.Context) int
+}
+
+// startFanout starts the goroutines an operator writes to its outputs with, if it
+// has any. It is called before the operator is started.
+func startFanout(op operator.Operator) {
+	if f, ok := op.(fanoutRunner); ok {
+		f.StartFanout()
+	}
+}
+
+// stopFanout waits for a stopped operator to finish writing any entries it has
+// queued for its outputs, or for the context to be done. It returns the number
+// of queued entries that were dropped.
+func stopFanout(ctx context.Context, op operator.Operator) int {
+	if f, ok := op.(fanoutRunner); ok {
+		return f.StopFanout(ctx)
+	}
+	return 0
+}
+
+// connectBackpressure gives each operator in the graph that receives backpressure
+// the signals reported by all operators downstream of it.
+func connectBackpressure(graph *simple.DirectedGraph) {
+	nodes := graph.Nodes()
+	for nodes.Next() {
+		node := nodes.Node().(OperatorNode)
+		receiver, ok := node.Operator().(operator.BackpressureReceiver)
+		if !ok {
+			continue
+		}
+
+		signals := make([]*operator.Backpressure, 0)
+		visited := make(map[int64]struct{})
+		var visit func(id int64)
+		visit = func(id int64) {
+			downstream := graph.From(id)
+			for downstream.Next() {
+				next := downstream.Node().(OperatorNode)
+				if _, ok := visited[next.ID()]; ok {
+					continue
+				}
+				visited[next.ID()] = struct{}{}
+
+				if reporter, ok := next.Operator().(operator.BackpressureReporter); ok {
+					if signal := reporter.Backpressure(); signal != nil {
+						signals = append(signals, signal)
+					}
+				}
+				visit(next.ID())
+			}
+		}
+		visit(node.ID())
+
+		receiver.SetBackpressure(signals)
+	}
 }
 
 func unorderableToCycles(err topo.Unorderable) string {
@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -298,3 +299,92 @@ func TestPipelineOperators(t *testing.T) {
 	operators := pipeline.Operators()
 	require.ElementsMatch(t, []operator.Operator{mockOperator1, mockOperator2, mockOperator3}, operators)
 }
+
+type backpressureReceiver struct {
+	*testutil.Operator
+	signals []*operator.Backpressure
+}
+
+func (r *backpressureReceiver) SetBackpressure(signals []*operator.Backpressure) {
+	r.signals = signals
+}
+
+type backpressureReporter struct {
+	*testutil.Operator
+	signal *operator.Backpressure
+}
+
+func (r *backpressureReporter) Backpressure() *operator.Backpressure {
+	return r.signal
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+	output := &backpressureReporter{testutil.NewMockOperator("output"), operator.NewBackpressure()}
+	output.On("Outputs").Return(nil)
+	output.On("SetOutputs", mock.Anything).Return(nil)
+
+	unrelated := &backpressureReporter{testutil.NewMockOperator("unrelated"), operator.NewBackpressure()}
+	unrelated.On("Outputs").Return(nil)
+	unrelated.On("SetOutputs", mock.Anything).Return(nil)
+
+	transformer := testutil.NewMockOperator("transformer")
+	transformer.On("Outputs").Return([]operator.Operator{output})
+	transformer.On("SetOutputs", mock.Anything).Return(nil)
+
+	input := &backpressureReceiver{Operator: testutil.NewMockOperator("input")}
+	input.On("Outputs").Return([]operator.Operator{transformer})
+	input.On("SetOutputs", mock.Anything).Return(nil)
+
+	_, err := NewDirectedPipeline([]operator.Operator{input, transformer, output, unrelated})
+	require.NoError(t, err)
+	require.Equal(t, []*operator.Backpressure{output.signal}, input.signals)
+}
+
+type drainer struct {
+	*testutil.Operator
+	unflushed int
+	events    *[]string
+}
+
+func (d *drainer) Drain(context.Context) int {
+	*d.events = append(*d.events, "drain "+d.ID())
+	return d.unflushed
+}
+
+func TestPipelineDrain(t *testing.T) {
+	newPipeline := func(t *testing.T, events *[]string) *DirectedPipeline {
+		output := &drainer{testutil.NewMockOperator("output"), 2, events}
+		output.On("Outputs").Return(nil)
+		input := testutil.NewMockOperator("input")
+		input.On("Outputs").Return([]operator.Operator{output})
+
+		for _, op := range []*testutil.Operator{input, output.Operator} {
+			id := op.ID()
+			op.On("SetOutputs", mock.Anything).Return(nil)
+			op.On("Logger", mock.Anything).Return(zap.NewNop().Sugar())
+			op.On("Stop").Run(func(mock.Arguments) { *events = append(*events, "stop "+id) }).Return(fmt.Errorf("stop error"))
+		}
+
+		pipeline, err := NewDirectedPipeline([]operator.Operator{input, output})
+		require.NoError(t, err)
+		return pipeline
+	}
+
+	t.Run("Drain", func(t *testing.T) {
+		events := []string{}
+		pipeline := newPipeline(t, &events)
+
+		unflushed, err := pipeline.Drain(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 2, unflushed)
+		require.Equal(t, []string{"stop input", "drain output", "stop output"}, events)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		events := []string{}
+		pipeline := newPipeline(t, &events)
+
+		require.NoError(t, pipeline.Stop())
+		require.Equal(t, []string{"stop input", "stop output"}, events)
+	})
+}
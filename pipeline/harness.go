@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/helper"
+	"go.uber.org/zap"
+)
+
+// Harness runs entries through a pipeline offline, to test its config. The inputs
+// of the pipeline are replaced by the entries supplied to Run, and its outputs are
+// replaced by sinks that capture the entries they receive.
+type Harness struct {
+	pipeline *DirectedPipeline
+	targets  []operator.Operator
+
+	mux      sync.Mutex
+	captured []*entry.Entry
+}
+
+// BuildHarness builds a test harness from the config. Outputs are replaced without
+// being built, so that the harness does not open their buffers or connect to their
+// destinations. Operators that are only found to be outputs once built, such as
+// those of plugins, are stopped as soon as they are built.
+func (c Config) BuildHarness(bc operator.BuildContext) (*Harness, error) {
+	h := &Harness{}
+	inputs := make([]operator.Operator, 0)
+	operators := make([]operator.Operator, 0, len(c))
+	for i, builder := range c {
+		nbc := getBuildContextWithDefaultOutput(c, i, bc)
+		if _, ok := builder.Builder.(helper.OutputBuilder); ok {
+			operators = append(operators, &captureOperator{
+				id:           nbc.PrependNamespace(builder.ID()),
+				operatorType: builder.Type(),
+				harness:      h,
+			})
+			continue
+		}
+
+		built, err := builder.Build(nbc)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, op := range built {
+			switch {
+			case !op.CanProcess():
+				inputs = append(inputs, op)
+			case isOutput(op):
+				if err := op.Stop(); err != nil {
+					op.Logger().Errorw("Failed to stop replaced output", zap.Error(err))
+				}
+				operators = append(operators, &captureOperator{id: op.ID(), operatorType: op.Type(), harness: h})
+			default:
+				operators = append(operators, op)
+			}
+		}
+	}
+
+	if len(inputs) == 0 {
+		return nil, errors.NewError(
+			"pipeline has no inputs to replace with the test entries",
+			"ensure that the pipeline contains at least one input",
+		)
+	}
+
+	// The inputs are never started, and are only used to find the operators
+	// that the test entries should be sent to
+	seen := make(map[string]struct{})
+	for _, input := range inputs {
+		if !input.CanOutput() {
+			continue
+		}
+		err := input.SetOutputs(operators)
+		if err != nil {
+			return nil, errors.WithDetails(err, "operator_id", input.ID())
+		}
+
+		for _, output := range input.Outputs() {
+			if _, ok := seen[output.ID()]; ok {
+				continue
+			}
+			seen[output.ID()] = struct{}{}
+			h.targets = append(h.targets, output)
+		}
+	}
+
+	pipeline, err := NewDirectedPipeline(operators)
+	if err != nil {
+		return nil, err
+	}
+	h.pipeline = pipeline
+	return h, nil
+}
+
+// isOutput returns true if an operator sends entries out of the pipeline. Outputs
+// with a dead letter output can output, so they are found by their type, along
+// with any other operator that is the end of the pipeline.
+func isOutput(op operator.Operator) bool {
+	if _, ok := op.(helper.Output); ok {
+		return true
+	}
+	return !op.CanOutput()
+}
+
+// Run starts the pipeline, sends each entry to the operators that the inputs of the
+// pipeline output to, and drains the pipeline. It returns the entries received by
+// the outputs, in the order they were received. A harness can only be run once.
+func (h *Harness) Run(ctx context.Context, entries []*entry.Entry) ([]*entry.Entry, error) {
+	if err := h.pipeline.Start(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		for i, target := range h.targets {
+			targetEntry := e
+			if i != len(h.targets)-1 {
+				targetEntry = e.Copy()
+			}
+			// Errors are handled by the operators, as they are when written by an input
+			_ = target.Process(ctx, targetEntry)
+		}
+	}
+
+	if _, err := h.pipeline.Drain(ctx); err != nil {
+		return nil, err
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.captured, nil
+}
+
+// capture records an entry received by an output
+func (h *Harness) capture(e *entry.Entry) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.captured = append(h.captured, e)
+}
+
+// captureOperator replaces an output of a pipeline run by a harness
+type captureOperator struct {
+	id           string
+	operatorType string
+	harness      *Harness
+}
+
+func (c *captureOperator) ID() string                           { return c.id }
+func (c *captureOperator) Type() string                         { return c.operatorType }
+func (c *captureOperator) Start() error                         { return nil }
+func (c *captureOperator) Stop() error                          { return nil }
+func (c *captureOperator) CanOutput() bool                      { return false }
+func (c *captureOperator) Outputs() []operator.Operator         { return nil }
+func (c *captureOperator) SetOutputs([]operator.Operator) error { return nil }
+func (c *captureOperator) CanProcess() bool                     { return true }
+func (c *captureOperator) Logger() *zap.SugaredLogger           { return zap.NewNop().Sugar() }
+
+func (c *captureOperator) Process(_ context.Context, e *entry.Entry) error {
+	c.harness.capture(e)
+	return nil
+}
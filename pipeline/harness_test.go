@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/operator/builtin/input/generate"
+	"github.com/observiq/stanza/operator/builtin/output/drop"
+	"github.com/observiq/stanza/operator/builtin/output/forward"
+	"github.com/observiq/stanza/operator/builtin/parser/json"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHarness(t *testing.T) {
+	input := generate.NewGenerateInputConfig("in")
+	parser := json.NewJSONParserConfig("parser")
+	parser.OnError = helper.DropOnError
+	config := Config{
+		operator.Config{Builder: input},
+		operator.Config{Builder: parser},
+		operator.Config{Builder: drop.NewDropOutputConfig("out")},
+	}
+
+	harness, err := config.BuildHarness(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+
+	entries := []*entry.Entry{entry.New(), entry.New(), entry.New()}
+	entries[0].Record = `{"key":"first"}`
+	entries[1].Record = `not json`
+	entries[2].Record = `{"key":"third"}`
+
+	captured, err := harness.Run(context.Background(), entries)
+	require.NoError(t, err)
+	require.Len(t, captured, 2)
+	require.Equal(t, map[string]interface{}{"key": "first"}, captured[0].Record)
+	require.Equal(t, map[string]interface{}{"key": "third"}, captured[1].Record)
+}
+
+func TestHarnessDeadLetterOutput(t *testing.T) {
+	// An output with a dead letter output can output, but must still be replaced,
+	// so that the test entries are not sent to its destination
+	output := forward.NewForwardOutputConfig("out")
+	output.Address = "http://127.0.0.1:1"
+	output.DeadLetterOutput = "failed"
+	config := Config{
+		operator.Config{Builder: generate.NewGenerateInputConfig("in")},
+		operator.Config{Builder: output},
+		operator.Config{Builder: drop.NewDropOutputConfig("failed")},
+	}
+
+	harness, err := config.BuildHarness(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+
+	e := entry.New()
+	e.Record = "test"
+	captured, err := harness.Run(context.Background(), []*entry.Entry{e})
+	require.NoError(t, err)
+	require.Len(t, captured, 1)
+	require.Equal(t, "test", captured[0].Record)
+}
+
+func TestHarnessOutputsNotBuilt(t *testing.T) {
+	diskBuffer := buffer.NewDiskBufferConfig()
+	diskBuffer.Path = testutil.NewTempDir(t)
+	output := forward.NewForwardOutputConfig("out")
+	output.Address = "http://127.0.0.1:1"
+	output.BufferConfig = buffer.Config{Builder: diskBuffer}
+	config := Config{
+		operator.Config{Builder: generate.NewGenerateInputConfig("in")},
+		operator.Config{Builder: output},
+	}
+
+	harness, err := config.BuildHarness(testutil.NewBuildContext(t))
+	require.NoError(t, err)
+	captured, err := harness.Run(context.Background(), []*entry.Entry{entry.New()})
+	require.NoError(t, err)
+	require.Len(t, captured, 1)
+
+	// The disk buffer of the replaced output is not opened, which would create its files
+	files, err := ioutil.ReadDir(diskBuffer.Path)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestHarnessNoInputs(t *testing.T) {
+	config := Config{
+		operator.Config{Builder: json.NewJSONParserConfig("parser")},
+		operator.Config{Builder: drop.NewDropOutputConfig("out")},
+	}
+
+	_, err := config.BuildHarness(testutil.NewBuildContext(t))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no inputs")
+}
@@ -2,7 +2,11 @@
 
 package pipeline
 
-import "github.com/observiq/stanza/operator"
+import (
+	"context"
+
+	"github.com/observiq/stanza/operator"
+)
 
 // Pipeline is a collection of connected operators that exchange entries
 type Pipeline interface {
@@ -11,3 +15,13 @@ type Pipeline interface {
 	Operators() []operator.Operator
 	Render() ([]byte, error)
 }
+
+// Reloader is a pipeline that can be updated in place to match a new config
+type Reloader interface {
+	Reload(Config, operator.BuildContext, operator.Operator) error
+}
+
+// Drainer is a pipeline that can flush the entries held by its operators before stopping
+type Drainer interface {
+	Drain(context.Context) (int, error)
+}
@@ -0,0 +1,264 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/metrics"
+	"github.com/observiq/stanza/operator"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// Reload will update a running pipeline to match the supplied config.
+//
+// Operators are kept running if neither their own config nor the config of
+// any operator downstream of them has changed. All other operators are stopped
+// and replaced by newly built operators. If the new operators fail to build,
+// the running pipeline is left untouched and an error is returned. If they fail
+// to start, the pipeline is restored to the config it was running before.
+func (p *DirectedPipeline) Reload(c Config, bc operator.BuildContext, defaultOperator operator.Operator) error {
+	previous, previousContext, previousDefault := p.config, p.buildContext, p.defaultOperator
+
+	failed, err := p.reload(c, bc, defaultOperator, nil)
+	if err == nil || failed == nil || previous == nil {
+		return err
+	}
+
+	// The operators replaced by the reload have been stopped, so they are rebuilt
+	// from the previous config. The new operators that failed are not running, and
+	// can not be kept.
+	if _, restoreErr := p.reload(previous, previousContext, previousDefault, failed); restoreErr != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to restore the previous pipeline (%s)", restoreErr))
+	}
+	return err
+}
+
+// reload updates the pipeline to match the config. The operators in notRunning
+// are part of the current pipeline, but are not running, so they are neither
+// stopped nor kept. If the new operators fail to start, those that started are
+// stopped, and all the new operators are returned with the error.
+func (p *DirectedPipeline) reload(c Config, bc operator.BuildContext, defaultOperator operator.Operator, notRunning []operator.Operator) ([]operator.Operator, error) {
+	skip := make(map[operator.Operator]struct{}, len(notRunning))
+	for _, op := range notRunning {
+		skip[op] = struct{}{}
+	}
+
+	originalContext := bc
+	if defaultOperator != nil {
+		bc.DefaultOutputIDs = []string{defaultOperator.ID()}
+	}
+
+	contexts := make([]operator.BuildContext, len(c))
+	fingerprints := make([]string, len(c))
+	for i, builder := range c {
+		contexts[i] = getBuildContextWithDefaultOutput(c, i, bc)
+		fingerprints[i] = fingerprintConfig(builder, contexts[i])
+	}
+
+	reused := p.reusableBuilds(c, contexts, fingerprints, defaultOperator, skip)
+
+	builds := make(map[string]configBuild, len(c))
+	operators := make([]operator.Operator, 0, len(c))
+	newOperators := make([]operator.Operator, 0, len(c))
+	for i, builder := range c {
+		key := contexts[i].PrependNamespace(builder.ID())
+		if build, ok := reused[key]; ok {
+			builds[key] = build
+			operators = append(operators, build.operators...)
+			continue
+		}
+
+		ops, err := builder.Build(contexts[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "build reloaded pipeline")
+		}
+		builds[key] = configBuild{fingerprint: fingerprints[i], operators: ops}
+		operators = append(operators, ops...)
+		newOperators = append(newOperators, ops...)
+	}
+
+	if defaultOperator != nil {
+		operators = append(operators, defaultOperator)
+	}
+
+	// Only the new operators have their outputs set, because reused operators
+	// are running and only ever output to other reused operators
+	for _, op := range newOperators {
+		if !op.CanOutput() {
+			continue
+		}
+		if err := op.SetOutputs(operators); err != nil {
+			return nil, errors.Wrap(errors.WithDetails(err, "operator_id", op.ID()), "build reloaded pipeline")
+		}
+	}
+
+	graph, err := newGraph(operators)
+	if err != nil {
+		return nil, errors.Wrap(err, "build reloaded pipeline")
+	}
+
+	if err := p.stopReplaced(operators, skip); err != nil {
+		return nil, err
+	}
+
+	p.Graph = graph
+	p.builds = builds
+	connectBackpressure(graph)
+
+	if err := p.startNew(newOperators); err != nil {
+		return newOperators, err
+	}
+
+	p.config = c
+	p.buildContext = originalContext
+	p.defaultOperator = defaultOperator
+	return nil, nil
+}
+
+// reusableBuilds returns the builds of the current pipeline that can be kept
+// running, keyed by the namespaced id of their config.
+func (p *DirectedPipeline) reusableBuilds(c Config, contexts []operator.BuildContext, fingerprints []string, defaultOperator operator.Operator, notRunning map[operator.Operator]struct{}) map[string]configBuild {
+	candidates := make(map[string]configBuild, len(c))
+	for i, builder := range c {
+		key := contexts[i].PrependNamespace(builder.ID())
+		build, ok := p.builds[key]
+		if !ok || build.fingerprint == "" || build.fingerprint != fingerprints[i] {
+			continue
+		}
+		if containsAny(build.operators, notRunning) {
+			continue
+		}
+		candidates[key] = build
+	}
+
+	// Remove candidates that output to an operator that is not reused, until
+	// every remaining candidate outputs only to other remaining candidates.
+	for {
+		kept := make(map[operator.Operator]struct{})
+		if defaultOperator != nil {
+			kept[defaultOperator] = struct{}{}
+		}
+		for _, build := range candidates {
+			for _, op := range build.operators {
+				kept[op] = struct{}{}
+			}
+		}
+
+		removed := false
+		for key, build := range candidates {
+			if !outputsKept(build.operators, kept) {
+				delete(candidates, key)
+				removed = true
+			}
+		}
+
+		if !removed {
+			return candidates
+		}
+	}
+}
+
+// containsAny returns true if any of the operators is in the set.
+func containsAny(operators []operator.Operator, set map[operator.Operator]struct{}) bool {
+	for _, op := range operators {
+		if _, ok := set[op]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// outputsKept returns true if all the outputs of the operators are in the kept set.
+func outputsKept(operators []operator.Operator, kept map[operator.Operator]struct{}) bool {
+	for _, op := range operators {
+		if !op.CanOutput() {
+			continue
+		}
+		for _, output := range op.Outputs() {
+			if _, ok := kept[output]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stopReplaced stops the operators in the current pipeline that are not part of
+// the supplied operators, except those that are not running. They are stopped in
+// topological order.
+func (p *DirectedPipeline) stopReplaced(operators []operator.Operator, notRunning map[operator.Operator]struct{}) error {
+	keep := make(map[operator.Operator]struct{}, len(operators))
+	ids := make(map[string]struct{}, len(operators))
+	for _, op := range operators {
+		keep[op] = struct{}{}
+		ids[op.ID()] = struct{}{}
+	}
+
+	sortedNodes, err := topo.Sort(p.Graph)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range sortedNodes {
+		op := node.(OperatorNode).Operator()
+		if _, ok := keep[op]; ok {
+			continue
+		}
+		if _, ok := notRunning[op]; ok {
+			continue
+		}
+		op.Logger().Debug("Stopping replaced operator")
+		_ = op.Stop()
+		stopFanout(context.Background(), op)
+		// An operator replaced by one with the same id keeps its metrics, since
+		// the new operator reports to the same series
+		if _, ok := ids[op.ID()]; !ok {
+			metrics.DeleteOperator(op.ID())
+		}
+		op.Logger().Debug("Stopped replaced operator")
+	}
+	return nil
+}
+
+// startNew starts the supplied operators in reverse topological order of the pipeline.
+// If one fails to start, those that started are stopped before the error is returned.
+func (p *DirectedPipeline) startNew(operators []operator.Operator) error {
+	start := make(map[operator.Operator]struct{}, len(operators))
+	for _, op := range operators {
+		start[op] = struct{}{}
+	}
+
+	sortedNodes, err := topo.Sort(p.Graph)
+	if err != nil {
+		return err
+	}
+
+	started := make([]operator.Operator, 0, len(operators))
+	for i := len(sortedNodes) - 1; i >= 0; i-- {
+		op := sortedNodes[i].(OperatorNode).Operator()
+		if _, ok := start[op]; !ok {
+			continue
+		}
+		op.Logger().Debug("Starting operator")
+		startFanout(op)
+		if err := op.Start(); err != nil {
+			stopFanout(context.Background(), op)
+			stopStarted(started)
+			return errors.Wrap(errors.WithDetails(err, "operator_id", op.ID()), "start reloaded operator")
+		}
+		op.Logger().Debug("Started operator")
+		started = append(started, op)
+	}
+	return nil
+}
+
+// stopStarted stops operators in the reverse of the order they were started.
+func stopStarted(started []operator.Operator) {
+	for i := len(started) - 1; i >= 0; i-- {
+		op := started[i]
+		op.Logger().Debug("Stopping operator that was started by a failed reload")
+		_ = op.Stop()
+		stopFanout(context.Background(), op)
+	}
+}
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"net"
+	"testing"
+
+	"github.com/observiq/stanza/metrics"
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/builtin/input/tcp"
+	"github.com/observiq/stanza/operator/builtin/output/drop"
+	"github.com/observiq/stanza/operator/builtin/transformer/noop"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newReloadTestConfig(ifExpr string) Config {
+	first := noop.NewNoopOperatorConfig("first")
+	second := noop.NewNoopOperatorConfig("second")
+	second.IfExpr = ifExpr
+	out := drop.NewDropOutputConfig("out")
+	return Config{
+		operator.Config{Builder: first},
+		operator.Config{Builder: second},
+		operator.Config{Builder: out},
+	}
+}
+
+func operatorsByID(p *DirectedPipeline) map[string]operator.Operator {
+	operators := make(map[string]operator.Operator)
+	for _, op := range p.Operators() {
+		operators[op.ID()] = op
+	}
+	return operators
+}
+
+func TestPipelineReload(t *testing.T) {
+	t.Run("Unchanged", func(t *testing.T) {
+		bc := testutil.NewBuildContext(t)
+		pipeline, err := newReloadTestConfig("").BuildPipeline(bc, nil)
+		require.NoError(t, err)
+		require.NoError(t, pipeline.Start())
+		defer pipeline.Stop()
+
+		before := operatorsByID(pipeline)
+		require.NoError(t, pipeline.Reload(newReloadTestConfig(""), bc, nil))
+		after := operatorsByID(pipeline)
+
+		require.Len(t, after, 3)
+		for id, op := range before {
+			require.Same(t, op, after[id])
+		}
+	})
+
+	t.Run("ChangedOperatorAndUpstream", func(t *testing.T) {
+		bc := testutil.NewBuildContext(t)
+		pipeline, err := newReloadTestConfig("").BuildPipeline(bc, nil)
+		require.NoError(t, err)
+		require.NoError(t, pipeline.Start())
+		defer pipeline.Stop()
+
+		before := operatorsByID(pipeline)
+		require.NoError(t, pipeline.Reload(newReloadTestConfig("true"), bc, nil))
+		after := operatorsByID(pipeline)
+
+		require.Len(t, after, 3)
+		require.NotSame(t, before["$.first"], after["$.first"])
+		require.NotSame(t, before["$.second"], after["$.second"])
+		require.Same(t, before["$.out"], after["$.out"])
+		require.Equal(t, []operator.Operator{after["$.second"]}, after["$.first"].Outputs())
+	})
+
+	t.Run("FailedBuild", func(t *testing.T) {
+		bc := testutil.NewBuildContext(t)
+		pipeline, err := newReloadTestConfig("").BuildPipeline(bc, nil)
+		require.NoError(t, err)
+		require.NoError(t, pipeline.Start())
+		defer pipeline.Stop()
+
+		before := operatorsByID(pipeline)
+		err = pipeline.Reload(newReloadTestConfig("not a valid {{ expression"), bc, nil)
+		require.Error(t, err)
+		require.Equal(t, before, operatorsByID(pipeline))
+	})
+
+	t.Run("FailedStart", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		bc := testutil.NewBuildContext(t)
+		pipeline, err := newReloadTestConfig("").BuildPipeline(bc, nil)
+		require.NoError(t, err)
+		require.NoError(t, pipeline.Start())
+		defer pipeline.Stop()
+
+		before := operatorsByID(pipeline)
+
+		// The new first operator can not listen on an address that is in use, so the
+		// pipeline is restored with a rebuilt second operator
+		cfg := newReloadTestConfig("true")
+		input := tcp.NewTCPInputConfig("first")
+		input.ListenAddress = listener.Addr().String()
+		cfg[0] = operator.Config{Builder: input}
+
+		err = pipeline.Reload(cfg, bc, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "start reloaded operator")
+
+		after := operatorsByID(pipeline)
+		require.Len(t, after, 3)
+		require.IsType(t, &noop.NoopOperator{}, after["$.first"])
+		require.NotSame(t, before["$.first"], after["$.first"])
+		require.NotSame(t, before["$.second"], after["$.second"])
+		require.Same(t, before["$.out"], after["$.out"])
+		require.Equal(t, []operator.Operator{after["$.second"]}, after["$.first"].Outputs())
+
+		// The restored pipeline is reloaded like any other
+		require.NoError(t, pipeline.Reload(newReloadTestConfig(""), bc, nil))
+		require.Same(t, after["$.out"], operatorsByID(pipeline)["$.out"])
+	})
+
+	t.Run("RemovedOperator", func(t *testing.T) {
+		bc := testutil.NewBuildContext(t)
+		pipeline, err := newReloadTestConfig("").BuildPipeline(bc, nil)
+		require.NoError(t, err)
+		require.NoError(t, pipeline.Start())
+		defer pipeline.Stop()
+
+		require.True(t, hasMetrics(t, "$.first"))
+
+		cfg := newReloadTestConfig("")
+		require.NoError(t, pipeline.Reload(cfg[1:], bc, nil))
+		after := operatorsByID(pipeline)
+		require.Len(t, after, 2)
+		require.NotContains(t, after, "$.first")
+
+		// The metrics of the removed operator are no longer reported
+		require.False(t, hasMetrics(t, "$.first"))
+		require.True(t, hasMetrics(t, "$.second"))
+	})
+}
+
+// hasMetrics returns true if any metric is reported for the operator with the supplied id
+func hasMetrics(t *testing.T, operatorID string) bool {
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operator_id" && label.GetValue() == operatorID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
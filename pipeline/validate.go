@@ -0,0 +1,174 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/observiq/stanza/entry"
+	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/operator"
+	"go.uber.org/zap"
+	"gonum.org/v1/gonum/graph/simple"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// ValidationError is a problem found while validating a pipeline config
+type ValidationError struct {
+	// Index is the index of the config the problem was found in, or -1 if
+	// the problem is not specific to a single config
+	Index int
+	// OperatorID is the namespaced id of the operator the problem was found in, if any
+	OperatorID string
+	Err        error
+}
+
+// Error returns the error message of the problem
+func (e ValidationError) Error() string {
+	if e.OperatorID == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.OperatorID, e.Err)
+}
+
+// Validate builds the pipeline described by the config without starting it, and
+// returns every problem found rather than stopping at the first one. In addition
+// to the errors returned by BuildPipeline, it reports operators that no input
+// can reach. The operators are built with a validating context, so that they do
+// not acquire resources, and are discarded.
+func (c Config) Validate(bc operator.BuildContext) []ValidationError {
+	problems := make([]ValidationError, 0)
+	bc.Validating = true
+
+	// Operators that fail to build are replaced by placeholders, so that
+	// operators that output to them are not reported as misconfigured
+	operators := make([]operator.Operator, 0, len(c))
+	indexes := make(map[string]int, len(c))
+	connected := true
+	for i, builder := range c {
+		nbc := getBuildContextWithDefaultOutput(c, i, bc)
+		id := nbc.PrependNamespace(builder.ID())
+		ops, err := builder.Build(nbc)
+		if err != nil {
+			problems = append(problems, ValidationError{Index: i, OperatorID: id, Err: err})
+			ops = []operator.Operator{&placeholderOperator{id: id, operatorType: builder.Type()}}
+			connected = false
+		}
+
+		for _, op := range ops {
+			if _, ok := indexes[op.ID()]; ok {
+				problems = append(problems, ValidationError{
+					Index:      i,
+					OperatorID: op.ID(),
+					Err: errors.NewError(
+						fmt.Sprintf("operator with id '%s' already exists in pipeline", op.ID()),
+						"ensure that each operator has a unique `type` or `id`",
+					),
+				})
+				continue
+			}
+			indexes[op.ID()] = i
+			operators = append(operators, op)
+		}
+	}
+
+	for _, op := range operators {
+		if !op.CanOutput() {
+			continue
+		}
+		err := op.SetOutputs(operators)
+		if err != nil {
+			problems = append(problems, ValidationError{Index: indexes[op.ID()], OperatorID: op.ID(), Err: err})
+			connected = false
+		}
+	}
+
+	graph := simple.NewDirectedGraph()
+	for _, op := range operators {
+		graph.AddNode(createOperatorNode(op))
+	}
+	for _, op := range operators {
+		node := graph.Node(createNodeID(op.ID())).(OperatorNode)
+		if err := connectNode(graph, node); err != nil {
+			problems = append(problems, ValidationError{Index: indexes[op.ID()], OperatorID: op.ID(), Err: err})
+			connected = false
+		}
+	}
+
+	if _, err := topo.Sort(graph); err != nil {
+		problems = append(problems, ValidationError{
+			Index: -1,
+			Err: errors.NewError(
+				"pipeline has a circular dependency",
+				"ensure that all operators are connected in a straight, acyclic line",
+				"cycles", unorderableToCycles(err.(topo.Unorderable)),
+			),
+		})
+	}
+
+	// The outputs of an operator that failed to build or connect are unknown,
+	// so unreachable operators are only reported for a fully connected pipeline
+	if !connected {
+		return problems
+	}
+
+	for _, op := range unreachableOperators(graph, operators) {
+		problems = append(problems, ValidationError{
+			Index:      indexes[op.ID()],
+			OperatorID: op.ID(),
+			Err: errors.NewError(
+				"operator is unreachable, because no input outputs to it",
+				"ensure that the operator is listed in the `output` of another operator",
+			),
+		})
+	}
+
+	return problems
+}
+
+// unreachableOperators returns the operators in the graph that process entries,
+// but can not be reached from any input.
+func unreachableOperators(graph *simple.DirectedGraph, operators []operator.Operator) []operator.Operator {
+	reached := make(map[int64]struct{})
+	var visit func(id int64)
+	visit = func(id int64) {
+		if _, ok := reached[id]; ok {
+			return
+		}
+		reached[id] = struct{}{}
+		downstream := graph.From(id)
+		for downstream.Next() {
+			visit(downstream.Node().ID())
+		}
+	}
+
+	for _, op := range operators {
+		if !op.CanProcess() {
+			visit(createNodeID(op.ID()))
+		}
+	}
+
+	unreachable := make([]operator.Operator, 0)
+	for _, op := range operators {
+		if _, ok := reached[createNodeID(op.ID())]; !ok {
+			unreachable = append(unreachable, op)
+		}
+	}
+	return unreachable
+}
+
+// placeholderOperator stands in for an operator that failed to build during validation
+type placeholderOperator struct {
+	id           string
+	operatorType string
+}
+
+func (p *placeholderOperator) ID() string                                  { return p.id }
+func (p *placeholderOperator) Type() string                                { return p.operatorType }
+func (p *placeholderOperator) Start() error                                { return nil }
+func (p *placeholderOperator) Stop() error                                 { return nil }
+func (p *placeholderOperator) CanOutput() bool                             { return false }
+func (p *placeholderOperator) Outputs() []operator.Operator                { return nil }
+func (p *placeholderOperator) SetOutputs([]operator.Operator) error        { return nil }
+func (p *placeholderOperator) CanProcess() bool                            { return true }
+func (p *placeholderOperator) Process(context.Context, *entry.Entry) error { return nil }
+func (p *placeholderOperator) Logger() *zap.SugaredLogger                  { return zap.NewNop().Sugar() }
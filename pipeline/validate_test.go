@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/buffer"
+	"github.com/observiq/stanza/operator/builtin/input/generate"
+	"github.com/observiq/stanza/operator/builtin/output/drop"
+	"github.com/observiq/stanza/operator/builtin/output/forward"
+	"github.com/observiq/stanza/operator/builtin/transformer/noop"
+	"github.com/observiq/stanza/operator/helper"
+	"github.com/observiq/stanza/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineValidate(t *testing.T) {
+	newGenerate := func(id string, outputs ...string) operator.Config {
+		cfg := generate.NewGenerateInputConfig(id)
+		cfg.OutputIDs = outputs
+		return operator.Config{Builder: cfg}
+	}
+	newNoop := func(id string, outputs ...string) operator.Config {
+		cfg := noop.NewNoopOperatorConfig(id)
+		cfg.OutputIDs = outputs
+		return operator.Config{Builder: cfg}
+	}
+	newBroken := func(id string, outputs ...string) operator.Config {
+		cfg := noop.NewNoopOperatorConfig(id)
+		cfg.OutputIDs = outputs
+		cfg.IfExpr = "invalid ==="
+		return operator.Config{Builder: cfg}
+	}
+	newDrop := func(id string) operator.Config {
+		return operator.Config{Builder: drop.NewDropOutputConfig(id)}
+	}
+
+	cases := []struct {
+		name     string
+		config   Config
+		expected map[int]string
+	}{
+		{
+			"Valid",
+			Config{newGenerate("in"), newNoop("noop"), newDrop("out")},
+			map[int]string{},
+		},
+		{
+			"DanglingOutputs",
+			Config{newGenerate("in", "missing"), newNoop("noop", "also_missing"), newDrop("out")},
+			map[int]string{
+				0: "$.in: operator '$.missing' does not exist",
+				1: "$.noop: operator '$.also_missing' does not exist",
+			},
+		},
+		{
+			"BuildErrors",
+			Config{newGenerate("in"), newBroken("first"), newBroken("second"), newDrop("out")},
+			map[int]string{
+				1: "$.first: failed to compile expression",
+				2: "$.second: failed to compile expression",
+			},
+		},
+		{
+			"DuplicateID",
+			Config{newGenerate("in", "out"), newDrop("out"), newDrop("out")},
+			map[int]string{
+				2: "$.out: operator with id '$.out' already exists in pipeline",
+			},
+		},
+		{
+			"Unreachable",
+			Config{newGenerate("in", "out"), newNoop("orphan", "out"), newDrop("out")},
+			map[int]string{
+				1: "$.orphan: operator is unreachable, because no input outputs to it",
+			},
+		},
+		{
+			"OutputToFailedBuild",
+			Config{newGenerate("in", "broken"), newBroken("broken", "out"), newDrop("out")},
+			map[int]string{
+				1: "$.broken: ",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := tc.config.Validate(testutil.NewBuildContext(t))
+			actual := make(map[int]string, len(problems))
+			for _, problem := range problems {
+				require.NotContains(t, actual, problem.Index)
+				actual[problem.Index] = problem.Error()
+			}
+
+			require.Len(t, actual, len(tc.expected))
+			for index, prefix := range tc.expected {
+				require.Contains(t, actual, index)
+				require.Contains(t, actual[index], prefix)
+			}
+		})
+	}
+}
+
+func TestPipelineValidateCycle(t *testing.T) {
+	first := noop.NewNoopOperatorConfig("first")
+	first.OutputIDs = helper.OutputIDs{"second"}
+	second := noop.NewNoopOperatorConfig("second")
+	second.OutputIDs = helper.OutputIDs{"first"}
+	input := generate.NewGenerateInputConfig("in")
+	input.OutputIDs = helper.OutputIDs{"first"}
+	config := Config{
+		operator.Config{Builder: input},
+		operator.Config{Builder: first},
+		operator.Config{Builder: second},
+	}
+
+	problems := config.Validate(testutil.NewBuildContext(t))
+	require.Len(t, problems, 1)
+	require.Equal(t, -1, problems[0].Index)
+	require.Contains(t, problems[0].Error(), "circular dependency")
+}
+
+func TestPipelineValidateNoSideEffects(t *testing.T) {
+	diskBuffer := buffer.NewDiskBufferConfig()
+	diskBuffer.Path = testutil.NewTempDir(t)
+	output := forward.NewForwardOutputConfig("out")
+	output.Address = "http://127.0.0.1:1"
+	output.BufferConfig = buffer.Config{Builder: diskBuffer}
+	input := generate.NewGenerateInputConfig("in")
+	input.OutputIDs = helper.OutputIDs{"out"}
+	config := Config{
+		operator.Config{Builder: input},
+		operator.Config{Builder: output},
+	}
+
+	require.Empty(t, config.Validate(testutil.NewBuildContext(t)))
+
+	// The disk buffer of the output is not opened, which would create its files
+	files, err := ioutil.ReadDir(diskBuffer.Path)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
@@ -12,8 +12,9 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-// Enforce that Config implements operator.Builder
+// Enforce that Config implements operator.Builder and pipeline.Fingerprinter
 var _ operator.Builder = (*Config)(nil)
+var _ pipeline.Fingerprinter = (*Config)(nil)
 
 // Config is the config values for the plugin
 type Config struct {
@@ -24,25 +25,50 @@ type Config struct {
 
 // Build implements operator.MultiBuilder
 func (c *Config) Build(bc operator.BuildContext) ([]operator.Operator, error) {
+	pipelineConfig, nbc, err := c.render(bc)
+	if err != nil {
+		return nil, err
+	}
+	return pipelineConfig.BuildOperators(nbc)
+}
+
+// Fingerprint implements pipeline.Fingerprinter. It includes the rendered pipeline
+// of the plugin, so that the plugin is rebuilt on reload when its template changes.
+func (c *Config) Fingerprint(bc operator.BuildContext) (string, error) {
+	pipelineConfig, nbc, err := c.render(bc)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := pipelineConfig.Fingerprint(nbc)
+	if fingerprint == "" {
+		return "", errors.NewError("failed to fingerprint the rendered plugin", "")
+	}
+	return fingerprint, nil
+}
+
+// render renders the plugin template into a pipeline config, and returns it with
+// the context its operators are built in
+func (c *Config) render(bc operator.BuildContext) (pipeline.Config, operator.BuildContext, error) {
 	if bc.PluginDepth > 10 {
-		return nil, errors.NewError("reached max plugin depth", "ensure that there are no recursive dependencies in plugins")
+		return nil, bc, errors.NewError("reached max plugin depth", "ensure that there are no recursive dependencies in plugins")
 	}
 
 	params := c.getRenderParams(bc)
 	pipelineConfigBytes, err := c.Plugin.Render(params)
 	if err != nil {
-		return nil, err
+		return nil, bc, err
 	}
 
 	var pipelineConfig struct {
 		Pipeline pipeline.Config
 	}
 	if err := yaml.Unmarshal(pipelineConfigBytes, &pipelineConfig); err != nil {
-		return nil, err
+		return nil, bc, err
 	}
 
 	nbc := bc.WithSubNamespace(c.ID()).WithIncrementedDepth()
-	return pipelineConfig.Pipeline.BuildOperators(nbc)
+	return pipelineConfig.Pipeline, nbc, nil
 }
 
 func (c *Config) getRenderParams(bc operator.BuildContext) map[string]interface{} {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/observiq/stanza/operator"
+	"github.com/observiq/stanza/operator/builtin/output/drop"
 	"github.com/observiq/stanza/operator/builtin/transformer/noop"
 	"github.com/observiq/stanza/operator/helper"
 	"github.com/observiq/stanza/pipeline"
@@ -118,3 +119,57 @@ pipeline:
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "reached max plugin depth")
 }
+
+func TestReloadChangedTemplate(t *testing.T) {
+	// Other tests in this package replace the default registry
+	operator.Register("noop", func() operator.Builder { return noop.NewNoopOperatorConfig("") })
+
+	newPluginConfig := func(template string) pipeline.Config {
+		plugin, err := NewPlugin("my_plugin", []byte(template))
+		require.NoError(t, err)
+
+		cfg := &Config{Plugin: plugin}
+		cfg.OperatorID = "my_plugin_id"
+		cfg.OperatorType = "my_plugin"
+		return pipeline.Config{
+			operator.Config{Builder: cfg},
+			operator.Config{Builder: drop.NewDropOutputConfig("out")},
+		}
+	}
+
+	original := `
+pipeline:
+  - id: {{ .input }}
+    type: noop
+    output: {{ .output }}
+`
+	changed := `
+pipeline:
+  - id: {{ .input }}
+    type: noop
+    if: 'true'
+    output: {{ .output }}
+`
+
+	bc := testutil.NewBuildContext(t)
+	p, err := newPluginConfig(original).BuildPipeline(bc, nil)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	defer p.Stop()
+
+	findNoop := func() operator.Operator {
+		for _, op := range p.Operators() {
+			if op.ID() == "$.my_plugin_id" {
+				return op
+			}
+		}
+		return nil
+	}
+
+	before := findNoop()
+	require.NoError(t, p.Reload(newPluginConfig(original), bc, nil))
+	require.Same(t, before, findNoop())
+
+	require.NoError(t, p.Reload(newPluginConfig(changed), bc, nil))
+	require.NotSame(t, before, findNoop())
+}
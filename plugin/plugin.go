@@ -10,6 +10,7 @@ import (
 	"text/template"
 
 	"github.com/observiq/stanza/errors"
+	"github.com/observiq/stanza/interpolate"
 	"github.com/observiq/stanza/operator"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -110,6 +111,13 @@ func (p *Plugin) UnmarshalText(text []byte) error {
 		return err
 	}
 
+	// References in the metadata, such as parameter defaults, are substituted
+	// when the plugin is loaded
+	metadataBytes, err = interpolate.Bytes(metadataBytes, nil)
+	if err != nil {
+		return err
+	}
+
 	if err := yaml.Unmarshal(metadataBytes, p); err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
@@ -66,6 +67,26 @@ func TestPluginRender(t *testing.T) {
 	})
 }
 
+func TestPluginMetadataReferences(t *testing.T) {
+	os.Setenv("PLUGIN_TEST_DEFAULT_HOST", "example.com")
+	defer os.Unsetenv("PLUGIN_TEST_DEFAULT_HOST")
+
+	plugin, err := NewPlugin("references", []byte(`
+parameters:
+  - name: host
+    type: string
+    default: ${env:PLUGIN_TEST_DEFAULT_HOST}
+  - name: port
+    type: int
+    default: ${env:PLUGIN_TEST_DEFAULT_PORT:-514}
+pipeline:
+`))
+	require.NoError(t, err)
+	require.Len(t, plugin.Parameters, 2)
+	require.Equal(t, "example.com", plugin.Parameters[0].Default)
+	require.Equal(t, 514, plugin.Parameters[1].Default)
+}
+
 func clearRegistry() {
 	operator.DefaultRegistry = operator.NewRegistry()
 }
@@ -90,6 +90,20 @@ func (f *FakeOutput) ExpectEntry(t testing.TB, expected *entry.Entry) {
 	}
 }
 
+// ReceivedEntries returns the entries received by the fake output that have not been
+// read from its Received channel yet, without waiting for more
+func (f *FakeOutput) ReceivedEntries() []*entry.Entry {
+	entries := make([]*entry.Entry, 0, len(f.Received))
+	for {
+		select {
+		case e := <-f.Received:
+			entries = append(entries, e)
+		default:
+			return entries
+		}
+	}
+}
+
 // ExpectNoEntry expects that no entry will be received within the specified time
 func (f *FakeOutput) ExpectNoEntry(t testing.TB, timeout time.Duration) {
 	select {
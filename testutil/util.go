@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/observiq/stanza/logger"
 	"github.com/observiq/stanza/operator"
@@ -14,6 +16,37 @@ import (
 	"go.uber.org/zap/zaptest"
 )
 
+// FakeClock is a clock controlled by a test
+type FakeClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock replaces the clock of a package, such as its timeNow variable, with a
+// FakeClock starting at a fixed time. The original clock is restored when the test
+// finishes.
+func NewFakeClock(t testing.TB, clock *func() time.Time) *FakeClock {
+	fake := &FakeClock{now: time.Unix(1000, 0)}
+	original := *clock
+	*clock = fake.Now
+	t.Cleanup(func() { *clock = original })
+	return fake
+}
+
+// Now returns the current time of the clock
+func (c *FakeClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the duration
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+}
+
 // NewTempDir will return a new temp directory for testing
 func NewTempDir(t testing.TB) string {
 	tempDir, err := ioutil.TempDir("", "")